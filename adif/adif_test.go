@@ -0,0 +1,216 @@
+package adif
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	created := time.Date(2026, 3, 12, 8, 30, 0, 0, time.UTC)
+	header := Header{
+		Text:           "Exported by test",
+		ADIFVersion:    "3.1.4",
+		ProgramID:      "clh-test",
+		ProgramVersion: "1.0",
+		CreatedAt:      created,
+		Extra:          map[string]string{"USERDEF1": "x"},
+	}
+	records := []Record{
+		{
+			Call:         "BH1XYZ",
+			TimeOn:       time.Date(2026, 3, 12, 1, 2, 3, 0, time.UTC),
+			TimeOff:      time.Date(2026, 3, 12, 1, 4, 0, 0, time.UTC),
+			Band:         "20m",
+			FreqHz:       14074123,
+			Mode:         "MFSK",
+			Submode:      "FT4",
+			RSTSent:      "-10",
+			RSTRcvd:      "+02",
+			Gridsquare:   "OM89",
+			MyGridsquare: "PM01aa",
+			Name:         "Zhāng Wěi",
+			Comment:      "<tnx> 73",
+			Extra:        map[string]string{"APP_TEST_X": "1"},
+		},
+		{Call: "JA1ABC", FreqHz: 7074000, Mode: "FT8"},
+	}
+
+	text := EncodeWithHeader(header, records)
+	r := NewReader(strings.NewReader(text))
+	gotHeader, ok, err := r.Header()
+	if err != nil || !ok {
+		t.Fatalf("Header() = %v, %v", ok, err)
+	}
+	if !reflect.DeepEqual(gotHeader, header) {
+		t.Fatalf("header = %+v, want %+v", gotHeader, header)
+	}
+	got, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Band is filled in from the frequency on write.
+	records[1].Band = "40m"
+	if !reflect.DeepEqual(got, records) {
+		t.Fatalf("records = %+v\nwant %+v", got, records)
+	}
+}
+
+func TestDecodeTolerant(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Record
+	}{
+		{
+			name: "no header, lower case, type indicators",
+			text: "<call:6>BH1XYZ <freq:6:N>14.074 <mode:3>FT8 <eor>",
+			want: []Record{{Call: "BH1XYZ", FreqHz: 14074000, Mode: "FT8"}},
+		},
+		{
+			name: "missing final EOR",
+			text: "<CALL:5>K1ABC<EOR><CALL:5>W9XYZ",
+			want: []Record{{Call: "K1ABC"}, {Call: "W9XYZ"}},
+		},
+		{
+			name: "header without fields",
+			text: "free text\n<EOH>\n<CALL:5>K1ABC<EOR>",
+			want: []Record{{Call: "K1ABC"}},
+		},
+		{
+			name: "date and time in either order, 4-digit time",
+			text: "<TIME_ON:4>0102<QSO_DATE:8>20260312<EOR>",
+			want: []Record{{TimeOn: time.Date(2026, 3, 12, 1, 2, 0, 0, time.UTC)}},
+		},
+		{
+			name: "length in characters",
+			text: "<NAME:3>Wěi <CALL:5>K1ABC<EOR>",
+			want: []Record{{Name: "Wěi", Call: "K1ABC"}},
+		},
+		{
+			name: "length in bytes",
+			text: "<NAME:4>Wěi<CALL:5>K1ABC<EOR>",
+			want: []Record{{Name: "Wěi", Call: "K1ABC"}},
+		},
+		{
+			name: "empty records and stray text",
+			text: "junk <EOR><EOR> <BAND:3>20M more junk <EOR>",
+			want: []Record{{Band: "20m"}},
+		},
+		{
+			name: "empty input",
+			text: "",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		got, err := Decode(tt.text)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReaderSmallReads(t *testing.T) {
+	var records []Record
+	for i := 0; i < 500; i++ {
+		records = append(records, Record{Call: "K1ABC", Comment: strings.Repeat("é", i%40), FreqHz: 14074000})
+	}
+	text := EncodeWithHeader(Header{ProgramID: "x"}, records)
+	got, err := NewReader(iotest.OneByteReader(strings.NewReader(text))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := Decode(text)
+	if len(got) != len(records) || !reflect.DeepEqual(got, want) {
+		t.Fatalf("read %d records one byte at a time, want %d", len(got), len(records))
+	}
+}
+
+func TestReaderError(t *testing.T) {
+	boom := errors.New("boom")
+	src := io.MultiReader(strings.NewReader("<CALL:5>K1ABC<EOR><CALL:5>W9"), iotest.ErrReader(boom))
+	r := NewReader(src)
+	rec, err := r.Read()
+	if err != nil || rec.Call != "K1ABC" {
+		t.Fatalf("first Read() = %+v, %v", rec, err)
+	}
+	if _, err := r.Read(); !errors.Is(err, boom) {
+		t.Fatalf("second Read() error = %v, want %v", err, boom)
+	}
+}
+
+func TestWriterHeaderAfterRecords(t *testing.T) {
+	w := NewWriter(io.Discard)
+	if err := w.Write(Record{Call: "K1ABC"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteHeader(Header{}); err == nil {
+		t.Fatal("WriteHeader after Write succeeded")
+	}
+}
+
+func TestFreq(t *testing.T) {
+	tests := []struct {
+		hz   uint64
+		text string
+	}{
+		{14074000, "14.074"},
+		{14074123, "14.074123"},
+		{7000000, "7"},
+		{136000, "0.136"},
+		{10368100000, "10368.1"},
+		{0, ""},
+	}
+	for _, tt := range tests {
+		if got := FormatFreq(tt.hz); got != tt.text {
+			t.Errorf("FormatFreq(%d) = %q, want %q", tt.hz, got, tt.text)
+		}
+		if tt.hz == 0 {
+			continue
+		}
+		if got, err := ParseFreq(tt.text); err != nil || got != tt.hz {
+			t.Errorf("ParseFreq(%q) = %d, %v, want %d", tt.text, got, err, tt.hz)
+		}
+	}
+	for _, s := range []string{"", "abc", "-1"} {
+		if _, err := ParseFreq(s); err == nil {
+			t.Errorf("ParseFreq(%q) succeeded", s)
+		}
+	}
+}
+
+func TestWriterInvalidFieldName(t *testing.T) {
+	for _, name := range []string{"", "MY:FIELD", "A<B", "A>B", "MY FIELD", "TAB\tX", "A,B", "{A}"} {
+		var sb strings.Builder
+		w := NewWriter(&sb)
+		if err := w.Write(Record{Call: "K1ABC", Extra: map[string]string{name: "x"}}); err == nil {
+			t.Errorf("Write accepted field name %q", name)
+		}
+		if sb.Len() != 0 {
+			t.Errorf("rejected record with %q wrote %q", name, sb.String())
+		}
+		if err := w.WriteHeader(Header{Extra: map[string]string{name: "x"}}); err == nil {
+			t.Errorf("WriteHeader accepted field name %q", name)
+		}
+		if err := w.w.Flush(); err != nil || sb.Len() != 0 {
+			t.Errorf("rejected header with %q wrote %q", name, sb.String())
+		}
+	}
+
+	got := Encode([]Record{
+		{Call: "K1ABC", Extra: map[string]string{"BAD NAME": "x"}},
+		{Call: "W9XYZ", Extra: map[string]string{"app_clh_note": "ok"}},
+	})
+	if strings.Contains(got, "K1ABC") || !strings.Contains(got, "<APP_CLH_NOTE:2>ok") {
+		t.Fatalf("Encode = %q", got)
+	}
+}
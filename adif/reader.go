@@ -0,0 +1,260 @@
+package adif
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const readChunk = 32 << 10
+
+// Reader parses ADI text. It is tolerant of what real loggers produce: field names in
+// any case, type indicators (<FREQ:6:N>), text outside fields, a missing header or final
+// <EOR>, and lengths counted in characters rather than UTF-8 bytes.
+//
+// Input is read in chunks as records are consumed, so large logs are not held in memory.
+type Reader struct {
+	src     io.Reader
+	buf     []byte // unconsumed input
+	srcErr  error  // first error from src, io.EOF at the end
+	started bool
+
+	header Header
+	hasHdr bool
+	first  []Field // the first record, read while looking for a header
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{src: r}
+}
+
+// Header returns the file header; ok is false when the file has none.
+func (r *Reader) Header() (h Header, ok bool, err error) {
+	if err = r.start(); err != nil {
+		return Header{}, false, err
+	}
+	return r.header, r.hasHdr, nil
+}
+
+// Read returns the next record, or io.EOF when there are no more.
+func (r *Reader) Read() (Record, error) {
+	if err := r.start(); err != nil {
+		return Record{}, err
+	}
+
+	var rec Record
+	if len(r.first) > 0 {
+		for _, f := range r.first {
+			rec.set(f.Name, f.Value)
+		}
+		r.first = nil
+		return rec, nil
+	}
+
+	fields := 0
+	for {
+		name, value, ok := r.next()
+		if !ok {
+			if err := r.err(); err != nil {
+				return Record{}, err
+			}
+			if fields > 0 {
+				return rec, nil
+			}
+			return Record{}, io.EOF
+		}
+		switch name {
+		case "EOR":
+			if fields > 0 {
+				return rec, nil
+			}
+		case "EOH":
+		default:
+			rec.set(name, value)
+			fields++
+		}
+	}
+}
+
+func (r *Reader) ReadAll() ([]Record, error) {
+	var out []Record
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return out, err
+		}
+		out = append(out, rec)
+	}
+}
+
+// Decode parses ADI text into records, ignoring any header.
+func Decode(s string) ([]Record, error) {
+	return NewReader(strings.NewReader(s)).ReadAll()
+}
+
+func (r *Reader) start() error {
+	if !r.started {
+		r.started = true
+		r.parseHeader()
+	}
+	return r.err()
+}
+
+// err returns the read error from src, if any other than io.EOF.
+func (r *Reader) err() error {
+	if r.srcErr == io.EOF {
+		return nil
+	}
+	return r.srcErr
+}
+
+// parseHeader consumes the header if an <EOH> appears before the first <EOR>. Otherwise
+// the fields it read belong to the first record and are kept for Read.
+func (r *Reader) parseHeader() {
+	lt := r.indexByte(0, '<')
+	if lt < 0 {
+		lt = len(r.buf)
+	}
+	h := Header{Text: strings.TrimSpace(string(r.buf[:lt]))}
+
+	var fields []Field
+	for {
+		name, value, ok := r.next()
+		if !ok || name == "EOR" {
+			r.first = fields
+			return
+		}
+		if name == "EOH" {
+			r.header, r.hasHdr = h, true
+			return
+		}
+		fields = append(fields, Field{Name: name, Value: value})
+		switch name {
+		case "ADIF_VER":
+			h.ADIFVersion = value
+		case "PROGRAMID":
+			h.ProgramID = value
+		case "PROGRAMVERSION":
+			h.ProgramVersion = value
+		case "CREATED_TIMESTAMP":
+			h.CreatedAt, _ = time.Parse(createdLayout, value)
+		default:
+			if h.Extra == nil {
+				h.Extra = map[string]string{}
+			}
+			h.Extra[name] = value
+		}
+	}
+}
+
+// next returns the next field or tag (EOR, EOH); ok is false at the end of the input.
+func (r *Reader) next() (name, value string, ok bool) {
+	for {
+		lt := r.indexByte(0, '<')
+		if lt < 0 {
+			r.buf = r.buf[:0]
+			return "", "", false
+		}
+		gt := r.indexByte(lt+1, '>')
+		if gt < 0 {
+			r.buf = r.buf[:0]
+			return "", "", false
+		}
+		spec := string(r.buf[lt+1 : gt])
+		r.buf = r.buf[gt+1:]
+
+		parts := strings.Split(spec, ":")
+		name = strings.ToUpper(strings.TrimSpace(parts[0]))
+		if len(parts) == 1 {
+			if name == "EOR" || name == "EOH" {
+				return name, "", true
+			}
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || n < 0 || name == "" {
+			continue
+		}
+		end := r.valueEnd(n)
+		value = string(r.buf[:end])
+		r.buf = r.buf[end:]
+		return name, value, true
+	}
+}
+
+// valueEnd finds where a value of length n at the start of buf ends. The spec counts
+// bytes, but some writers count characters; use the character count when the byte
+// count would split a rune or run into the middle of the next token.
+func (r *Reader) valueEnd(n int) int {
+	r.fill(n)
+	byteEnd := min(n, len(r.buf))
+	if utf8.Valid(r.buf[:byteEnd]) && r.atBoundary(byteEnd) {
+		return byteEnd
+	}
+
+	runeEnd := 0
+	for i := 0; i < n; i++ {
+		r.fill(runeEnd + utf8.UTFMax)
+		if runeEnd >= len(r.buf) {
+			break
+		}
+		_, size := utf8.DecodeRune(r.buf[runeEnd:])
+		runeEnd += size
+	}
+	if r.atBoundary(runeEnd) {
+		return runeEnd
+	}
+	return byteEnd
+}
+
+func (r *Reader) atBoundary(i int) bool {
+	for ; r.fill(i + 1); i++ {
+		switch r.buf[i] {
+		case ' ', '\t', '\r', '\n':
+		case '<':
+			return true
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// indexByte returns the index of c in buf at or after from, reading more input as
+// needed, or -1 when the input ends first.
+func (r *Reader) indexByte(from int, c byte) int {
+	for {
+		if from < len(r.buf) {
+			if i := bytes.IndexByte(r.buf[from:], c); i >= 0 {
+				return from + i
+			}
+			from = len(r.buf)
+		}
+		if !r.fill(len(r.buf) + 1) {
+			return -1
+		}
+	}
+}
+
+// fill reads until buf holds at least n bytes and reports whether it does.
+func (r *Reader) fill(n int) bool {
+	for len(r.buf) < n && r.srcErr == nil {
+		if cap(r.buf)-len(r.buf) < readChunk {
+			grown := make([]byte, len(r.buf), 2*len(r.buf)+readChunk)
+			copy(grown, r.buf)
+			r.buf = grown
+		}
+		m, err := r.src.Read(r.buf[len(r.buf):cap(r.buf)])
+		r.buf = r.buf[:len(r.buf)+m]
+		if err != nil {
+			r.srcErr = err
+		}
+	}
+	return len(r.buf) >= n
+}
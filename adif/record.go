@@ -0,0 +1,216 @@
+// Package adif reads and writes ADIF (ADI format) QSO records.
+//
+//	var buf strings.Builder
+//	w := adif.NewWriter(&buf)
+//	_ = w.Write(adif.Record{Call: "BH1XYZ", Mode: "FT8", FreqHz: 14074000, TimeOn: time.Now()})
+package adif
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	dateLayout = "20060102"
+	timeLayout = "150405"
+)
+
+// Record is one QSO. Times are UTC; a zero time, frequency or string leaves the field out.
+// Fields without a typed member are kept in Extra under their upper-case ADIF name.
+type Record struct {
+	Call            string
+	TimeOn          time.Time // QSO_DATE + TIME_ON
+	TimeOff         time.Time // QSO_DATE_OFF + TIME_OFF
+	Band            string
+	BandRX          string
+	FreqHz          uint64
+	FreqRXHz        uint64
+	Mode            string
+	Submode         string
+	RSTSent         string
+	RSTRcvd         string
+	Gridsquare      string
+	MyGridsquare    string
+	Name            string
+	Comment         string
+	Operator        string
+	StationCallsign string
+	TXPwr           string
+	SRXString       string
+	STXString       string
+	PropMode        string
+	Extra           map[string]string
+}
+
+// Header is the optional ADI file header.
+type Header struct {
+	Text           string // free text before the first header field
+	ADIFVersion    string
+	ProgramID      string
+	ProgramVersion string
+	CreatedAt      time.Time
+	Extra          map[string]string
+}
+
+// Field is one name/value pair in file order.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Fields returns the record as ADIF fields in a stable order: typed fields first, then
+// Extra sorted by name. Band is derived from FreqHz when empty.
+func (r Record) Fields() []Field {
+	var out []Field
+	add := func(name, value string) {
+		if value != "" {
+			out = append(out, Field{name, value})
+		}
+	}
+
+	add("CALL", r.Call)
+	if !r.TimeOn.IsZero() {
+		on := r.TimeOn.UTC()
+		add("QSO_DATE", on.Format(dateLayout))
+		add("TIME_ON", on.Format(timeLayout))
+	}
+	if !r.TimeOff.IsZero() {
+		off := r.TimeOff.UTC()
+		add("QSO_DATE_OFF", off.Format(dateLayout))
+		add("TIME_OFF", off.Format(timeLayout))
+	}
+	band := r.Band
+	if band == "" {
+		band = bandName(r.FreqHz)
+	}
+	add("BAND", band)
+	add("BAND_RX", r.BandRX)
+	add("FREQ", FormatFreq(r.FreqHz))
+	add("FREQ_RX", FormatFreq(r.FreqRXHz))
+	add("MODE", r.Mode)
+	add("SUBMODE", r.Submode)
+	add("RST_SENT", r.RSTSent)
+	add("RST_RCVD", r.RSTRcvd)
+	add("GRIDSQUARE", r.Gridsquare)
+	add("MY_GRIDSQUARE", r.MyGridsquare)
+	add("NAME", r.Name)
+	add("COMMENT", r.Comment)
+	add("OPERATOR", r.Operator)
+	add("STATION_CALLSIGN", r.StationCallsign)
+	add("TX_PWR", r.TXPwr)
+	add("SRX_STRING", r.SRXString)
+	add("STX_STRING", r.STXString)
+	add("PROP_MODE", r.PropMode)
+
+	for _, name := range sortedKeys(r.Extra) {
+		add(strings.ToUpper(name), r.Extra[name])
+	}
+	return out
+}
+
+// set applies one parsed field; unknown fields go to Extra.
+func (r *Record) set(name, value string) {
+	switch name {
+	case "CALL":
+		r.Call = value
+	case "QSO_DATE":
+		r.TimeOn = withDate(r.TimeOn, value)
+	case "TIME_ON":
+		r.TimeOn = withTime(r.TimeOn, value)
+	case "QSO_DATE_OFF":
+		r.TimeOff = withDate(r.TimeOff, value)
+	case "TIME_OFF":
+		r.TimeOff = withTime(r.TimeOff, value)
+	case "BAND":
+		r.Band = strings.ToLower(value)
+	case "BAND_RX":
+		r.BandRX = strings.ToLower(value)
+	case "FREQ":
+		r.FreqHz, _ = ParseFreq(value)
+	case "FREQ_RX":
+		r.FreqRXHz, _ = ParseFreq(value)
+	case "MODE":
+		r.Mode = value
+	case "SUBMODE":
+		r.Submode = value
+	case "RST_SENT":
+		r.RSTSent = value
+	case "RST_RCVD":
+		r.RSTRcvd = value
+	case "GRIDSQUARE":
+		r.Gridsquare = value
+	case "MY_GRIDSQUARE":
+		r.MyGridsquare = value
+	case "NAME":
+		r.Name = value
+	case "COMMENT":
+		r.Comment = value
+	case "OPERATOR":
+		r.Operator = value
+	case "STATION_CALLSIGN":
+		r.StationCallsign = value
+	case "TX_PWR":
+		r.TXPwr = value
+	case "SRX_STRING":
+		r.SRXString = value
+	case "STX_STRING":
+		r.STXString = value
+	case "PROP_MODE":
+		r.PropMode = value
+	default:
+		if r.Extra == nil {
+			r.Extra = map[string]string{}
+		}
+		r.Extra[name] = value
+	}
+}
+
+// FormatFreq renders Hz as ADIF MHz with trailing zeros trimmed: 14074000 -> "14.074".
+func FormatFreq(hz uint64) string {
+	if hz == 0 {
+		return ""
+	}
+	s := strconv.FormatUint(hz/1e6, 10)
+	if frac := hz % 1e6; frac != 0 {
+		s += "." + strings.TrimRight(strconv.FormatUint(frac+1e6, 10)[1:], "0")
+	}
+	return s
+}
+
+// ParseFreq parses an ADIF MHz value into Hz.
+func ParseFreq(s string) (uint64, error) {
+	mhz, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, err
+	}
+	if mhz < 0 {
+		return 0, strconv.ErrRange
+	}
+	return uint64(mhz*1e6 + 0.5), nil
+}
+
+// withDate and withTime combine the separate ADIF date and time fields, in either order.
+func withDate(t time.Time, value string) time.Time {
+	d, err := time.Parse(dateLayout, strings.TrimSpace(value))
+	if err != nil {
+		return t
+	}
+	return time.Date(d.Year(), d.Month(), d.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+func withTime(t time.Time, value string) time.Time {
+	value = strings.TrimSpace(value)
+	layout := timeLayout
+	if len(value) == 4 {
+		layout = "1504"
+	}
+	clock, err := time.Parse(layout, value)
+	if err != nil {
+		return t
+	}
+	if t.IsZero() {
+		t = time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, time.UTC)
+}
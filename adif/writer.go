@@ -0,0 +1,156 @@
+package adif
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/SydneyOwl/clh-plugin-go-sdk/bandplan"
+)
+
+const createdLayout = "20060102 150405"
+
+var errHeaderAfterRecords = errors.New("adif: header must be written before records")
+
+// Writer writes ADI records. Field lengths are UTF-8 byte counts, which is what common
+// ADI readers, including this package's Reader, expect.
+type Writer struct {
+	w       *bufio.Writer
+	records int
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// WriteHeader writes the header and <EOH>. It must precede the first record.
+func (w *Writer) WriteHeader(h Header) error {
+	if w.records > 0 {
+		return errHeaderAfterRecords
+	}
+	fields := []Field{
+		{"ADIF_VER", h.ADIFVersion},
+		{"PROGRAMID", h.ProgramID},
+		{"PROGRAMVERSION", h.ProgramVersion},
+	}
+	if !h.CreatedAt.IsZero() {
+		fields = append(fields, Field{"CREATED_TIMESTAMP", h.CreatedAt.UTC().Format(createdLayout)})
+	}
+	for _, name := range sortedKeys(h.Extra) {
+		fields = append(fields, Field{strings.ToUpper(name), h.Extra[name]})
+	}
+	if err := checkFieldNames(fields); err != nil {
+		return err
+	}
+
+	text := h.Text
+	if text == "" {
+		text = "ADIF export"
+	}
+	if _, err := w.w.WriteString(text + "\n"); err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if f.Value == "" {
+			continue
+		}
+		if err := w.writeField(f); err != nil {
+			return err
+		}
+		if err := w.w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	if _, err := w.w.WriteString("<EOH>\n"); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// Write writes one record terminated by <EOR>. A record with an invalid Extra field
+// name is rejected before anything is written.
+func (w *Writer) Write(r Record) error {
+	fields := r.Fields()
+	if err := checkFieldNames(fields); err != nil {
+		return err
+	}
+	for i, f := range fields {
+		if i > 0 {
+			if err := w.w.WriteByte(' '); err != nil {
+				return err
+			}
+		}
+		if err := w.writeField(f); err != nil {
+			return err
+		}
+	}
+	if _, err := w.w.WriteString(" <EOR>\n"); err != nil {
+		return err
+	}
+	w.records++
+	return w.w.Flush()
+}
+
+func (w *Writer) writeField(f Field) error {
+	_, err := w.w.WriteString("<" + f.Name + ":" + strconv.Itoa(len(f.Value)) + ">" + f.Value)
+	return err
+}
+
+// checkFieldNames rejects names that would break the <NAME:LEN> tag: empty names and
+// names with a colon, angle or curly bracket, comma or whitespace.
+func checkFieldNames(fields []Field) error {
+	for _, f := range fields {
+		if f.Name == "" || strings.ContainsAny(f.Name, ":<>{},") || strings.IndexFunc(f.Name, unicode.IsSpace) >= 0 {
+			return fmt.Errorf("adif: invalid field name %q", f.Name)
+		}
+	}
+	return nil
+}
+
+// Encode renders records as ADI text without a header. Records the Writer rejects are
+// left out.
+func Encode(records []Record) string {
+	var sb strings.Builder
+	w := NewWriter(&sb)
+	for _, r := range records {
+		_ = w.Write(r)
+	}
+	return sb.String()
+}
+
+// EncodeWithHeader renders a complete ADI file. Empty header fields default to ADIF
+// 3.1.4 and the current time. A header or record the Writer rejects is left out.
+func EncodeWithHeader(h Header, records []Record) string {
+	if h.ADIFVersion == "" {
+		h.ADIFVersion = "3.1.4"
+	}
+	if h.CreatedAt.IsZero() {
+		h.CreatedAt = time.Now()
+	}
+	var sb strings.Builder
+	w := NewWriter(&sb)
+	_ = w.WriteHeader(h)
+	for _, r := range records {
+		_ = w.Write(r)
+	}
+	return sb.String()
+}
+
+func bandName(hz uint64) string {
+	return bandplan.BandName(hz)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,152 @@
+// Package bandplan maps frequencies to ADIF band names, IARU region allocations and
+// sub-band segments, and modes to their family (CW, PHONE, DATA, IMAGE).
+//
+//	band, ok := bandplan.BandFor(14074000) // band.Name == "20m"
+package bandplan
+
+import (
+	"sort"
+	"strings"
+)
+
+type Region int
+
+const (
+	// RegionAny uses the ADIF band edges, which span every region's allocation.
+	RegionAny Region = 0
+	Region1   Region = 1 // Europe, Africa, Middle East, northern Asia
+	Region2   Region = 2 // the Americas
+	Region3   Region = 3 // Asia-Pacific
+)
+
+// Band is an amateur band with inclusive edges in Hz. Name is the ADIF band enumeration.
+type Band struct {
+	Name    string
+	LowerHz uint64
+	UpperHz uint64
+}
+
+func (b Band) Contains(hz uint64) bool {
+	return hz >= b.LowerHz && hz <= b.UpperHz
+}
+
+func khz(v float64) uint64 { return uint64(v*1e3 + 0.5) }
+func mhz(v float64) uint64 { return uint64(v*1e6 + 0.5) }
+
+// adifBands follows the ADIF 3.1 Band enumeration, sorted by frequency.
+var adifBands = []Band{
+	{"2190m", khz(135.7), khz(137.8)},
+	{"630m", khz(472), khz(479)},
+	{"560m", khz(501), khz(504)},
+	{"160m", mhz(1.8), mhz(2.0)},
+	{"80m", mhz(3.5), mhz(4.0)},
+	{"60m", mhz(5.06), mhz(5.45)},
+	{"40m", mhz(7.0), mhz(7.3)},
+	{"30m", mhz(10.1), mhz(10.15)},
+	{"20m", mhz(14.0), mhz(14.35)},
+	{"17m", mhz(18.068), mhz(18.168)},
+	{"15m", mhz(21.0), mhz(21.45)},
+	{"12m", mhz(24.89), mhz(24.99)},
+	{"10m", mhz(28.0), mhz(29.7)},
+	{"8m", mhz(40), mhz(45)},
+	{"6m", mhz(50), mhz(54)},
+	{"5m", mhz(54.000001), mhz(69.9)},
+	{"4m", mhz(70), mhz(71)},
+	{"2m", mhz(144), mhz(148)},
+	{"1.25m", mhz(222), mhz(225)},
+	{"70cm", mhz(420), mhz(450)},
+	{"33cm", mhz(902), mhz(928)},
+	{"23cm", mhz(1240), mhz(1300)},
+	{"13cm", mhz(2300), mhz(2450)},
+	{"9cm", mhz(3300), mhz(3500)},
+	{"6cm", mhz(5650), mhz(5925)},
+	{"3cm", mhz(10000), mhz(10500)},
+	{"1.25cm", mhz(24000), mhz(24250)},
+	{"6mm", mhz(47000), mhz(47200)},
+	{"4mm", mhz(75500), mhz(81000)},
+	{"2.5mm", mhz(119980), mhz(123000)},
+	{"2mm", mhz(134000), mhz(149000)},
+	{"1mm", mhz(241000), mhz(250000)},
+	{"submm", mhz(300000), mhz(7500000)},
+}
+
+// regionEdges narrows the ADIF edges where an IARU region allocates less. A zero Band
+// means the region has no allocation; bands not listed use the ADIF edges.
+var regionEdges = map[Region]map[string]Band{
+	Region1: {
+		"160m":  {"160m", mhz(1.81), mhz(2.0)},
+		"80m":   {"80m", mhz(3.5), mhz(3.8)},
+		"60m":   {"60m", khz(5351.5), khz(5366.5)},
+		"40m":   {"40m", mhz(7.0), mhz(7.2)},
+		"8m":    {},
+		"6m":    {"6m", mhz(50), mhz(52)},
+		"5m":    {},
+		"4m":    {"4m", mhz(70), mhz(70.5)},
+		"2m":    {"2m", mhz(144), mhz(146)},
+		"1.25m": {},
+		"70cm":  {"70cm", mhz(430), mhz(440)},
+		"33cm":  {},
+	},
+	Region2: {
+		"60m": {"60m", khz(5330.5), khz(5406.4)},
+		"8m":  {},
+		"5m":  {},
+		"4m":  {},
+	},
+	Region3: {
+		"80m":   {"80m", mhz(3.5), mhz(3.9)},
+		"60m":   {"60m", khz(5351.5), khz(5366.5)},
+		"40m":   {"40m", mhz(7.0), mhz(7.2)},
+		"8m":    {},
+		"5m":    {},
+		"4m":    {},
+		"1.25m": {},
+		"70cm":  {"70cm", mhz(430), mhz(440)},
+		"33cm":  {},
+	},
+}
+
+// Bands returns the ADIF bands in frequency order.
+func Bands() []Band {
+	return append([]Band(nil), adifBands...)
+}
+
+// BandFor returns the ADIF band containing hz.
+func BandFor(hz uint64) (Band, bool) {
+	i := sort.Search(len(adifBands), func(i int) bool { return adifBands[i].UpperHz >= hz })
+	if i < len(adifBands) && adifBands[i].Contains(hz) {
+		return adifBands[i], true
+	}
+	return Band{}, false
+}
+
+// BandForRegion is BandFor restricted to the allocation of one IARU region.
+func BandForRegion(hz uint64, region Region) (Band, bool) {
+	band, ok := BandFor(hz)
+	if !ok || region == RegionAny {
+		return band, ok
+	}
+	if edges, ok := regionEdges[region][band.Name]; ok {
+		if edges.Name == "" || !edges.Contains(hz) {
+			return Band{}, false
+		}
+		return edges, true
+	}
+	return band, true
+}
+
+// BandName returns the ADIF band name for hz, or "" outside the amateur bands.
+func BandName(hz uint64) string {
+	band, _ := BandFor(hz)
+	return band.Name
+}
+
+// BandByName looks up an ADIF band name such as "20m" or "70cm", case-insensitively.
+func BandByName(name string) (Band, bool) {
+	for _, b := range adifBands {
+		if strings.EqualFold(b.Name, name) {
+			return b, true
+		}
+	}
+	return Band{}, false
+}
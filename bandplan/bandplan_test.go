@@ -0,0 +1,144 @@
+package bandplan
+
+import "testing"
+
+func TestBandFor(t *testing.T) {
+	tests := []struct {
+		hz   uint64
+		name string
+	}{
+		{136_000, "2190m"},
+		{1_800_000, "160m"},
+		{2_000_000, "160m"},
+		{3_573_000, "80m"},
+		{7_074_000, "40m"},
+		{14_074_000, "20m"},
+		{14_350_000, "20m"},
+		{28_074_000, "10m"},
+		{50_313_000, "6m"},
+		{144_174_000, "2m"},
+		{432_174_000, "70cm"},
+		{10_368_000_000, "3cm"},
+		{14_350_001, ""},
+		{9_000_000, ""},
+		{0, ""},
+	}
+	for _, tt := range tests {
+		if got := BandName(tt.hz); got != tt.name {
+			t.Errorf("BandName(%d) = %q, want %q", tt.hz, got, tt.name)
+		}
+	}
+}
+
+func TestBandForRegion(t *testing.T) {
+	tests := []struct {
+		hz     uint64
+		region Region
+		name   string
+		ok     bool
+	}{
+		{3_900_000, RegionAny, "80m", true},
+		{3_900_000, Region1, "", false},
+		{3_850_000, Region3, "80m", true},
+		{3_900_000, Region2, "80m", true},
+		{7_250_000, Region1, "", false},
+		{7_250_000, Region2, "40m", true},
+		{5_357_000, Region1, "60m", true},
+		{5_400_000, Region1, "", false},
+		{5_400_000, Region2, "60m", true},
+		{70_200_000, Region1, "4m", true},
+		{70_200_000, Region2, "", false},
+		{147_000_000, Region1, "", false},
+		{147_000_000, Region2, "2m", true},
+	}
+	for _, tt := range tests {
+		band, ok := BandForRegion(tt.hz, tt.region)
+		if ok != tt.ok || band.Name != tt.name {
+			t.Errorf("BandForRegion(%d, %d) = %q, %v, want %q, %v", tt.hz, tt.region, band.Name, ok, tt.name, tt.ok)
+		}
+	}
+}
+
+func TestBandByName(t *testing.T) {
+	band, ok := BandByName("70CM")
+	if !ok || band.Name != "70cm" || !band.Contains(435_000_000) {
+		t.Fatalf("BandByName(70CM) = %+v, %v", band, ok)
+	}
+	if _, ok := BandByName("11m"); ok {
+		t.Fatal("BandByName(11m) found a band")
+	}
+	bands := Bands()
+	for i := 1; i < len(bands); i++ {
+		if bands[i].LowerHz <= bands[i-1].UpperHz {
+			t.Fatalf("bands %s and %s overlap or are out of order", bands[i-1].Name, bands[i].Name)
+		}
+	}
+}
+
+func TestSegmentFor(t *testing.T) {
+	tests := []struct {
+		hz      uint64
+		region  Region
+		segment Segment
+		ok      bool
+	}{
+		{14_010_000, Region1, SegmentCW, true},
+		{14_074_500, Region1, SegmentDigital, true},
+		{14_100_000, Region1, SegmentBeacon, true},
+		{14_200_000, Region1, SegmentPhone, true},
+		{14_120_000, Region1, SegmentPhone, true},
+		{14_120_000, Region2, SegmentDigital, true},
+		{14_350_000, Region1, SegmentPhone, true},
+		{7_074_000, Region2, SegmentDigital, true},
+		{7_150_000, RegionAny, SegmentPhone, true},
+		{5_357_000, Region1, SegmentDigital, true},
+		{5_363_000, Region1, SegmentAll, true},
+		{50_090_000, Region1, SegmentCW, true},
+		{144_300_000, Region1, SegmentPhone, true},
+		{1_296_200_000, Region1, SegmentAll, true},
+		{7_250_000, Region1, "", false},
+		{9_000_000, RegionAny, "", false},
+	}
+	for _, tt := range tests {
+		segment, ok := SegmentFor(tt.hz, tt.region)
+		if ok != tt.ok || segment != tt.segment {
+			t.Errorf("SegmentFor(%d, %d) = %q, %v, want %q, %v", tt.hz, tt.region, segment, ok, tt.segment, tt.ok)
+		}
+	}
+}
+
+func TestModes(t *testing.T) {
+	tests := []struct {
+		mode    string
+		adif    string
+		submode string
+		family  Family
+	}{
+		{"USB", "SSB", "USB", FamilyPhone},
+		{"lsb", "SSB", "LSB", FamilyPhone},
+		{"FT8", "FT8", "", FamilyData},
+		{"FT4", "MFSK", "FT4", FamilyData},
+		{"CW-R", "CW", "", FamilyCW},
+		{"RTTY-R", "RTTY", "", FamilyData},
+		{"PKTUSB", "PKTUSB", "", FamilyData},
+		{"DMR", "DIGITALVOICE", "DMR", FamilyPhone},
+		{"SSTV", "SSTV", "", FamilyImage},
+		{" fm ", "FM", "", FamilyPhone},
+		{"", "", "", ""},
+	}
+	for _, tt := range tests {
+		adif, submode := ADIFMode(tt.mode)
+		if adif != tt.adif || submode != tt.submode {
+			t.Errorf("ADIFMode(%q) = %q, %q, want %q, %q", tt.mode, adif, submode, tt.adif, tt.submode)
+		}
+		if got := ModeFamily(tt.mode); got != tt.family {
+			t.Errorf("ModeFamily(%q) = %q, want %q", tt.mode, got, tt.family)
+		}
+	}
+	if got := SegmentFamily(SegmentBeacon); got != FamilyCW {
+		t.Errorf("SegmentFamily(beacon) = %q", got)
+	}
+	if got := SegmentFamily(SegmentAll); got != "" {
+		t.Errorf("SegmentFamily(all) = %q", got)
+	}
+}
@@ -0,0 +1,97 @@
+package bandplan
+
+import "strings"
+
+// Family groups modes the way awards and contest logs do.
+type Family string
+
+const (
+	FamilyCW    Family = "CW"
+	FamilyPhone Family = "PHONE"
+	FamilyData  Family = "DATA"
+	FamilyImage Family = "IMAGE"
+)
+
+// submodeParents maps common rig and WSJT-X mode names that ADIF treats as submodes to
+// their ADIF mode, which is what QSODetail.ParentMode carries.
+var submodeParents = map[string]string{
+	"USB":    "SSB",
+	"LSB":    "SSB",
+	"FT4":    "MFSK",
+	"FST4":   "MFSK",
+	"FST4W":  "MFSK",
+	"JS8":    "MFSK",
+	"Q65":    "MFSK",
+	"PSK31":  "PSK",
+	"PSK63":  "PSK",
+	"PSK125": "PSK",
+	"BPSK31": "PSK",
+	"QPSK31": "PSK",
+	"C4FM":   "DIGITALVOICE",
+	"DSTAR":  "DIGITALVOICE",
+	"DMR":    "DIGITALVOICE",
+	"FREEDV": "DIGITALVOICE",
+	"M17":    "DIGITALVOICE",
+}
+
+// rigModes are rig CAT mode names that are not ADIF modes.
+var rigModes = map[string]string{
+	"CW-R":   "CW",
+	"RTTY-R": "RTTY",
+}
+
+var parentFamilies = map[string]Family{
+	"CW":           FamilyCW,
+	"SSB":          FamilyPhone,
+	"AM":           FamilyPhone,
+	"FM":           FamilyPhone,
+	"DIGITALVOICE": FamilyPhone,
+	"SSTV":         FamilyImage,
+	"FAX":          FamilyImage,
+	"ATV":          FamilyImage,
+}
+
+// ADIFMode splits a mode name into the ADIF MODE and SUBMODE fields: USB -> SSB/USB,
+// FT4 -> MFSK/FT4, FT8 -> FT8/"".
+func ADIFMode(mode string) (adifMode, submode string) {
+	mode = strings.ToUpper(strings.TrimSpace(mode))
+	if parent, ok := rigModes[mode]; ok {
+		return parent, ""
+	}
+	if parent, ok := submodeParents[mode]; ok {
+		return parent, mode
+	}
+	return mode, ""
+}
+
+// ModeFamily returns the family for a mode or ADIF parent mode. Rig data modes such as
+// PKTUSB are DATA although they run over an SSB filter. Unknown modes are assumed to be
+// digital; the empty mode returns "".
+func ModeFamily(mode string) Family {
+	mode = strings.ToUpper(strings.TrimSpace(mode))
+	switch mode {
+	case "":
+		return ""
+	case "PKTUSB", "PKTLSB", "DIGU", "DIGL", "PKTFM":
+		return FamilyData
+	}
+	parent, _ := ADIFMode(mode)
+	if family, ok := parentFamilies[parent]; ok {
+		return family
+	}
+	return FamilyData
+}
+
+// SegmentFamily maps a band plan segment to the mode family it is intended for.
+func SegmentFamily(s Segment) Family {
+	switch s {
+	case SegmentCW, SegmentBeacon:
+		return FamilyCW
+	case SegmentDigital:
+		return FamilyData
+	case SegmentPhone:
+		return FamilyPhone
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,157 @@
+package bandplan
+
+type Segment string
+
+const (
+	SegmentCW      Segment = "cw"
+	SegmentDigital Segment = "digital"
+	SegmentPhone   Segment = "phone"
+	SegmentBeacon  Segment = "beacon"
+	// SegmentAll covers allocations without a mode split, e.g. most microwave bands.
+	SegmentAll Segment = "all"
+)
+
+type segment struct {
+	lower, upper uint64
+	kind         Segment
+}
+
+// Segment tables simplify the IARU region band plans (R2 follows the ARRL plan) to
+// CW, digital and phone ranges. Narrow beacon and satellite slots are folded into
+// their neighbours except where they are well known.
+var (
+	hfSegmentsR1 = []segment{
+		{mhz(1.81), mhz(1.838), SegmentCW},
+		{mhz(1.838), mhz(1.843), SegmentDigital},
+		{mhz(1.843), mhz(2.0), SegmentPhone},
+		{mhz(3.5), mhz(3.57), SegmentCW},
+		{mhz(3.57), mhz(3.6), SegmentDigital},
+		{mhz(3.6), mhz(3.8), SegmentPhone},
+		{mhz(7.0), mhz(7.04), SegmentCW},
+		{mhz(7.04), mhz(7.06), SegmentDigital},
+		{mhz(7.06), mhz(7.2), SegmentPhone},
+		{mhz(10.1), mhz(10.13), SegmentCW},
+		{mhz(10.13), mhz(10.15), SegmentDigital},
+		{mhz(14.0), mhz(14.07), SegmentCW},
+		{mhz(14.07), mhz(14.099), SegmentDigital},
+		{mhz(14.099), mhz(14.101), SegmentBeacon},
+		{mhz(14.101), mhz(14.112), SegmentDigital},
+		{mhz(14.112), mhz(14.35), SegmentPhone},
+		{mhz(18.068), mhz(18.095), SegmentCW},
+		{mhz(18.095), mhz(18.109), SegmentDigital},
+		{mhz(18.109), mhz(18.111), SegmentBeacon},
+		{mhz(18.111), mhz(18.168), SegmentPhone},
+		{mhz(21.0), mhz(21.07), SegmentCW},
+		{mhz(21.07), mhz(21.149), SegmentDigital},
+		{mhz(21.149), mhz(21.151), SegmentBeacon},
+		{mhz(21.151), mhz(21.45), SegmentPhone},
+		{mhz(24.89), mhz(24.915), SegmentCW},
+		{mhz(24.915), mhz(24.929), SegmentDigital},
+		{mhz(24.929), mhz(24.931), SegmentBeacon},
+		{mhz(24.931), mhz(24.99), SegmentPhone},
+		{mhz(28.0), mhz(28.07), SegmentCW},
+		{mhz(28.07), mhz(28.19), SegmentDigital},
+		{mhz(28.19), mhz(28.225), SegmentBeacon},
+		{mhz(28.225), mhz(29.7), SegmentPhone},
+	}
+
+	hfSegmentsR2 = []segment{
+		{mhz(1.8), mhz(1.84), SegmentCW},
+		{mhz(1.84), mhz(1.843), SegmentDigital},
+		{mhz(1.843), mhz(2.0), SegmentPhone},
+		{mhz(3.5), mhz(3.57), SegmentCW},
+		{mhz(3.57), mhz(3.6), SegmentDigital},
+		{mhz(3.6), mhz(4.0), SegmentPhone},
+		{mhz(7.0), mhz(7.04), SegmentCW},
+		{mhz(7.04), mhz(7.1), SegmentDigital},
+		{mhz(7.1), mhz(7.3), SegmentPhone},
+		{mhz(10.1), mhz(10.13), SegmentCW},
+		{mhz(10.13), mhz(10.15), SegmentDigital},
+		{mhz(14.0), mhz(14.07), SegmentCW},
+		{mhz(14.07), mhz(14.099), SegmentDigital},
+		{mhz(14.099), mhz(14.101), SegmentBeacon},
+		{mhz(14.101), mhz(14.15), SegmentDigital},
+		{mhz(14.15), mhz(14.35), SegmentPhone},
+		{mhz(18.068), mhz(18.1), SegmentCW},
+		{mhz(18.1), mhz(18.109), SegmentDigital},
+		{mhz(18.109), mhz(18.111), SegmentBeacon},
+		{mhz(18.111), mhz(18.168), SegmentPhone},
+		{mhz(21.0), mhz(21.07), SegmentCW},
+		{mhz(21.07), mhz(21.149), SegmentDigital},
+		{mhz(21.149), mhz(21.151), SegmentBeacon},
+		{mhz(21.151), mhz(21.2), SegmentDigital},
+		{mhz(21.2), mhz(21.45), SegmentPhone},
+		{mhz(24.89), mhz(24.92), SegmentCW},
+		{mhz(24.92), mhz(24.929), SegmentDigital},
+		{mhz(24.929), mhz(24.931), SegmentBeacon},
+		{mhz(24.931), mhz(24.99), SegmentPhone},
+		{mhz(28.0), mhz(28.07), SegmentCW},
+		{mhz(28.07), mhz(28.2), SegmentDigital},
+		{mhz(28.2), mhz(28.3), SegmentBeacon},
+		{mhz(28.3), mhz(29.7), SegmentPhone},
+	}
+
+	vhfSegments = []segment{
+		{mhz(50.0), mhz(50.1), SegmentCW},
+		{mhz(50.1), mhz(50.3), SegmentPhone},
+		{mhz(50.3), mhz(50.6), SegmentDigital},
+		{mhz(50.6), mhz(54.0), SegmentPhone},
+		{mhz(144.0), mhz(144.1), SegmentCW},
+		{mhz(144.1), mhz(144.4), SegmentPhone},
+		{mhz(144.4), mhz(144.49), SegmentBeacon},
+		{mhz(144.49), mhz(144.5), SegmentDigital},
+		{mhz(144.5), mhz(148.0), SegmentPhone},
+		{mhz(432.0), mhz(432.1), SegmentCW},
+		{mhz(432.1), mhz(432.4), SegmentPhone},
+		{mhz(432.4), mhz(432.49), SegmentBeacon},
+	}
+
+	// digitalWindows are the FT8/FT4/JT/WSPR dial frequencies plus their 3 kHz passband.
+	// They override the segment tables since several sit in SSB or CW ranges.
+	digitalWindows = []uint64{
+		khz(136), khz(474.2), mhz(1.836), mhz(1.84), mhz(3.568), mhz(3.573), mhz(3.575),
+		khz(5357), mhz(7.038), mhz(7.0475), mhz(7.074), mhz(10.136), mhz(10.14),
+		mhz(14.074), mhz(14.08), mhz(14.095), mhz(18.1), mhz(18.104),
+		mhz(21.074), mhz(21.094), mhz(21.14), mhz(24.915), mhz(24.919), mhz(24.924),
+		mhz(28.074), mhz(28.124), mhz(28.18), mhz(50.313), mhz(50.318), mhz(50.323),
+		mhz(70.154), mhz(144.174), mhz(144.17), mhz(432.174), mhz(1296.174),
+	}
+)
+
+const digitalWindowHz = 3000
+
+// SegmentFor classifies hz within the band plan of region (RegionAny uses the Region 1
+// HF plan). Ranges without a mode split, such as 60m and most of UHF, report SegmentAll.
+// It reports false outside the region's allocations.
+func SegmentFor(hz uint64, region Region) (Segment, bool) {
+	band, ok := BandForRegion(hz, region)
+	if !ok {
+		return "", false
+	}
+	for _, dial := range digitalWindows {
+		if hz >= dial && hz <= dial+digitalWindowHz {
+			return SegmentDigital, true
+		}
+	}
+
+	table := hfSegmentsR1
+	if region == Region2 {
+		table = hfSegmentsR2
+	}
+	for _, tables := range [][]segment{table, vhfSegments} {
+		for _, s := range tables {
+			if hz >= s.lower && hz < s.upper {
+				return s.kind, true
+			}
+		}
+	}
+	// Band edges are inclusive while segments are half-open.
+	if hz == band.UpperHz {
+		for _, s := range table {
+			if s.upper == hz {
+				return s.kind, true
+			}
+		}
+	}
+	return SegmentAll, true
+}
@@ -0,0 +1,28 @@
+package clhplugin
+
+import "github.com/SydneyOwl/clh-plugin-go-sdk/bandplan"
+
+// Band returns the ADIF band of the TX frequency, or "" outside the amateur bands.
+func (r RigData) Band() string {
+	return bandplan.BandName(r.Frequency)
+}
+
+func (r RigData) ModeFamily() bandplan.Family {
+	return bandplan.ModeFamily(r.Mode)
+}
+
+func (s RigSnapshot) Band() string {
+	return bandplan.BandName(s.TXFrequencyHz)
+}
+
+func (d QSODetail) Band() string {
+	return bandplan.BandName(d.TXFrequencyHz)
+}
+
+// ModeFamily prefers ParentMode, falling back to Mode when CLH did not fill it in.
+func (d QSODetail) ModeFamily() bandplan.Family {
+	if d.ParentMode != "" {
+		return bandplan.ModeFamily(d.ParentMode)
+	}
+	return bandplan.ModeFamily(d.Mode)
+}
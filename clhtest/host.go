@@ -0,0 +1,415 @@
+// Package clhtest provides an in-process fake Cloudlog Helper host so plugins built on
+// the SDK can be tested without a running Cloudlog Helper.
+//
+//	host, err := clhtest.NewHost()
+//	defer host.Close()
+//	client, _ := clhplugin.NewClient(manifest, host.ClientOption())
+package clhtest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	clhplugin "github.com/SydneyOwl/clh-plugin-go-sdk"
+)
+
+const (
+	CodeUnsupportedTopic = clhplugin.ServerCodeUnsupportedTopic
+	CodeHandlerError     = clhplugin.ServerCodeInternal
+)
+
+var ErrHostClosed = errors.New("clhtest: host is closed")
+
+// Request is a query or command received from a plugin. Payload holds the decoded SDK
+// model, e.g. clhplugin.SettingsPatch.
+type Request struct {
+	PluginUUID   string
+	ID           string
+	Kind         clhplugin.EnvelopeKind
+	Topic        clhplugin.EnvelopeTopic
+	Attributes   map[string]string
+	Subscription *clhplugin.EventSubscription
+	Payload      any
+	Timestamp    time.Time
+}
+
+func (r Request) SettingsPatch() (clhplugin.SettingsPatch, bool) {
+	p, ok := r.Payload.(clhplugin.SettingsPatch)
+	return p, ok
+}
+
+func (r Request) Notification() (clhplugin.NotificationCommand, bool) {
+	p, ok := r.Payload.(clhplugin.NotificationCommand)
+	return p, ok
+}
+
+// Response is what a Handler sends back. Payload may be a protobuf message or an SDK
+// model such as clhplugin.ServerInfo or clhplugin.RigSnapshot.
+type Response struct {
+	Payload   any
+	Message   string
+	ErrorCode string
+	Failed    bool
+}
+
+func Reply(payload any) Response {
+	return Response{Payload: payload, Message: "ok"}
+}
+
+func Fail(code, message string) Response {
+	return Response{Failed: true, ErrorCode: code, Message: message}
+}
+
+type Handler func(ctx context.Context, req Request) Response
+
+type Option func(*config) error
+
+type config struct {
+	listener   net.Listener
+	socketPath string
+	serverInfo clhplugin.ServerInfo
+	onRegister func(clhplugin.PluginInfo) error
+}
+
+// WithSocketPath listens on the given unix socket path instead of a fresh temp directory.
+func WithSocketPath(path string) Option {
+	return func(cfg *config) error {
+		if path == "" {
+			return errors.New("socket path cannot be empty")
+		}
+		cfg.socketPath = path
+		return nil
+	}
+}
+
+// WithListener serves on an existing listener, e.g. a TCP listener.
+func WithListener(listener net.Listener) Option {
+	return func(cfg *config) error {
+		if listener == nil {
+			return errors.New("listener cannot be nil")
+		}
+		cfg.listener = listener
+		return nil
+	}
+}
+
+// WithMemoryTransport serves over an in-process clhplugin.MemoryListener instead of a
+// unix socket. Connect clients with ClientOption.
+func WithMemoryTransport() Option {
+	return func(cfg *config) error {
+		cfg.listener = clhplugin.NewMemoryListener()
+		return nil
+	}
+}
+
+// WithServerInfo sets the instance id, version and keepalive timeout the host reports.
+func WithServerInfo(info clhplugin.ServerInfo) Option {
+	return func(cfg *config) error {
+		cfg.serverInfo = info
+		return nil
+	}
+}
+
+// WithRegisterHandler lets a test reject registrations by returning an error.
+func WithRegisterHandler(fn func(clhplugin.PluginInfo) error) Option {
+	return func(cfg *config) error {
+		cfg.onRegister = fn
+		return nil
+	}
+}
+
+// Host is a clhplugin.Server that records what plugins send so tests can assert on it.
+type Host struct {
+	server   *clhplugin.Server
+	tempDir  string
+	listener net.Listener
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu            sync.Mutex
+	requests      []Request
+	heartbeats    map[string]int
+	registrations map[string]int
+	changed       chan struct{}
+}
+
+func NewHost(opts ...Option) (*Host, error) {
+	cfg := config{
+		serverInfo: clhplugin.ServerInfo{
+			InstanceID:          "clhtest",
+			Version:             "clhtest",
+			KeepaliveTimeoutSec: 30,
+		},
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	h := &Host{
+		heartbeats:    map[string]int{},
+		registrations: map[string]int{},
+		changed:       make(chan struct{}),
+	}
+
+	server, err := clhplugin.NewServer(
+		clhplugin.WithServerInstance(cfg.serverInfo.InstanceID, cfg.serverInfo.Version),
+		clhplugin.WithKeepaliveTimeout(time.Duration(cfg.serverInfo.KeepaliveTimeoutSec)*time.Second),
+		clhplugin.WithRegisterHook(cfg.onRegister),
+		clhplugin.WithLifecycleHandler(h.onLifecycle),
+		clhplugin.WithHeartbeatHandler(h.onHeartbeat),
+		clhplugin.WithRequestObserver(h.onRequest),
+	)
+	if err != nil {
+		return nil, err
+	}
+	h.server = server
+
+	if cfg.listener != nil {
+		h.listener = cfg.listener
+	} else {
+		path := cfg.socketPath
+		if path == "" {
+			dir, err := os.MkdirTemp("", "clhtest-")
+			if err != nil {
+				_ = server.Close()
+				return nil, err
+			}
+			h.tempDir = dir
+			path = filepath.Join(dir, "clh.plugin")
+		}
+		listener, err := net.Listen("unix", path)
+		if err != nil {
+			_ = server.Close()
+			if h.tempDir != "" {
+				_ = os.RemoveAll(h.tempDir)
+			}
+			return nil, err
+		}
+		h.listener = listener
+	}
+
+	h.ctx, h.cancel = context.WithCancel(context.Background())
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		_ = server.Serve(h.listener)
+	}()
+	return h, nil
+}
+
+// Server returns the underlying server, for tests that need its full API.
+func (h *Host) Server() *clhplugin.Server {
+	return h.server
+}
+
+// PipePath returns the endpoint in the form accepted by clhplugin.WithPipePath.
+func (h *Host) PipePath() string {
+	addr := h.listener.Addr()
+	switch addr.Network() {
+	case "tcp":
+		return "tcp://" + addr.String()
+	case "unix":
+		return "unix://" + addr.String()
+	default:
+		return addr.String()
+	}
+}
+
+// ClientOption points a client at this host, whatever transport it listens on.
+func (h *Host) ClientOption() clhplugin.Option {
+	if mem, ok := h.listener.(*clhplugin.MemoryListener); ok {
+		return clhplugin.WithDialer(mem.Dial)
+	}
+	return clhplugin.WithPipePath(h.PipePath())
+}
+
+func (h *Host) Close() error {
+	h.cancel()
+	err := h.server.Close()
+	h.wg.Wait()
+	if h.tempDir != "" {
+		_ = os.RemoveAll(h.tempDir)
+	}
+	return err
+}
+
+// Handle installs the handler for a query or command topic. A nil handler restores the
+// server's built-in behaviour.
+func (h *Host) Handle(topic clhplugin.EnvelopeTopic, handler Handler) {
+	if handler == nil {
+		h.server.Handle(topic, nil)
+		return
+	}
+	h.server.Handle(topic, func(ctx context.Context, req clhplugin.ServerRequest) (any, error) {
+		resp := handler(ctx, toRequest(req))
+		if resp.Failed {
+			return nil, &clhplugin.RemoteError{Topic: req.Topic, Code: resp.ErrorCode, Message: resp.Message}
+		}
+		return resp.Payload, nil
+	})
+}
+
+// Requests returns every query and command received so far, in arrival order.
+func (h *Host) Requests() []Request {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Request(nil), h.requests...)
+}
+
+// Plugins returns the currently registered plugins.
+func (h *Host) Plugins() []clhplugin.PluginInfo {
+	return h.server.Plugins()
+}
+
+func (h *Host) Plugin(uuid string) (clhplugin.PluginInfo, bool) {
+	return h.server.Plugin(uuid)
+}
+
+// Heartbeats returns how many heartbeats the plugin has sent across all its connections.
+func (h *Host) Heartbeats(uuid string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.heartbeats[uuid]
+}
+
+// Registrations returns how many times the plugin has registered, which is handy for
+// asserting reconnects.
+func (h *Host) Registrations(uuid string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.registrations[uuid]
+}
+
+// WaitFor blocks until cond returns true or ctx is done. cond is re-evaluated whenever
+// a plugin registers, disconnects, heartbeats or sends a request.
+func (h *Host) WaitFor(ctx context.Context, cond func(h *Host) bool) error {
+	for {
+		h.mu.Lock()
+		changed := h.changed
+		h.mu.Unlock()
+
+		if cond(h) {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-h.ctx.Done():
+			return ErrHostClosed
+		}
+	}
+}
+
+func (h *Host) WaitForPlugin(ctx context.Context, uuid string) (clhplugin.PluginInfo, error) {
+	var info clhplugin.PluginInfo
+	err := h.WaitFor(ctx, func(h *Host) bool {
+		var ok bool
+		info, ok = h.Plugin(uuid)
+		return ok
+	})
+	return info, err
+}
+
+func (h *Host) WaitForHeartbeats(ctx context.Context, uuid string, n int) error {
+	return h.WaitFor(ctx, func(h *Host) bool {
+		return h.Heartbeats(uuid) >= n
+	})
+}
+
+// PushEvent sends an event envelope to every plugin subscribed to topic and returns how
+// many plugins it was delivered to.
+func (h *Host) PushEvent(topic clhplugin.EnvelopeTopic, payload any) (int, error) {
+	return h.server.Broadcast(topic, payload)
+}
+
+func (h *Host) PushRigData(data clhplugin.RigData) (int, error) {
+	return h.PushEvent(clhplugin.EnvelopeTopicEventRigData, data)
+}
+
+// PushInternal sends the populated member of msg on its matching event topic.
+func (h *Host) PushInternal(msg clhplugin.CLHInternalMessage) (int, error) {
+	return h.server.BroadcastInternal(msg)
+}
+
+func (h *Host) PushWsjtxMessage(msg clhplugin.WsjtxMessage) (int, error) {
+	topic := clhplugin.EnvelopeTopicEventWsjtxMessage
+	if msg.Decode != nil {
+		topic = clhplugin.EnvelopeTopicEventWsjtxDecodeRealtime
+	}
+	return h.PushEvent(topic, msg)
+}
+
+func (h *Host) PushPackedDecode(msg clhplugin.PackedDecodeMessage) (int, error) {
+	return h.PushEvent(clhplugin.EnvelopeTopicEventWsjtxDecodeBatch, msg)
+}
+
+// PushRaw writes msg as a top level message to one plugin, bypassing subscriptions.
+// Use it to send bare RigData or ClhInternalMessage frames.
+func (h *Host) PushRaw(uuid string, msg any) error {
+	return h.server.SendRaw(uuid, msg)
+}
+
+// Disconnect sends PipeConnectionClosed to the plugin and drops its connection, the way
+// Cloudlog Helper does on shutdown.
+func (h *Host) Disconnect(uuid string) error {
+	return h.server.Disconnect(uuid)
+}
+
+// Drop closes the plugin's connection without notice, simulating a crash.
+func (h *Host) Drop(uuid string) error {
+	return h.server.Drop(uuid)
+}
+
+func (h *Host) onLifecycle(ev clhplugin.PluginLifecycleChanged) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if ev.EventType == clhplugin.PluginLifecycleEventConnected {
+		h.registrations[ev.PluginUUID]++
+	}
+	h.notifyLocked()
+}
+
+func (h *Host) onHeartbeat(info clhplugin.PluginInfo) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.heartbeats[info.UUID]++
+	h.notifyLocked()
+}
+
+func (h *Host) onRequest(req clhplugin.ServerRequest) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.requests = append(h.requests, toRequest(req))
+	h.notifyLocked()
+}
+
+func toRequest(req clhplugin.ServerRequest) Request {
+	return Request{
+		PluginUUID:   req.Plugin.UUID,
+		ID:           req.ID,
+		Kind:         req.Kind,
+		Topic:        req.Topic,
+		Attributes:   req.Attributes,
+		Subscription: req.Subscription,
+		Payload:      req.Payload,
+		Timestamp:    req.Timestamp,
+	}
+}
+
+func (h *Host) notifyLocked() {
+	close(h.changed)
+	h.changed = make(chan struct{})
+}
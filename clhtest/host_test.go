@@ -0,0 +1,131 @@
+package clhtest_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	clhplugin "github.com/SydneyOwl/clh-plugin-go-sdk"
+	"github.com/SydneyOwl/clh-plugin-go-sdk/clhtest"
+)
+
+var manifest = clhplugin.PluginManifest{UUID: "host-test", Name: "host test", Version: "1.0.0"}
+
+func connect(t *testing.T, opts ...clhtest.Option) (*clhtest.Host, *clhplugin.Client, context.Context) {
+	t.Helper()
+	host, err := clhtest.NewHost(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = host.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	client, err := clhplugin.NewClient(manifest, host.ClientOption())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close(context.Background()) })
+	if _, err := client.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return host, client, ctx
+}
+
+func TestDefaultResponders(t *testing.T) {
+	for _, transport := range []struct {
+		name string
+		opt  clhtest.Option
+	}{
+		{"unix socket", nil},
+		{"memory", clhtest.WithMemoryTransport()},
+	} {
+		t.Run(transport.name, func(t *testing.T) {
+			host, client, ctx := connect(t, transport.opt,
+				clhtest.WithServerInfo(clhplugin.ServerInfo{InstanceID: "clh-a", Version: "2.0", KeepaliveTimeoutSec: 10}))
+
+			info, err := client.QueryServerInfo(ctx)
+			if err != nil || info.InstanceID != "clh-a" || info.Version != "2.0" || info.KeepaliveTimeoutSec != 10 {
+				t.Fatalf("QueryServerInfo = %+v, %v", info, err)
+			}
+			if info.ConnectedPluginCount != 1 {
+				t.Fatalf("ConnectedPluginCount = %d", info.ConnectedPluginCount)
+			}
+
+			plugins, err := client.QueryConnectedPlugins(ctx)
+			if err != nil || len(plugins.Plugins) != 1 || plugins.Plugins[0].UUID != manifest.UUID {
+				t.Fatalf("QueryConnectedPlugins = %+v, %v", plugins, err)
+			}
+
+			sub := clhplugin.EventSubscription{Topics: []clhplugin.EnvelopeTopic{clhplugin.EnvelopeTopicEventRigData}}
+			if _, err := client.SubscribeEvents(ctx, sub); err != nil {
+				t.Fatal(err)
+			}
+			p, ok := host.Plugin(manifest.UUID)
+			if !ok || !slices.Equal(p.EventSubscription.Topics, sub.Topics) {
+				t.Fatalf("host plugin = %+v, %v", p, ok)
+			}
+			if n, err := host.PushRigData(clhplugin.RigData{Frequency: 7074000}); err != nil || n != 1 {
+				t.Fatalf("PushRigData = %d, %v", n, err)
+			}
+
+			telemetry, err := client.QueryPluginTelemetry(ctx, manifest.UUID)
+			if err != nil || telemetry.PluginUUID != manifest.UUID || telemetry.ReceivedMessageCount == 0 || telemetry.SentMessageCount == 0 {
+				t.Fatalf("QueryPluginTelemetry = %+v, %v", telemetry, err)
+			}
+			if _, err := client.QueryPluginTelemetry(ctx, "nobody"); err == nil {
+				t.Fatal("telemetry for an unknown plugin succeeded")
+			}
+
+			// Every request is recorded with its decoded payload.
+			topics := make([]clhplugin.EnvelopeTopic, 0, 4)
+			for _, req := range host.Requests() {
+				if req.PluginUUID != manifest.UUID {
+					t.Fatalf("request from %q", req.PluginUUID)
+				}
+				topics = append(topics, req.Topic)
+			}
+			want := []clhplugin.EnvelopeTopic{
+				clhplugin.EnvelopeTopicQueryServerInfo,
+				clhplugin.EnvelopeTopicQueryConnectedPlugins,
+				clhplugin.EnvelopeTopicCommandSubscribeEvents,
+				clhplugin.EnvelopeTopicQueryPluginTelemetry,
+				clhplugin.EnvelopeTopicQueryPluginTelemetry,
+			}
+			if !slices.Equal(topics, want) {
+				t.Fatalf("request topics = %v, want %v", topics, want)
+			}
+		})
+	}
+}
+
+func TestHandleOverride(t *testing.T) {
+	host, client, ctx := connect(t, clhtest.WithMemoryTransport())
+
+	var remote *clhplugin.RemoteError
+	if _, err := client.QueryRigSnapshot(ctx); !errors.As(err, &remote) || remote.Code != clhtest.CodeUnsupportedTopic {
+		t.Fatalf("unhandled topic: err = %v", err)
+	}
+
+	host.Handle(clhplugin.EnvelopeTopicQueryRigSnapshot, func(context.Context, clhtest.Request) clhtest.Response {
+		return clhtest.Reply(clhplugin.RigSnapshot{Provider: "flrig"})
+	})
+	if rig, err := client.QueryRigSnapshot(ctx); err != nil || rig.Provider != "flrig" {
+		t.Fatalf("QueryRigSnapshot = %+v, %v", rig, err)
+	}
+
+	host.Handle(clhplugin.EnvelopeTopicQueryServerInfo, func(context.Context, clhtest.Request) clhtest.Response {
+		return clhtest.Fail("maintenance", "try later")
+	})
+	if _, err := client.QueryServerInfo(ctx); !errors.As(err, &remote) || remote.Code != "maintenance" || remote.Message != "try later" {
+		t.Fatalf("failing handler: err = %v", err)
+	}
+
+	// A nil handler brings back the built-in responder.
+	host.Handle(clhplugin.EnvelopeTopicQueryServerInfo, nil)
+	if info, err := client.QueryServerInfo(ctx); err != nil || info.InstanceID != "clhtest" {
+		t.Fatalf("QueryServerInfo after reset = %+v, %v", info, err)
+	}
+}
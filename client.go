@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net"
 	"sync"
 	"sync/atomic"
@@ -18,27 +19,58 @@ import (
 type Client struct {
 	manifest PluginManifest
 	cfg      Config
+	log      *slog.Logger
 
 	connMu sync.RWMutex
 	conn   net.Conn
 
+	sessionMu sync.Mutex
+
 	writeMu sync.Mutex
 
 	pendingMu sync.Mutex
 	pending   map[string]chan *pb.PipeEnvelope
 
-	waitCh chan Message
+	waitCh    chan Message
+	waitStats keyedCounters
+	metrics   metrics
+	health    healthState
+
+	// inboundMu guards waitCh against messages injected from outside the read loop
+	// (the WSJT-X bridge) while finish closes it; finishCh unblocks those injectors.
+	inboundMu     sync.RWMutex
+	inboundClosed bool
+	finishCh      chan struct{}
 
 	doneCh chan struct{}
 	stopCh chan struct{}
 	endMu  sync.Once
+	// closingCh is closed as soon as Close starts, ahead of stopCh, so a reconnect dial
+	// holding sessionMu gives up instead of keeping Close waiting.
+	closingCh chan struct{}
 
 	reqSeq atomic.Uint64
 
 	connected atomic.Bool
 	closed    atomic.Bool
 
+	stateMu   sync.Mutex
+	state     ConnectionState
+	stateCh   chan StateChange
+	stateDone bool
+	stateSubs handlerSet[StateChange]
+
 	registerResp RegisterResponse
+
+	subMu          sync.Mutex
+	subscription   *EventSubscription
+	requiredTopics map[EnvelopeTopic]int
+
+	router       router
+	dispatcher   dispatcher
+	subscribers  subscriberSet
+	inbound      InboundHandler
+	interceptors []RequestInterceptor
 }
 
 func NewClient(manifest PluginManifest, opts ...Option) (*Client, error) {
@@ -57,13 +89,25 @@ func NewClient(manifest PluginManifest, opts ...Option) (*Client, error) {
 	}
 
 	c := &Client{
-		manifest: manifest,
-		cfg:      cfg,
-		pending:  make(map[string]chan *pb.PipeEnvelope),
-		waitCh:   make(chan Message, cfg.WaitBufferSize),
-		doneCh:   make(chan struct{}),
-		stopCh:   make(chan struct{}),
-	}
+		manifest:  manifest,
+		cfg:       cfg,
+		log:       newLogger(cfg, manifest),
+		pending:   make(map[string]chan *pb.PipeEnvelope),
+		waitCh:    make(chan Message, cfg.WaitBufferSize),
+		doneCh:    make(chan struct{}),
+		stopCh:    make(chan struct{}),
+		finishCh:  make(chan struct{}),
+		closingCh: make(chan struct{}),
+		state:     ConnectionStateIdle,
+		stateCh:   make(chan StateChange, defaultStateBuffer),
+	}
+	c.dispatcher.cfg = cfg.Dispatch
+	c.dispatcher.deliver = c.deliverCallbacks
+	c.dispatcher.dropped = func(msg Message) { c.logDrop("dispatch", msg) }
+	c.dispatcher.stop = make(chan struct{})
+	c.subscribers.dropped = func(msg Message) { c.logDrop("subscriber", msg) }
+	c.buildInboundChain()
+	c.buildRequestChain()
 	return c, nil
 }
 
@@ -90,25 +134,50 @@ func (c *Client) Connect(ctx context.Context) (RegisterResponse, error) {
 	if c.closed.Load() {
 		return RegisterResponse{}, ErrClientClosed
 	}
+	select {
+	case <-c.doneCh:
+		return RegisterResponse{}, ErrClientClosed
+	default:
+	}
+
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
 	if c.connected.Load() {
-		return c.registerResp, nil
+		return c.RegisterResponse(), nil
 	}
 
-	conn, err := dialPipe(ctx, c.cfg.PipePath)
+	conn, modelResp, err := c.dialAndRegister(ctx)
 	if err != nil {
-		return RegisterResponse{}, err
+		c.setState(ConnectionStateDisconnected, err)
+		return modelResp, err
 	}
+	c.startSession(conn, modelResp)
+	return modelResp, nil
+}
+
+func (c *Client) dialAndRegister(ctx context.Context) (net.Conn, RegisterResponse, error) {
+	c.setState(ConnectionStateDialing, nil)
+	c.log.Debug("dialing clh", "endpoint", c.endpoint())
+	conn, err := c.dial(ctx)
+	if err != nil {
+		c.log.Warn("dial failed", "endpoint", c.endpoint(), "error", err)
+		return nil, RegisterResponse{}, err
+	}
+
+	c.setState(ConnectionStateRegistering, nil)
 
 	req := toPBManifest(c.manifest)
 	if err = writeDelimitedMessage(conn, req); err != nil {
 		_ = conn.Close()
-		return RegisterResponse{}, err
+		c.log.Warn("register failed", "error", err)
+		return nil, RegisterResponse{}, err
 	}
 
 	resp := &pb.PipeRegisterPluginResp{}
 	if err = readDelimitedMessage(conn, resp); err != nil {
 		_ = conn.Close()
-		return RegisterResponse{}, err
+		c.log.Warn("register failed", "error", err)
+		return nil, RegisterResponse{}, err
 	}
 
 	modelResp := fromPBRegisterResponse(resp)
@@ -117,29 +186,50 @@ func (c *Client) Connect(ctx context.Context) (RegisterResponse, error) {
 		if modelResp.Message == "" {
 			modelResp.Message = "register failed"
 		}
-		return modelResp, errors.New(modelResp.Message)
+		c.log.Warn("register rejected", "message", modelResp.Message)
+		return nil, modelResp, errors.New(modelResp.Message)
 	}
+	c.log.Info("registered with clh",
+		"instance_id", modelResp.InstanceID,
+		"clh_version", modelResp.ServerInfo.Version,
+		"keepalive_timeout_sec", modelResp.ServerInfo.KeepaliveTimeoutSec,
+	)
+	return conn, modelResp, nil
+}
+
+// startSession must be called with sessionMu held.
+func (c *Client) startSession(conn net.Conn, resp RegisterResponse) {
+	s := newSession(conn)
 
 	c.connMu.Lock()
 	c.conn = conn
+	c.registerResp = resp
 	c.connMu.Unlock()
 
-	c.registerResp = modelResp
 	c.connected.Store(true)
+	c.setState(ConnectionStateConnected, nil)
 
-	go c.readLoop()
-	if c.cfg.HeartbeatInterval > 0 {
-		go c.heartbeatLoop()
+	go c.readLoop(s)
+	interval, window := c.heartbeatTiming(resp)
+	c.health.reset(interval, window)
+	if interval > 0 {
+		go c.heartbeatLoop(s, interval, window)
 	}
-
-	return modelResp, nil
 }
 
 func (c *Client) IsConnected() bool {
 	return c.connected.Load() && !c.closed.Load()
 }
 
+// Done is closed when the client has finished: after Close, or once the connection is lost
+// and will not be re-established.
+func (c *Client) Done() <-chan struct{} {
+	return c.doneCh
+}
+
 func (c *Client) RegisterResponse() RegisterResponse {
+	c.connMu.RLock()
+	defer c.connMu.RUnlock()
 	return c.registerResp
 }
 
@@ -152,6 +242,7 @@ func (c *Client) WaitMessage(ctx context.Context) (Message, error) {
 		if !ok {
 			return Message{}, ErrClientClosed
 		}
+		c.waitStats.record(msg, queueDelivered)
 		return msg, nil
 	case <-ctx.Done():
 		return Message{}, ctx.Err()
@@ -168,21 +259,35 @@ func (c *Client) Close(ctx context.Context) error {
 		return nil
 	}
 
+	c.setState(ConnectionStateClosed, nil)
+	close(c.closingCh)
+
+	c.sessionMu.Lock()
+	connected := c.connected.Load()
+	c.sessionMu.Unlock()
+
+	// closed is already set, so sendAnyMessage would refuse; write to the session directly.
+	// This happens before stopCh is closed, since the read loop drops the connection as soon
+	// as it sees stopCh. The host may have gone away first, which is why a failure is not
+	// worth a warning.
+	if connected {
+		if conn, err := c.getConn(); err == nil {
+			if err := c.writeAny(conn, &pb.PipeDeregisterPluginReq{
+				Uuid:      c.manifest.UUID,
+				Reason:    "client-close",
+				Timestamp: nowTimestamp(),
+			}); err != nil {
+				c.log.Debug("deregister not sent", "error", err)
+			}
+		}
+	}
 	close(c.stopCh)
 
-	if !c.connected.Load() {
+	if !connected {
 		c.finish()
 		return nil
 	}
 
-	if c.connected.Load() {
-		_ = c.sendAnyMessage(&pb.PipeDeregisterPluginReq{
-			Uuid:      c.manifest.UUID,
-			Reason:    "client-close",
-			Timestamp: nowTimestamp(),
-		})
-	}
-
 	c.connMu.Lock()
 	if c.conn != nil {
 		_ = c.conn.Close()
@@ -198,28 +303,11 @@ func (c *Client) Close(ctx context.Context) error {
 	}
 }
 
-func (c *Client) heartbeatLoop() {
-	ticker := time.NewTicker(c.cfg.HeartbeatInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			err := c.sendAnyMessage(&pb.PipeHeartbeat{
-				Uuid:      c.manifest.UUID,
-				Timestamp: nowTimestamp(),
-			})
-			if err != nil {
-				return
-			}
-		case <-c.stopCh:
-			return
-		}
-	}
-}
-
-func (c *Client) readLoop() {
-	defer c.finish()
+func (c *Client) readLoop(s *session) {
+	defer func() {
+		close(s.done)
+		c.handleDisconnect(s)
+	}()
 
 	for {
 		select {
@@ -228,19 +316,18 @@ func (c *Client) readLoop() {
 		default:
 		}
 
-		conn, err := c.getConn()
-		if err != nil {
-			return
-		}
-
 		anyMsg := &anypb.Any{}
-		if err = readDelimitedMessage(conn, anyMsg); err != nil {
-			if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+		if err := readDelimitedMessage(s.conn, anyMsg); err != nil {
+			if errors.Is(err, io.EOF) {
+				s.fail(ConnectionStateRemoteClosed, err)
 				return
 			}
+			s.fail(ConnectionStateDisconnected, err)
 			return
 		}
 
+		c.health.update(func(h *Health) { h.LastInbound = time.Now() })
+
 		protoMsg, modelMsg, convErr := fromAnyMessage(anyMsg)
 		if env, ok := protoMsg.(*pb.PipeEnvelope); ok && env.Kind == pb.PipeEnvelopeKind_PIPE_ENVELOPE_KIND_RESPONSE {
 			c.resolvePending(env)
@@ -254,43 +341,146 @@ func (c *Client) readLoop() {
 				},
 			}
 		}
+		if convErr != nil {
+			c.log.Warn("inbound message not decoded", "type_url", anyMsg.GetTypeUrl(), "error", convErr)
+		}
 		c.dispatchMessage(modelMsg)
 
 		if _, ok := protoMsg.(*pb.PipeConnectionClosed); ok {
+			s.fail(ConnectionStateRemoteClosed, nil)
 			return
 		}
 	}
 }
 
+// handleDisconnect tears down the current connection and either finishes the
+// client or hands over to the reconnect loop.
+func (c *Client) handleDisconnect(s *session) {
+	c.sessionMu.Lock()
+	c.connMu.Lock()
+	if c.conn == s.conn {
+		c.conn = nil
+	}
+	c.connMu.Unlock()
+	_ = s.conn.Close()
+	c.connected.Store(false)
+	c.sessionMu.Unlock()
+
+	c.rejectAllPending()
+
+	if !c.closed.Load() {
+		c.setState(s.cause())
+	}
+	if c.closed.Load() || c.cfg.Reconnect == nil {
+		c.finish()
+		return
+	}
+	go c.reconnectLoop()
+}
+
 func (c *Client) finish() {
 	c.endMu.Do(func() {
+		c.log.Info("client stopped", "state", string(c.State()))
 		c.connected.Store(false)
 		c.rejectAllPending()
+		close(c.finishCh)
+		c.inboundMu.Lock()
+		c.inboundClosed = true
+		c.inboundMu.Unlock()
 		close(c.waitCh)
+		c.dispatcher.close()
+		c.subscribers.close()
 		close(c.doneCh)
+		c.closeStateChanges()
 	})
 }
 
 func (c *Client) dispatchMessage(msg Message) {
-	if c.cfg.OnMessage != nil {
-		handler := c.cfg.OnMessage
-		go handler(msg)
+	c.metrics.message(msg)
+	span := c.startEventSpan(msg)
+	c.inbound(msg)
+	if span != nil {
+		span.End()
+	}
+}
+
+func (c *Client) deliverInbound(msg Message) {
+	if c.cfg.OnMessage != nil || c.router.active() {
+		c.dispatcher.dispatch(msg)
+	}
+	c.subscribers.publish(msg)
+	c.enqueueWait(msg)
+}
+
+// injectMessage delivers a message that did not come from CLH as if the read loop had
+// read it. It reports false once the client has finished.
+func (c *Client) injectMessage(msg Message) bool {
+	c.inboundMu.RLock()
+	defer c.inboundMu.RUnlock()
+	if c.inboundClosed {
+		return false
 	}
+	c.dispatchMessage(msg)
+	return true
+}
 
+func (c *Client) enqueueWait(msg Message) {
 	select {
 	case c.waitCh <- msg:
+		c.waitStats.record(msg, queueEnqueued)
+		return
 	default:
-		select {
-		case <-c.waitCh:
-		default:
+	}
+
+	switch c.cfg.WaitOverflow {
+	case OverflowDropNewest:
+		c.waitStats.record(msg, queueDropped)
+		c.logDrop("wait", msg)
+	case OverflowBlock:
+		var timeout <-chan time.Time
+		if c.cfg.WaitBlockTimeout > 0 {
+			timer := time.NewTimer(c.cfg.WaitBlockTimeout)
+			defer timer.Stop()
+			timeout = timer.C
 		}
 		select {
 		case c.waitCh <- msg:
-		default:
+			c.waitStats.record(msg, queueEnqueued)
+		case <-timeout:
+			c.waitStats.record(msg, queueDropped)
+			c.logDrop("wait", msg)
+		case <-c.stopCh:
+			c.waitStats.record(msg, queueDropped)
+			c.logDrop("wait", msg)
+		case <-c.finishCh:
+			c.waitStats.record(msg, queueDropped)
+			c.logDrop("wait", msg)
+		}
+	default:
+		for {
+			select {
+			case c.waitCh <- msg:
+				c.waitStats.record(msg, queueEnqueued)
+				return
+			default:
+			}
+			select {
+			case old := <-c.waitCh:
+				c.waitStats.record(old, queueDropped)
+				c.logDrop("wait", old)
+			default:
+			}
 		}
 	}
 }
 
+func (c *Client) deliverCallbacks(msg Message) {
+	if c.cfg.OnMessage != nil {
+		c.cfg.OnMessage(msg)
+	}
+	c.router.route(msg)
+}
+
 func (c *Client) getConn() (net.Conn, error) {
 	c.connMu.RLock()
 	defer c.connMu.RUnlock()
@@ -312,6 +502,10 @@ func (c *Client) sendAnyMessage(msg proto.Message) error {
 	if err != nil {
 		return err
 	}
+	return c.writeAny(conn, msg)
+}
+
+func (c *Client) writeAny(conn net.Conn, msg proto.Message) error {
 	packed, err := anypb.New(msg)
 	if err != nil {
 		return err
@@ -369,23 +563,44 @@ func (c *Client) requestRaw(
 	attributes map[string]string,
 	payload proto.Message,
 	subscription *EventSubscription,
-) (*pb.PipeEnvelope, error) {
-	if c.closed.Load() {
-		return nil, ErrClientClosed
-	}
-	if !c.connected.Load() {
-		return nil, ErrNotConnected
+) (out *pb.PipeEnvelope, err error) {
+	var (
+		reqID string
+		span  Span
+	)
+	if c.cfg.Tracer != nil {
+		ctx, span = c.startRequestSpan(ctx, kind, topic)
 	}
+	started := time.Now()
+	defer func() {
+		latency := time.Since(started)
+		c.metrics.request(topic, out, err, latency)
+		c.logRequest(kind, topic, reqID, out, err, latency)
+		if span != nil {
+			endRequestSpan(span, topic, reqID, out, err)
+		}
+	}()
 
-	if _, hasDeadline := ctx.Deadline(); !hasDeadline && c.cfg.RequestTimeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, c.cfg.RequestTimeout)
-		defer cancel()
+	req, err := c.newRequest(kind, topic, attributes, payload, subscription)
+	if err != nil {
+		return nil, err
 	}
+	reqID = req.Id
+	if sc := spanContextOf(span); sc.IsValid() && req.Attributes[TraceParentAttribute] == "" {
+		req.Attributes[TraceParentAttribute] = sc.TraceParent()
+	}
+	return c.roundTrip(ctx, req)
+}
 
-	reqID := c.nextRequestID()
+func (c *Client) newRequest(
+	kind EnvelopeKind,
+	topic EnvelopeTopic,
+	attributes map[string]string,
+	payload proto.Message,
+	subscription *EventSubscription,
+) (*pb.PipeEnvelope, error) {
 	req := &pb.PipeEnvelope{
-		Id:         reqID,
+		Id:         c.nextRequestID(),
 		Kind:       pb.PipeEnvelopeKind(kind),
 		Topic:      pb.PipeEnvelopeTopic(topic),
 		Success:    true,
@@ -406,15 +621,32 @@ func (c *Client) requestRaw(
 		}
 		req.Payload = reqPayload
 	}
+	return req, nil
+}
+
+// roundTrip sends req and waits for its response, without metrics, logs or spans.
+func (c *Client) roundTrip(ctx context.Context, req *pb.PipeEnvelope) (*pb.PipeEnvelope, error) {
+	if c.closed.Load() {
+		return nil, ErrClientClosed
+	}
+	if !c.connected.Load() {
+		return nil, ErrNotConnected
+	}
+
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline && c.cfg.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.RequestTimeout)
+		defer cancel()
+	}
 
 	respCh := make(chan *pb.PipeEnvelope, 1)
 	c.pendingMu.Lock()
-	c.pending[reqID] = respCh
+	c.pending[req.Id] = respCh
 	c.pendingMu.Unlock()
 
 	defer func() {
 		c.pendingMu.Lock()
-		delete(c.pending, reqID)
+		delete(c.pending, req.Id)
 		c.pendingMu.Unlock()
 	}()
 
@@ -425,6 +657,9 @@ func (c *Client) requestRaw(
 	select {
 	case resp := <-respCh:
 		if resp == nil {
+			if c.cfg.Reconnect != nil && !c.closed.Load() {
+				return nil, ErrConnectionLost
+			}
 			return nil, ErrClientClosed
 		}
 		return resp, nil
@@ -443,21 +678,43 @@ func (c *Client) requestExpectSuccess(
 	payload proto.Message,
 	subscription *EventSubscription,
 ) (*pb.PipeEnvelope, error) {
-	resp, err := c.requestRaw(ctx, kind, topic, attributes, payload, subscription)
+	var (
+		resp *pb.PipeEnvelope
+		err  error
+	)
+	if len(c.interceptors) == 0 {
+		resp, err = c.requestRaw(ctx, kind, topic, attributes, payload, subscription)
+	} else {
+		attrs := make(map[string]string, len(attributes))
+		for k, v := range attributes {
+			attrs[k] = v
+		}
+		resp, err = c.intercept(ctx, Request{
+			Kind:         kind,
+			Topic:        topic,
+			Attributes:   attrs,
+			Payload:      payload,
+			Subscription: subscription,
+		})
+	}
 	if err != nil {
 		return nil, err
 	}
 	if !resp.Success {
-		return nil, &RemoteError{
-			Topic:         topic,
-			Code:          resp.ErrorCode,
-			Message:       resp.Message,
-			CorrelationID: resp.CorrelationId,
-		}
+		return nil, remoteError(topic, resp)
 	}
 	return resp, nil
 }
 
+func remoteError(topic EnvelopeTopic, resp *pb.PipeEnvelope) *RemoteError {
+	return &RemoteError{
+		Topic:         topic,
+		Code:          resp.ErrorCode,
+		Message:       resp.Message,
+		CorrelationID: resp.CorrelationId,
+	}
+}
+
 func decodeResponsePayload(resp *pb.PipeEnvelope, out proto.Message) error {
 	if resp.Payload == nil {
 		return errors.New("response payload is empty")
@@ -566,6 +823,9 @@ func (c *Client) QueryPluginTelemetry(ctx context.Context, pluginUUID string) (P
 }
 
 func (c *Client) SubscribeEvents(ctx context.Context, sub EventSubscription) (EventSubscription, error) {
+	saved := EventSubscription{Topics: append([]EnvelopeTopic(nil), sub.Topics...)}
+	sub.Topics = c.withRequiredTopics(sub.Topics)
+
 	resp, err := c.requestExpectSuccess(
 		ctx,
 		EnvelopeKindCommand,
@@ -581,6 +841,11 @@ func (c *Client) SubscribeEvents(ctx context.Context, sub EventSubscription) (Ev
 	if err = decodeResponsePayload(resp, p); err != nil {
 		return EventSubscription{}, err
 	}
+
+	c.subMu.Lock()
+	c.subscription = &saved
+	c.subMu.Unlock()
+
 	return fromPBEventSubscription(p), nil
 }
 
@@ -0,0 +1,414 @@
+package clhplugin_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	clhplugin "github.com/SydneyOwl/clh-plugin-go-sdk"
+	"github.com/SydneyOwl/clh-plugin-go-sdk/clhtest"
+)
+
+var testManifest = clhplugin.PluginManifest{
+	UUID:    "test-plugin",
+	Name:    "test",
+	Version: "1.0.0",
+}
+
+func startHost(t *testing.T, opts ...clhtest.Option) *clhtest.Host {
+	t.Helper()
+	host, err := clhtest.NewHost(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = host.Close() })
+	return host
+}
+
+func newClient(t *testing.T, host *clhtest.Host, opts ...clhplugin.Option) *clhplugin.Client {
+	t.Helper()
+	client, err := clhplugin.NewClient(testManifest, append([]clhplugin.Option{host.ClientOption()}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = client.Close(ctx)
+	})
+	return client
+}
+
+func connectClient(t *testing.T, host *clhtest.Host, opts ...clhplugin.Option) *clhplugin.Client {
+	t.Helper()
+	client := newClient(t, host, opts...)
+	if _, err := client.Connect(testContext(t)); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// waitState reads StateChanges until the client reaches state.
+func waitState(t *testing.T, client *clhplugin.Client, state clhplugin.ConnectionState) clhplugin.StateChange {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case change, ok := <-client.StateChanges():
+			if !ok {
+				t.Fatalf("state changes closed before %s; state is %s", state, client.State())
+			}
+			if change.To == state {
+				return change
+			}
+		case <-timeout:
+			t.Fatalf("state %s not reached; state is %s", state, client.State())
+		}
+	}
+}
+
+func fastReconnect() clhplugin.ReconnectPolicy {
+	return clhplugin.ReconnectPolicy{InitialDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+}
+
+func TestConnect(t *testing.T) {
+	host := startHost(t, clhtest.WithServerInfo(clhplugin.ServerInfo{InstanceID: "clh-1", Version: "1.2.3", KeepaliveTimeoutSec: 30}))
+	client := connectClient(t, host)
+
+	if !client.IsConnected() || client.State() != clhplugin.ConnectionStateConnected {
+		t.Fatalf("state = %s", client.State())
+	}
+	if resp := client.RegisterResponse(); resp.ServerInfo.InstanceID != "clh-1" || resp.ServerInfo.Version != "1.2.3" {
+		t.Fatalf("register response = %+v", resp)
+	}
+	info, err := client.QueryServerInfo(testContext(t))
+	if err != nil || info.InstanceID != "clh-1" {
+		t.Fatalf("QueryServerInfo = %+v, %v", info, err)
+	}
+	if _, ok := host.Plugin(testManifest.UUID); !ok {
+		t.Fatal("host does not list the plugin")
+	}
+}
+
+func TestConnectRejected(t *testing.T) {
+	host := startHost(t, clhtest.WithRegisterHandler(func(clhplugin.PluginInfo) error {
+		return errors.New("not allowed")
+	}))
+	client := newClient(t, host)
+	if _, err := client.Connect(testContext(t)); err == nil {
+		t.Fatal("Connect succeeded")
+	}
+	if client.IsConnected() {
+		t.Fatal("client is connected after a rejected registration")
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	host := startHost(t)
+	client := connectClient(t, host, clhplugin.WithAutoReconnect(fastReconnect()))
+	ctx := testContext(t)
+
+	rig := make(chan clhplugin.RigData, 1)
+	client.OnRigData(func(data clhplugin.RigData) { rig <- data })
+	sub := clhplugin.EventSubscription{Topics: []clhplugin.EnvelopeTopic{clhplugin.EnvelopeTopicEventRigData}}
+	if _, err := client.SubscribeEvents(ctx, sub); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := host.Drop(testManifest.UUID); err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, client, clhplugin.ConnectionStateReconnecting)
+	waitState(t, client, clhplugin.ConnectionStateConnected)
+	if n := host.Registrations(testManifest.UUID); n != 2 {
+		t.Fatalf("%d registrations, want 2", n)
+	}
+
+	// The subscription is restored on the new session.
+	if err := host.WaitFor(ctx, func(h *clhtest.Host) bool {
+		info, ok := h.Plugin(testManifest.UUID)
+		return ok && slices.Contains(info.EventSubscription.Topics, clhplugin.EnvelopeTopicEventRigData)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := host.PushRigData(clhplugin.RigData{Frequency: 7074000}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case data := <-rig:
+		if data.Frequency != 7074000 {
+			t.Fatalf("rig data = %+v", data)
+		}
+	case <-ctx.Done():
+		t.Fatal("rig data not delivered after reconnect")
+	}
+	if m := client.Metrics(); m.Reconnects != 1 {
+		t.Fatalf("metrics reconnects = %d, want 1", m.Reconnects)
+	}
+}
+
+func TestReconnectGivesUp(t *testing.T) {
+	host := startHost(t)
+	policy := fastReconnect()
+	policy.MaxAttempts = 2
+	client := connectClient(t, host, clhplugin.WithAutoReconnect(policy))
+
+	if err := host.Close(); err != nil {
+		t.Fatal(err)
+	}
+	change := waitState(t, client, clhplugin.ConnectionStateDisconnected)
+	if change.Err == nil {
+		t.Fatal("giving up carries no error")
+	}
+	for range client.StateChanges() {
+	}
+	if _, err := client.QueryServerInfo(testContext(t)); !errors.Is(err, clhplugin.ErrNotConnected) {
+		t.Fatalf("query after giving up: %v, want ErrNotConnected", err)
+	}
+	if m := client.Metrics(); m.ReconnectAttempts != 2 {
+		t.Fatalf("metrics reconnect attempts = %d, want 2", m.ReconnectAttempts)
+	}
+}
+
+func TestDisconnectWithoutReconnect(t *testing.T) {
+	host := startHost(t)
+	client := connectClient(t, host)
+
+	if err := host.Drop(testManifest.UUID); err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, client, clhplugin.ConnectionStateRemoteClosed)
+	for range client.StateChanges() {
+	}
+	if _, err := client.WaitMessage(testContext(t)); !errors.Is(err, clhplugin.ErrClientClosed) {
+		t.Fatalf("WaitMessage after disconnect: %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	host := startHost(t)
+	client := connectClient(t, host)
+	ctx := testContext(t)
+
+	if err := client.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := host.WaitFor(ctx, func(h *clhtest.Host) bool { return len(h.Plugins()) == 0 }); err != nil {
+		t.Fatal("plugin still registered after Close")
+	}
+	if client.State() != clhplugin.ConnectionStateClosed {
+		t.Fatalf("state = %s", client.State())
+	}
+	if err := client.Close(ctx); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+	if _, err := client.Connect(ctx); !errors.Is(err, clhplugin.ErrClientClosed) {
+		t.Fatalf("Connect after Close: %v", err)
+	}
+}
+
+// levelRecorder is a slog.Handler that keeps the level of every record.
+type levelRecorder struct {
+	mu     sync.Mutex
+	levels []slog.Level
+}
+
+func (r *levelRecorder) Enabled(context.Context, slog.Level) bool { return true }
+func (r *levelRecorder) WithAttrs([]slog.Attr) slog.Handler       { return r }
+func (r *levelRecorder) WithGroup(string) slog.Handler            { return r }
+
+func (r *levelRecorder) Handle(_ context.Context, rec slog.Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.levels = append(r.levels, rec.Level)
+	return nil
+}
+
+func (r *levelRecorder) max() slog.Level {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	max := slog.LevelDebug
+	for _, level := range r.levels {
+		max = maxLevel(max, level)
+	}
+	return max
+}
+
+func maxLevel(a, b slog.Level) slog.Level {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func TestCloseDeregisters(t *testing.T) {
+	events := make(chan clhplugin.PluginLifecycleChanged, 4)
+	server, err := clhplugin.NewServer(clhplugin.WithLifecycleHandler(func(ev clhplugin.PluginLifecycleChanged) {
+		events <- ev
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := clhplugin.NewMemoryListener()
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	logs := &levelRecorder{}
+	client, err := clhplugin.NewClient(testManifest, clhplugin.WithDialer(listener.Dial), clhplugin.WithLogger(slog.New(logs)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := testContext(t)
+	if _, err := client.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		select {
+		case ev := <-events:
+			if ev.EventType != clhplugin.PluginLifecycleEventDisconnected {
+				continue
+			}
+			if ev.Reason != "client-close" {
+				t.Fatalf("disconnect reason = %q, want client-close", ev.Reason)
+			}
+			if level := logs.max(); level >= slog.LevelWarn {
+				t.Fatalf("Close logged at %s", level)
+			}
+			return
+		case <-ctx.Done():
+			t.Fatal("server did not see the plugin disconnect")
+		}
+	}
+}
+
+func TestCloseDuringReconnect(t *testing.T) {
+	listener := clhplugin.NewMemoryListener()
+	host := startHost(t, clhtest.WithListener(listener))
+
+	// After the first connection, dials stall until their context is cancelled.
+	var dials atomic.Int32
+	stalled := make(chan struct{})
+	dialer := func(ctx context.Context) (net.Conn, error) {
+		if dials.Add(1) == 1 {
+			return listener.Dial(ctx)
+		}
+		close(stalled)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	client := newClient(t, host,
+		clhplugin.WithDialer(dialer),
+		clhplugin.WithAutoReconnect(fastReconnect()),
+		clhplugin.WithRequestTimeout(time.Minute),
+	)
+	if _, err := client.Connect(testContext(t)); err != nil {
+		t.Fatal(err)
+	}
+	if err := host.Drop(testManifest.UUID); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stalled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reconnect did not dial")
+	}
+
+	started := time.Now()
+	if err := client.Close(testContext(t)); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("Close took %v behind a stalled dial", elapsed)
+	}
+	if client.State() != clhplugin.ConnectionStateClosed {
+		t.Fatalf("state = %s", client.State())
+	}
+	select {
+	case <-client.Done():
+	case <-time.After(time.Second):
+		t.Fatal("client not finished after Close")
+	}
+}
+
+func TestOrderedDispatchOverflow(t *testing.T) {
+	host := startHost(t)
+	client := connectClient(t, host, clhplugin.WithOrderedDispatch(clhplugin.DispatchConfig{
+		Workers: 1, QueueSize: 1, Overflow: clhplugin.OverflowDropNewest,
+	}))
+	ctx := testContext(t)
+
+	started := make(chan struct{}, 1)
+	gate := make(chan struct{})
+	var (
+		mu    sync.Mutex
+		freqs []uint64
+	)
+	delivered := func() []uint64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(freqs)
+	}
+	client.OnRigData(func(data clhplugin.RigData) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-gate
+		mu.Lock()
+		freqs = append(freqs, data.Frequency)
+		mu.Unlock()
+	})
+	sub := clhplugin.EventSubscription{Topics: []clhplugin.EnvelopeTopic{clhplugin.EnvelopeTopicEventRigData}}
+	if _, err := client.SubscribeEvents(ctx, sub); err != nil {
+		t.Fatal(err)
+	}
+
+	push := func(freq uint64) {
+		t.Helper()
+		if _, err := host.PushRigData(clhplugin.RigData{Frequency: freq}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	push(1)
+	<-started
+	for freq := uint64(2); freq <= 5; freq++ {
+		push(freq)
+	}
+	// 1 is in the handler and 2 fills the queue; 3 to 5 are dropped.
+	waitUntil(t, func() bool { return client.Stats().Callback.Dropped == 3 })
+	close(gate)
+	waitUntil(t, func() bool { return len(delivered()) == 2 })
+
+	if got := delivered(); !slices.Equal(got, []uint64{1, 2}) {
+		t.Fatalf("delivered %v, want [1 2]", got)
+	}
+}
+
+// waitUntil polls cond until it holds.
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not reached")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
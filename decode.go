@@ -0,0 +1,27 @@
+package clhplugin
+
+import (
+	"github.com/SydneyOwl/clh-plugin-go-sdk/ft8"
+	"github.com/SydneyOwl/clh-plugin-go-sdk/grid"
+)
+
+// Parse classifies the decode text. Pass the SpecialOpMode from the latest WsjtxStatus,
+// or SpecialOperationModeNone.
+func (d WsjtxDecode) Parse(mode SpecialOperationMode) ft8.Message {
+	return ft8.Parse(d.Message, ft8.Mode(mode))
+}
+
+// Parse classifies every decode in the batch, preserving order.
+func (p PackedDecodeMessage) Parse(mode SpecialOperationMode) []ft8.Message {
+	out := make([]ft8.Message, 0, len(p.Messages))
+	for _, d := range p.Messages {
+		out = append(out, d.Parse(mode))
+	}
+	return out
+}
+
+// Spot parses the decode and adds the path from myGrid, typically
+// SettingsSnapshot.MyMaidenheadGrid, to the grid it carries.
+func (d WsjtxDecode) Spot(mode SpecialOperationMode, myGrid string) grid.Spot {
+	return grid.Enrich(d.Parse(mode), myGrid)
+}
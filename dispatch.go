@@ -0,0 +1,159 @@
+package clhplugin
+
+import (
+	"errors"
+	"hash/fnv"
+	"strconv"
+	"sync"
+)
+
+const defaultDispatchQueue = 256
+
+type OverflowPolicy string
+
+const (
+	OverflowBlock      OverflowPolicy = "block"
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+	OverflowDropNewest OverflowPolicy = "drop_newest"
+)
+
+func (p OverflowPolicy) validate() error {
+	switch p {
+	case OverflowBlock, OverflowDropOldest, OverflowDropNewest:
+		return nil
+	default:
+		return errors.New("unknown overflow policy: " + string(p))
+	}
+}
+
+// DispatchConfig switches callbacks from one goroutine per message to a fixed set of
+// workers. With one worker every callback runs in arrival order; with more, messages are
+// partitioned by kind and topic so each topic stays ordered.
+//
+// OverflowBlock applies backpressure to the read loop, so a handler that waits on a request
+// response while its queue is full stalls until the request times out.
+type DispatchConfig struct {
+	Workers   int
+	QueueSize int
+	Overflow  OverflowPolicy
+}
+
+// dispatcher delivers inbound messages to OnMessage and the typed router.
+type dispatcher struct {
+	cfg      *DispatchConfig
+	deliver  func(Message)
+	dropped  func(Message)
+	counters queueCounters
+
+	startOnce sync.Once
+	mu        sync.RWMutex
+	closed    bool
+	queues    []chan Message
+	wg        sync.WaitGroup
+
+	// stop unblocks OverflowBlock sends so close does not wait on a stuck handler.
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+func (d *dispatcher) start() {
+	d.startOnce.Do(func() {
+		d.queues = make([]chan Message, d.cfg.Workers)
+		for i := range d.queues {
+			q := make(chan Message, d.cfg.QueueSize)
+			d.queues[i] = q
+			d.wg.Add(1)
+			go d.work(q)
+		}
+	})
+}
+
+func (d *dispatcher) work(q chan Message) {
+	defer d.wg.Done()
+	for msg := range q {
+		d.deliver(msg)
+		d.counters.delivered.Add(1)
+	}
+}
+
+func (d *dispatcher) dispatch(msg Message) {
+	if d.cfg == nil {
+		d.counters.enqueued.Add(1)
+		go func() {
+			d.deliver(msg)
+			d.counters.delivered.Add(1)
+		}()
+		return
+	}
+
+	d.start()
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		d.counters.dropped.Add(1)
+		d.dropped(msg)
+		return
+	}
+
+	q := d.queues[d.partition(msg)]
+	switch d.cfg.Overflow {
+	case OverflowBlock:
+		select {
+		case q <- msg:
+			d.counters.enqueued.Add(1)
+		case <-d.stop:
+			d.counters.dropped.Add(1)
+			d.dropped(msg)
+		}
+	case OverflowDropNewest:
+		select {
+		case q <- msg:
+			d.counters.enqueued.Add(1)
+		default:
+			d.counters.dropped.Add(1)
+			d.dropped(msg)
+		}
+	default:
+		for {
+			select {
+			case q <- msg:
+				d.counters.enqueued.Add(1)
+				return
+			default:
+			}
+			select {
+			case old := <-q:
+				d.counters.dropped.Add(1)
+				d.dropped(old)
+			default:
+			}
+		}
+	}
+}
+
+func (d *dispatcher) partition(msg Message) int {
+	if len(d.queues) == 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(msg.Kind))
+	if msg.Envelope != nil {
+		_, _ = h.Write([]byte(strconv.Itoa(int(msg.Envelope.Topic))))
+	}
+	return int(h.Sum32() % uint32(len(d.queues)))
+}
+
+// close stops accepting messages and lets the workers drain what is already queued. A
+// message blocked on a full queue is dropped.
+func (d *dispatcher) close() {
+	d.stopOnce.Do(func() { close(d.stop) })
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	d.closed = true
+	for _, q := range d.queues {
+		close(q)
+	}
+}
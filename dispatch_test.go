@@ -0,0 +1,148 @@
+package clhplugin
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type dispatchRecorder struct {
+	mu        sync.Mutex
+	delivered []string
+	dropped   []string
+	started   chan struct{}
+	gate      chan struct{}
+}
+
+func newDispatchRecorder(cfg DispatchConfig) (*dispatcher, *dispatchRecorder) {
+	rec := &dispatchRecorder{started: make(chan struct{}, 64), gate: make(chan struct{})}
+	d := &dispatcher{
+		cfg:  &cfg,
+		stop: make(chan struct{}),
+		deliver: func(msg Message) {
+			rec.started <- struct{}{}
+			<-rec.gate
+			rec.mu.Lock()
+			rec.delivered = append(rec.delivered, msg.Envelope.ID)
+			rec.mu.Unlock()
+		},
+		dropped: func(msg Message) {
+			rec.mu.Lock()
+			rec.dropped = append(rec.dropped, msg.Envelope.ID)
+			rec.mu.Unlock()
+		},
+	}
+	return d, rec
+}
+
+func (r *dispatchRecorder) result() (delivered, dropped []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.delivered), slices.Clone(r.dropped)
+}
+
+func testEnvelope(topic EnvelopeTopic, id int) Message {
+	return Message{Kind: InboundKindEnvelope, Envelope: &Envelope{Topic: topic, ID: strconv.Itoa(id)}}
+}
+
+func TestDispatchOverflow(t *testing.T) {
+	tests := []struct {
+		policy    OverflowPolicy
+		delivered []string
+		dropped   []string
+	}{
+		{OverflowDropNewest, []string{"1", "2", "3"}, []string{"4", "5"}},
+		{OverflowDropOldest, []string{"1", "4", "5"}, []string{"2", "3"}},
+	}
+	for _, tt := range tests {
+		d, rec := newDispatchRecorder(DispatchConfig{Workers: 1, QueueSize: 2, Overflow: tt.policy})
+		// The worker holds message 1, so 2 and 3 fill the queue.
+		d.dispatch(testEnvelope(EnvelopeTopicEventRigData, 1))
+		<-rec.started
+		for i := 2; i <= 5; i++ {
+			d.dispatch(testEnvelope(EnvelopeTopicEventRigData, i))
+		}
+		close(rec.gate)
+		d.close()
+		d.wg.Wait()
+
+		delivered, dropped := rec.result()
+		if !slices.Equal(delivered, tt.delivered) || !slices.Equal(dropped, tt.dropped) {
+			t.Errorf("%s: delivered %v dropped %v, want %v and %v", tt.policy, delivered, dropped, tt.delivered, tt.dropped)
+		}
+		stats := d.counters.snapshot()
+		if stats.Dropped != uint64(len(tt.dropped)) || stats.Delivered != uint64(len(tt.delivered)) {
+			t.Errorf("%s: stats %+v", tt.policy, stats)
+		}
+	}
+}
+
+func TestDispatchBlockReleasedByClose(t *testing.T) {
+	d, rec := newDispatchRecorder(DispatchConfig{Workers: 1, QueueSize: 1, Overflow: OverflowBlock})
+	d.dispatch(testEnvelope(EnvelopeTopicEventRigData, 1))
+	<-rec.started
+	d.dispatch(testEnvelope(EnvelopeTopicEventRigData, 2))
+
+	blocked := make(chan struct{})
+	go func() {
+		d.dispatch(testEnvelope(EnvelopeTopicEventRigData, 3))
+		close(blocked)
+	}()
+	select {
+	case <-blocked:
+		t.Fatal("dispatch into a full queue did not block")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		d.close()
+		close(closed)
+	}()
+	for _, ch := range []chan struct{}{blocked, closed} {
+		select {
+		case <-ch:
+		case <-time.After(2 * time.Second):
+			t.Fatal("close did not release the blocked dispatch")
+		}
+	}
+	close(rec.gate)
+	d.wg.Wait()
+
+	delivered, dropped := rec.result()
+	if !slices.Equal(delivered, []string{"1", "2"}) || !slices.Equal(dropped, []string{"3"}) {
+		t.Fatalf("delivered %v dropped %v", delivered, dropped)
+	}
+	d.dispatch(testEnvelope(EnvelopeTopicEventRigData, 4))
+	if _, dropped := rec.result(); !slices.Equal(dropped, []string{"3", "4"}) {
+		t.Fatalf("dispatch after close: dropped %v", dropped)
+	}
+}
+
+func TestDispatchTopicOrder(t *testing.T) {
+	d, rec := newDispatchRecorder(DispatchConfig{Workers: 4, QueueSize: 64, Overflow: OverflowBlock})
+	close(rec.gate)
+	topics := []EnvelopeTopic{EnvelopeTopicEventRigData, EnvelopeTopicEventServerStatus, EnvelopeTopicEventWsjtxMessage}
+	var mu sync.Mutex
+	perTopic := map[EnvelopeTopic][]int{}
+	d.deliver = func(msg Message) {
+		id, _ := strconv.Atoi(msg.Envelope.ID)
+		mu.Lock()
+		perTopic[msg.Envelope.Topic] = append(perTopic[msg.Envelope.Topic], id)
+		mu.Unlock()
+	}
+	for i := 0; i < 150; i++ {
+		d.dispatch(testEnvelope(topics[i%len(topics)], i))
+	}
+	d.close()
+	d.wg.Wait()
+
+	for _, topic := range topics {
+		ids := perTopic[topic]
+		if len(ids) != 50 || !slices.IsSorted(ids) {
+			t.Errorf("topic %d delivered out of order: %v", topic, ids)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package dxcc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var ErrNoEntities = errors.New("dxcc: no entities in country file")
+
+// LoadFile loads a cty.dat or cty.csv file.
+func LoadFile(path string) (*Resolver, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Load reads cty.dat or cty.csv, telling them apart by the first line.
+func Load(r io.Reader) (*Resolver, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	first, _, _ := bytes.Cut(bytes.TrimSpace(data), []byte("\n"))
+	if bytes.Count(first, []byte(",")) >= 9 && !bytes.Contains(first, []byte(":")) {
+		return ParseCSV(bytes.NewReader(data))
+	}
+	return ParseDat(bytes.NewReader(data))
+}
+
+// ParseDat reads the cty.dat format: a colon-separated entity header followed by its
+// comma-separated prefixes, terminated by a semicolon.
+func ParseDat(r io.Reader) (*Resolver, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	res := newResolver()
+	for i, record := range strings.Split(string(data), ";") {
+		if strings.TrimSpace(record) == "" {
+			continue
+		}
+		fields := strings.SplitN(record, ":", 9)
+		if len(fields) != 9 {
+			return nil, fmt.Errorf("dxcc: cty.dat record %d: expected 8 header fields", i+1)
+		}
+		e, err := parseEntity(fields[0], "", fields[1], fields[2], fields[3], fields[4], fields[5], fields[6], fields[7])
+		if err != nil {
+			return nil, fmt.Errorf("dxcc: cty.dat record %d: %w", i+1, err)
+		}
+		res.add(e, strings.Split(fields[8], ","))
+	}
+	if len(res.entities) == 0 {
+		return nil, ErrNoEntities
+	}
+	return res, nil
+}
+
+// ParseCSV reads the cty.csv format: primary prefix, name, DXCC number, continent, CQ
+// zone, ITU zone, latitude, longitude, GMT offset and space-separated prefixes.
+func ParseCSV(r io.Reader) (*Resolver, error) {
+	res := newResolver()
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 4<<20)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		cols := strings.SplitN(text, ",", 10)
+		if len(cols) != 10 {
+			return nil, fmt.Errorf("dxcc: cty.csv line %d: expected 10 columns", line)
+		}
+		e, err := parseEntity(cols[1], cols[2], cols[4], cols[5], cols[3], cols[6], cols[7], cols[8], cols[0])
+		if err != nil {
+			return nil, fmt.Errorf("dxcc: cty.csv line %d: %w", line, err)
+		}
+		res.add(e, strings.Fields(strings.TrimSuffix(cols[9], ";")))
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(res.entities) == 0 {
+		return nil, ErrNoEntities
+	}
+	return res, nil
+}
+
+func parseEntity(name, dxcc, cq, itu, cont, lat, lon, tz, prefix string) (Entity, error) {
+	e := Entity{
+		Name:      strings.TrimSpace(name),
+		DXCC:      strings.TrimSpace(dxcc),
+		Continent: strings.TrimSpace(cont),
+	}
+	var err error
+	if e.CQZone, err = parseZone(cq); err != nil {
+		return Entity{}, err
+	}
+	if e.ITUZone, err = parseZone(itu); err != nil {
+		return Entity{}, err
+	}
+	if e.Latitude, err = parseFloat(lat); err != nil {
+		return Entity{}, err
+	}
+	if e.Longitude, err = parseFloat(lon); err != nil {
+		return Entity{}, err
+	}
+	if e.GMTOffset, err = parseFloat(tz); err != nil {
+		return Entity{}, err
+	}
+	e.Longitude, e.GMTOffset = -e.Longitude, -e.GMTOffset
+
+	prefix = strings.TrimSpace(prefix)
+	e.PrimaryPrefix, e.WAE = strings.CutPrefix(prefix, "*")
+	if e.PrimaryPrefix == "" {
+		return Entity{}, errors.New("missing primary prefix")
+	}
+	return e, nil
+}
+
+func parseZone(s string) (int32, error) {
+	v, err := strconv.ParseInt(strings.TrimSpace(s), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid zone %q", strings.TrimSpace(s))
+	}
+	return int32(v), nil
+}
+
+func parseFloat(s string) (float32, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", strings.TrimSpace(s))
+	}
+	return float32(v), nil
+}
+
+// add registers e and its aliases. An alias starting with = is an exact call; any alias
+// may carry overrides: (CQ zone), [ITU zone], <lat/lon>, {continent} and ~GMT offset~.
+func (r *Resolver) add(e Entity, aliases []string) {
+	r.entities = append(r.entities, e)
+	for _, raw := range aliases {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		exact := strings.HasPrefix(raw, "=")
+		key, ae := applyOverrides(strings.TrimPrefix(raw, "="), e)
+		if key == "" {
+			continue
+		}
+		if exact {
+			r.exact[key] = ae
+		} else {
+			r.addPrefix(key, ae)
+		}
+	}
+}
+
+func applyOverrides(raw string, e Entity) (string, Entity) {
+	key := raw
+	if i := strings.IndexAny(raw, "([<{~"); i >= 0 {
+		key = raw[:i]
+	}
+	if v, ok := between(raw, '(', ')'); ok {
+		if z, err := parseZone(v); err == nil {
+			e.CQZone = z
+		}
+	}
+	if v, ok := between(raw, '[', ']'); ok {
+		if z, err := parseZone(v); err == nil {
+			e.ITUZone = z
+		}
+	}
+	if v, ok := between(raw, '<', '>'); ok {
+		if lat, lon, found := strings.Cut(v, "/"); found {
+			la, err1 := parseFloat(lat)
+			lo, err2 := parseFloat(lon)
+			if err1 == nil && err2 == nil {
+				e.Latitude, e.Longitude = la, -lo
+			}
+		}
+	}
+	if v, ok := between(raw, '{', '}'); ok {
+		e.Continent = v
+	}
+	if v, ok := between(raw, '~', '~'); ok {
+		if tz, err := parseFloat(v); err == nil {
+			e.GMTOffset = -tz
+		}
+	}
+	return strings.ToUpper(key), e
+}
+
+func between(s string, open, close byte) (string, bool) {
+	i := strings.IndexByte(s, open)
+	if i < 0 {
+		return "", false
+	}
+	j := strings.IndexByte(s[i+1:], close)
+	if j < 0 {
+		return "", false
+	}
+	return s[i+1 : i+1+j], true
+}
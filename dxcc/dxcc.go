@@ -0,0 +1,151 @@
+// Package dxcc resolves callsigns to DXCC entities offline, from the AD1C country files
+// (cty.dat or cty.csv, https://www.country-files.com). No data is bundled; load the
+// current file with LoadFile or Load.
+//
+//	r, _ := dxcc.LoadFile("cty.csv")
+//	e, ok := r.Resolve("VE3/BH1XYZ/P") // e.Name == "Canada"
+package dxcc
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Entity mirrors the location fields of clhplugin.QSODetail. Latitude and Longitude are
+// degrees with north and east positive, and GMTOffset is hours east of UTC; the country
+// files store both west-positive and are converted on load.
+type Entity struct {
+	Name          string
+	DXCC          string // entity number; only cty.csv carries it
+	CQZone        int32
+	ITUZone       int32
+	Continent     string
+	Latitude      float32
+	Longitude     float32
+	GMTOffset     float32
+	PrimaryPrefix string
+	// WAE marks entities that only count for the DARC WAE award (a * in the file).
+	WAE bool
+}
+
+// Resolver maps callsigns to entities. It is safe for concurrent use.
+type Resolver struct {
+	entities     []Entity
+	prefixes     map[string]Entity // overrides of the prefix applied
+	exact        map[string]Entity
+	maxPrefixLen int
+}
+
+func newResolver() *Resolver {
+	return &Resolver{prefixes: map[string]Entity{}, exact: map[string]Entity{}}
+}
+
+// Entities returns every entity in file order.
+func (r *Resolver) Entities() []Entity {
+	return append([]Entity(nil), r.entities...)
+}
+
+func (r *Resolver) addPrefix(prefix string, e Entity) {
+	r.prefixes[prefix] = e
+	if len(prefix) > r.maxPrefixLen {
+		r.maxPrefixLen = len(prefix)
+	}
+}
+
+// Resolve returns the DXCC entity for call. Exact-call exceptions win over prefixes;
+// portable forms such as VE3/BH1XYZ, BH1XYZ/P and W1ABC/4 are handled. Maritime and
+// aeronautical mobile (/MM, /AM) have no entity. WAE-only entities are skipped in favour
+// of the DXCC entity they belong to.
+func (r *Resolver) Resolve(call string) (Entity, bool) {
+	return r.resolve(call, false)
+}
+
+// ResolveWAE is Resolve including WAE-only entities such as European Turkey.
+func (r *Resolver) ResolveWAE(call string) (Entity, bool) {
+	return r.resolve(call, true)
+}
+
+func (r *Resolver) resolve(call string, wae bool) (Entity, bool) {
+	call = normalizeCall(call)
+	if call == "" {
+		return Entity{}, false
+	}
+	if e, ok := r.exact[call]; ok && (wae || !e.WAE) {
+		return e, true
+	}
+
+	parts := strings.Split(call, "/")
+	var kept []string
+	for i, p := range parts {
+		switch {
+		case p == "":
+		case i > 0 && (p == "MM" || p == "AM"):
+			return Entity{}, false
+		case i > 0 && ignoredSuffixes[p]:
+		default:
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) == 0 {
+		return Entity{}, false
+	}
+	if e, ok := r.exact[strings.Join(kept, "/")]; ok && (wae || !e.WAE) {
+		return e, true
+	}
+	return r.matchPrefix(prefixPart(kept), wae)
+}
+
+// ignoredSuffixes do not change the entity.
+var ignoredSuffixes = map[string]bool{
+	"P": true, "M": true, "A": true, "QRP": true, "QRPP": true, "LH": true,
+	"LGT": true, "B": true, "J": true, "R": true, "N": true, "T": true,
+}
+
+// prefixPart picks the string to match against the prefix table: the shorter of
+// prefix/call, or the call with its area digit replaced for call/digit.
+func prefixPart(parts []string) string {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	a, b := parts[0], parts[len(parts)-1]
+	if len(b) == 1 && b[0] >= '0' && b[0] <= '9' {
+		return replaceAreaDigit(a, b[0])
+	}
+	if len(b) < len(a) {
+		return b
+	}
+	return a
+}
+
+// replaceAreaDigit swaps the first digit run after the leading character: W1ABC/4 -> W4ABC.
+func replaceAreaDigit(call string, digit byte) string {
+	for i := 1; i < len(call); i++ {
+		if call[i] >= '0' && call[i] <= '9' {
+			j := i
+			for j < len(call) && call[j] >= '0' && call[j] <= '9' {
+				j++
+			}
+			return call[:i] + string(digit) + call[j:]
+		}
+	}
+	return call
+}
+
+func (r *Resolver) matchPrefix(s string, wae bool) (Entity, bool) {
+	for n := min(len(s), r.maxPrefixLen); n > 0; n-- {
+		if e, ok := r.prefixes[s[:n]]; ok && (wae || !e.WAE) {
+			return e, true
+		}
+	}
+	return Entity{}, false
+}
+
+// normalizeCall upper-cases call and strips FT8 hash brackets and surrounding spaces.
+func normalizeCall(call string) string {
+	call = strings.TrimSpace(call)
+	call = strings.TrimSuffix(strings.TrimPrefix(call, "<"), ">")
+	if strings.IndexFunc(call, unicode.IsSpace) >= 0 {
+		return ""
+	}
+	return strings.ToUpper(call)
+}
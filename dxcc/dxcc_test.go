@@ -0,0 +1,145 @@
+package dxcc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const ctyDat = `Canada:                   05:  09:  NA:   44.35:    78.75:     5.0:  VE:
+    CY0,CY9,VA,VE,VO1(5)[9],=VE3ABC(4);
+China:                    24:  44:  AS:   36.00:  -102.00:    -8.0:  BY:
+    BA,BD,BG,BH,BY,BY0<31.0/-91.0>~-6.0~;
+European Turkey:          20:  39:  EU:   41.02:   -28.97:    -2.0:  *TA1:
+    TA1,YM1;
+Asiatic Turkey:           20:  39:  AS:   39.18:   -35.65:    -2.0:  TA:
+    TA,TB,TC,YM;
+United States:            05:  08:  NA:   37.53:    91.67:     5.0:  K:
+    AA,K,N,W,=W1AW/4{SA};
+`
+
+const ctyCSV = `VE,Canada,1,NA,5,9,44.35,78.75,5.0,CY0 CY9 VA VE VO1(5)[9] =VE3ABC(4);
+BY,China,318,AS,24,44,36.00,-102.00,-8.0,BA BD BG BH BY BY0<31.0/-91.0>~-6.0~;
+*TA1,European Turkey,390,EU,20,39,41.02,-28.97,-2.0,TA1 YM1;
+TA,Asiatic Turkey,390,AS,20,39,39.18,-35.65,-2.0,TA TB TC YM;
+K,United States,291,NA,5,8,37.53,91.67,5.0,AA K N W =W1AW/4{SA};
+`
+
+func TestResolve(t *testing.T) {
+	for _, format := range []struct {
+		name string
+		data string
+	}{{"cty.dat", ctyDat}, {"cty.csv", ctyCSV}} {
+		r, err := Load(strings.NewReader(format.data))
+		if err != nil {
+			t.Fatalf("%s: %v", format.name, err)
+		}
+		if n := len(r.Entities()); n != 5 {
+			t.Fatalf("%s: %d entities, want 5", format.name, n)
+		}
+
+		tests := []struct {
+			call      string
+			wae       bool
+			name      string
+			cq        int32
+			itu       int32
+			continent string
+		}{
+			{"BH1XYZ", false, "China", 24, 44, "AS"},
+			{"<bh1xyz>", false, "China", 24, 44, "AS"},
+			{"VE3/BH1XYZ/P", false, "Canada", 5, 9, "NA"},
+			{"VO1ABC", false, "Canada", 5, 9, "NA"},
+			{"VE3ABC", false, "Canada", 4, 9, "NA"},
+			{"VE3ABC/QRP", false, "Canada", 4, 9, "NA"},
+			{"W1ABC/4", false, "United States", 5, 8, "NA"},
+			{"W1AW/4", false, "United States", 5, 8, "SA"},
+			{"TA1ABC", false, "Asiatic Turkey", 20, 39, "AS"},
+			{"TA1ABC", true, "European Turkey", 20, 39, "EU"},
+			{"TA2ABC", true, "Asiatic Turkey", 20, 39, "AS"},
+		}
+		for _, tt := range tests {
+			resolve := r.Resolve
+			if tt.wae {
+				resolve = r.ResolveWAE
+			}
+			e, ok := resolve(tt.call)
+			if !ok || e.Name != tt.name || e.CQZone != tt.cq || e.ITUZone != tt.itu || e.Continent != tt.continent {
+				t.Errorf("%s: resolve(%q, wae=%v) = %+v, %v, want %s", format.name, tt.call, tt.wae, e, ok, tt.name)
+			}
+		}
+		for _, call := range []string{"", "K1ABC/MM", "W1ABC/AM", "ZZ9ZZ", "BH1 XYZ"} {
+			if e, ok := r.Resolve(call); ok {
+				t.Errorf("%s: Resolve(%q) = %+v", format.name, call, e)
+			}
+		}
+
+		china, _ := r.Resolve("BH1XYZ")
+		if china.Longitude != 102 || china.GMTOffset != 8 || china.PrimaryPrefix != "BY" {
+			t.Errorf("%s: China = %+v, want east-positive longitude and offset", format.name, china)
+		}
+		tibet, _ := r.Resolve("BY0ABC")
+		if tibet.Latitude != 31 || tibet.Longitude != 91 || tibet.GMTOffset != 6 {
+			t.Errorf("%s: BY0 overrides = %+v", format.name, tibet)
+		}
+		eu, _ := r.ResolveWAE("YM1ABC")
+		if !eu.WAE || eu.PrimaryPrefix != "TA1" {
+			t.Errorf("%s: European Turkey = %+v", format.name, eu)
+		}
+	}
+}
+
+func TestDXCCNumber(t *testing.T) {
+	r, err := ParseCSV(strings.NewReader(ctyCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := r.Resolve("BH1XYZ"); e.DXCC != "318" {
+		t.Errorf("DXCC = %q, want 318", e.DXCC)
+	}
+	r, err = ParseDat(strings.NewReader(ctyDat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := r.Resolve("BH1XYZ"); e.DXCC != "" {
+		t.Errorf("cty.dat DXCC = %q, want empty", e.DXCC)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cty.dat")
+	if err := os.WriteFile(path, []byte(ctyDat), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := r.Resolve("K1ABC"); !ok || e.Name != "United States" {
+		t.Errorf("Resolve(K1ABC) = %+v, %v", e, ok)
+	}
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("LoadFile of a missing file succeeded")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := Load(strings.NewReader("  \n")); !errors.Is(err, ErrNoEntities) {
+		t.Errorf("empty file: %v, want ErrNoEntities", err)
+	}
+	bad := []string{
+		"Canada: 05: 09: NA: 44.35: 78.75: VE:\n    VE;",
+		"Canada: x5: 09: NA: 44.35: 78.75: 5.0: VE:\n    VE;",
+		"Canada: 05: 09: NA: 44.35: 78.75: 5.0: :\n    VE;",
+	}
+	for _, data := range bad {
+		if _, err := ParseDat(strings.NewReader(data)); err == nil {
+			t.Errorf("ParseDat(%q) succeeded", data)
+		}
+	}
+	if _, err := ParseCSV(strings.NewReader("VE,Canada,1,NA,5,9\n")); err == nil {
+		t.Error("ParseCSV with missing columns succeeded")
+	}
+}
@@ -0,0 +1,16 @@
+package clhplugin
+
+import "github.com/SydneyOwl/clh-plugin-go-sdk/dxcc"
+
+// SetEntity fills the country fields CLH sets when it logs a QSO from a resolved entity,
+// e.g. to annotate live traffic the same way.
+func (d *QSODetail) SetEntity(e dxcc.Entity) {
+	d.OriginalCountryName = e.Name
+	d.DXCC = e.DXCC
+	d.CQZone = e.CQZone
+	d.ITUZone = e.ITUZone
+	d.Continent = e.Continent
+	d.Latitude = e.Latitude
+	d.Longitude = e.Longitude
+	d.GMTOffset = e.GMTOffset
+}
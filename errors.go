@@ -9,6 +9,14 @@ var (
 	ErrClientClosed    = errors.New("client is closed")
 	ErrNotConnected    = errors.New("client is not connected")
 	ErrInvalidManifest = errors.New("invalid plugin manifest")
+	ErrConnectionLost  = errors.New("connection lost")
+	ErrServerClosed    = errors.New("server is closed")
+	ErrNoQSOIDs        = errors.New("at least one qso id is required")
+
+	ErrInvalidWsjtxMagic      = errors.New("invalid wsjtx magic number")
+	ErrUnsupportedWsjtxSchema = errors.New("unsupported wsjtx schema")
+
+	ErrHeartbeatTimeout = errors.New("no response from clh within keepalive window")
 )
 
 type RemoteError struct {
@@ -0,0 +1,107 @@
+package ft8
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	baseCallRe  = regexp.MustCompile(`^[A-Z0-9]{1,3}[0-9][A-Z0-9]{0,3}[A-Z]$`)
+	affixRe     = regexp.MustCompile(`^[A-Z0-9]{1,4}$`)
+	grid4Re     = regexp.MustCompile(`^[A-R]{2}[0-9]{2}$`)
+	grid6Re     = regexp.MustCompile(`^[A-R]{2}[0-9]{2}[A-X]{2}$`)
+	reportRe    = regexp.MustCompile(`^[+-][0-9]{2}$`)
+	cqModRe     = regexp.MustCompile(`^([A-Z]{1,4}|[0-9]{3})$`)
+	fdClassRe   = regexp.MustCompile(`^[0-9]{1,2}[A-F]$`)
+	sectionRe   = regexp.MustCompile(`^[A-Z]{2,3}$`)
+	rstRe       = regexp.MustCompile(`^5[0-9]9$`)
+	serialRe    = regexp.MustCompile(`^[0-9]{1,4}$`)
+	euVHFExchRe = regexp.MustCompile(`^[0-9]{6}$`)
+)
+
+var portableSuffixes = map[string]bool{"P": true, "M": true, "MM": true, "AM": true, "QRP": true}
+
+// IsCallsign reports whether s looks like an amateur callsign, including compound forms
+// such as VE3/W1AW or W1AW/P. Hashed callsigns in angle brackets are not accepted.
+func IsCallsign(s string) bool {
+	if len(s) < 3 || len(s) > 13 {
+		return false
+	}
+	parts := strings.Split(s, "/")
+	if len(parts) > 3 {
+		return false
+	}
+	base := false
+	for _, part := range parts {
+		switch {
+		case baseCallRe.MatchString(part):
+			base = true
+		case !affixRe.MatchString(part):
+			return false
+		}
+	}
+	return base
+}
+
+// BaseCall strips prefixes, suffixes and hash brackets from a compound callsign:
+// KH1/KH7Z -> KH7Z, W1AW/P -> W1AW, <PA3XYZ> -> PA3XYZ.
+func BaseCall(call string) string {
+	call = strings.TrimSuffix(strings.TrimPrefix(call, "<"), ">")
+	best := ""
+	for _, part := range strings.Split(call, "/") {
+		if baseCallRe.MatchString(part) && len(part) > len(best) {
+			best = part
+		}
+	}
+	if best == "" {
+		return call
+	}
+	return best
+}
+
+// IsPortable reports whether call carries a portable, mobile or call-area suffix.
+func IsPortable(call string) bool {
+	idx := strings.LastIndex(call, "/")
+	if idx < 0 {
+		return false
+	}
+	suffix := call[idx+1:]
+	if portableSuffixes[suffix] {
+		return true
+	}
+	return len(suffix) == 1 && suffix[0] >= '0' && suffix[0] <= '9'
+}
+
+// IsGrid reports whether s is a 4 or 6 character Maidenhead locator. RR73 is excluded
+// since FT8 uses it as a sign-off.
+func IsGrid(s string) bool {
+	return isGrid4(s) || grid6Re.MatchString(s)
+}
+
+func isGrid4(s string) bool {
+	return s != "RR73" && grid4Re.MatchString(s)
+}
+
+func parseReport(s string) (int, bool) {
+	if !reportRe.MatchString(s) {
+		return 0, false
+	}
+	n := int(s[1]-'0')*10 + int(s[2]-'0')
+	if s[0] == '-' {
+		n = -n
+	}
+	return n, true
+}
+
+// parseCallToken accepts a plain callsign or a hashed one. <...> is an unresolved hash and
+// yields an empty call.
+func parseCallToken(s string) (call string, hashed, ok bool) {
+	if strings.HasPrefix(s, "<") && strings.HasSuffix(s, ">") && len(s) >= 2 {
+		inner := s[1 : len(s)-1]
+		if inner == "..." {
+			return "", true, true
+		}
+		return inner, true, IsCallsign(inner)
+	}
+	return s, false, IsCallsign(s)
+}
@@ -0,0 +1,268 @@
+// Package ft8 parses the free-form text of FT8/FT4 decodes (WsjtxDecode.Message) into
+// structured messages.
+//
+//	msg := ft8.Parse("JA1ABC BH1XYZ R-12", ft8.ModeNone)
+//	// msg.Kind == ft8.KindRogerReport, msg.From == "BH1XYZ", msg.Report == -12
+package ft8
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Mode selects contest-specific exchanges. The values match
+// clhplugin.SpecialOperationMode, so ft8.Mode(status.SpecialOpMode) converts directly.
+type Mode int32
+
+const (
+	ModeNone        Mode = 0
+	ModeNAVHF       Mode = 1
+	ModeEUVHF       Mode = 2
+	ModeFieldDay    Mode = 3
+	ModeRTTYRoundup Mode = 4
+	ModeWWDigi      Mode = 5
+	ModeFox         Mode = 6
+	ModeHound       Mode = 7
+)
+
+type Kind string
+
+const (
+	KindCQ          Kind = "cq"
+	KindCalls       Kind = "calls"
+	KindGrid        Kind = "grid"
+	KindReport      Kind = "report"
+	KindRogerReport Kind = "roger_report"
+	KindRR73        Kind = "rr73"
+	KindRRR         Kind = "rrr"
+	Kind73          Kind = "73"
+	KindContest     Kind = "contest"
+	KindFreeText    Kind = "free_text"
+)
+
+// Exchange holds the contest fields of a KindContest message, or of a grid message sent
+// in NA VHF or WW Digi mode.
+type Exchange struct {
+	Mode    Mode
+	Class   string // Field Day class, e.g. 3A
+	Section string // Field Day ARRL/RAC section
+	RST     string // RTTY Roundup and EU VHF
+	Serial  int    // RTTY Roundup and EU VHF serial number, 0 if absent
+	State   string // RTTY Roundup state, province or DX
+}
+
+// Message is one parsed decode. To and From are the callsigns as sent, with hash
+// brackets removed; an unresolved hash <...> leaves the call empty and the Hashed flag set.
+type Message struct {
+	Raw        string
+	Kind       Kind
+	IsCQ       bool
+	CQModifier string // DX, POTA, NA, a 3-digit frequency, ...
+	To         string
+	From       string
+	ToHashed   bool
+	FromHashed bool
+	Grid       string
+	Report     int
+	HasReport  bool
+	Roger      bool // R-report, R grid, R exchange, RRR or RR73
+	ThankYou   bool // RTTY Roundup "TU;" prefix
+	Exchange   *Exchange
+	// Completed lists the stations a Fox sign-off (RR73) went to in a multi-stream message
+	// such as "K1ABC RR73; W9XYZ <KH1/KH7Z> -08".
+	Completed []string
+}
+
+// Parse classifies text. The mode is needed to tag NA VHF and WW Digi grid exchanges;
+// other contest formats are recognised from their syntax.
+func Parse(text string, mode Mode) Message {
+	norm := strings.ToUpper(strings.Join(strings.Fields(text), " "))
+	msg := Message{Raw: text, Kind: KindFreeText}
+	if norm == "" {
+		return msg
+	}
+
+	if rest, ok := strings.CutPrefix(norm, "TU; "); ok {
+		out := parseTokens(strings.Fields(rest), mode)
+		if out.Kind == KindFreeText {
+			return msg
+		}
+		out.Raw = text
+		out.ThankYou = true
+		return out
+	}
+	if strings.Contains(norm, ";") {
+		out, ok := parseMultiStream(norm, mode)
+		if !ok {
+			return msg
+		}
+		out.Raw = text
+		return out
+	}
+
+	out := parseTokens(strings.Fields(norm), mode)
+	out.Raw = text
+	return out
+}
+
+func parseTokens(tokens []string, mode Mode) Message {
+	if len(tokens) > 0 && tokens[0] == "CQ" {
+		return parseCQ(tokens[1:])
+	}
+	return parseDirected(tokens, mode)
+}
+
+func parseCQ(rest []string) Message {
+	msg := Message{Kind: KindCQ, IsCQ: true}
+	if len(rest) >= 2 && cqModRe.MatchString(rest[0]) {
+		msg.CQModifier = rest[0]
+		rest = rest[1:]
+	}
+	if len(rest) == 0 || len(rest) > 2 {
+		return Message{Kind: KindFreeText}
+	}
+
+	call, hashed, ok := parseCallToken(rest[0])
+	if !ok {
+		return Message{Kind: KindFreeText}
+	}
+	msg.From, msg.FromHashed = call, hashed
+	if len(rest) == 2 {
+		if !isGrid4(rest[1]) {
+			return Message{Kind: KindFreeText}
+		}
+		msg.Grid = rest[1]
+	}
+	return msg
+}
+
+func parseDirected(tokens []string, mode Mode) Message {
+	if len(tokens) < 2 {
+		return Message{Kind: KindFreeText}
+	}
+	to, toHashed, ok := parseCallToken(tokens[0])
+	if !ok {
+		return Message{Kind: KindFreeText}
+	}
+	from, fromHashed, ok := parseCallToken(tokens[1])
+	if !ok {
+		return Message{Kind: KindFreeText}
+	}
+
+	msg := Message{To: to, ToHashed: toHashed, From: from, FromHashed: fromHashed}
+	rest := tokens[2:]
+	if len(rest) > 1 && rest[0] == "R" {
+		msg.Roger = true
+		rest = rest[1:]
+	}
+
+	switch len(rest) {
+	case 0:
+		if msg.Roger {
+			return Message{Kind: KindFreeText}
+		}
+		msg.Kind = KindCalls
+	case 1:
+		if !applySingle(&msg, rest[0], mode) {
+			return Message{Kind: KindFreeText}
+		}
+	case 2:
+		if !applyExchange(&msg, rest[0], rest[1]) {
+			return Message{Kind: KindFreeText}
+		}
+	default:
+		return Message{Kind: KindFreeText}
+	}
+	return msg
+}
+
+func applySingle(msg *Message, tok string, mode Mode) bool {
+	if msg.Roger {
+		// Only "R <grid>" is valid after a standalone R.
+		if !isGrid4(tok) {
+			return false
+		}
+		applyGrid(msg, tok, mode)
+		return true
+	}
+
+	switch tok {
+	case "RR73":
+		msg.Kind, msg.Roger = KindRR73, true
+		return true
+	case "RRR":
+		msg.Kind, msg.Roger = KindRRR, true
+		return true
+	case "73":
+		msg.Kind = Kind73
+		return true
+	}
+	if isGrid4(tok) {
+		applyGrid(msg, tok, mode)
+		return true
+	}
+	if n, ok := parseReport(tok); ok {
+		msg.Kind, msg.Report, msg.HasReport = KindReport, n, true
+		return true
+	}
+	if strings.HasPrefix(tok, "R") {
+		if n, ok := parseReport(tok[1:]); ok {
+			msg.Kind, msg.Report, msg.HasReport, msg.Roger = KindRogerReport, n, true, true
+			return true
+		}
+	}
+	return false
+}
+
+func applyGrid(msg *Message, grid string, mode Mode) {
+	msg.Kind, msg.Grid = KindGrid, grid
+	if mode == ModeNAVHF || mode == ModeWWDigi {
+		msg.Exchange = &Exchange{Mode: mode}
+	}
+}
+
+// applyExchange recognises the two-field contest exchanges: Field Day (class, section),
+// RTTY Roundup (RST, serial or state) and EU VHF (RST+serial, 6-char grid).
+func applyExchange(msg *Message, a, b string) bool {
+	switch {
+	case fdClassRe.MatchString(a) && sectionRe.MatchString(b):
+		msg.Exchange = &Exchange{Mode: ModeFieldDay, Class: a, Section: b}
+	case rstRe.MatchString(a) && serialRe.MatchString(b):
+		serial, _ := strconv.Atoi(b)
+		msg.Exchange = &Exchange{Mode: ModeRTTYRoundup, RST: a, Serial: serial}
+	case rstRe.MatchString(a) && sectionRe.MatchString(b):
+		msg.Exchange = &Exchange{Mode: ModeRTTYRoundup, RST: a, State: b}
+	case euVHFExchRe.MatchString(a) && grid6Re.MatchString(b):
+		serial, _ := strconv.Atoi(a[2:])
+		msg.Exchange = &Exchange{Mode: ModeEUVHF, RST: a[:2], Serial: serial}
+		msg.Grid = b
+	default:
+		return false
+	}
+	msg.Kind = KindContest
+	return true
+}
+
+// parseMultiStream handles Fox messages that complete one QSO and continue another:
+// "K1ABC RR73; W9XYZ <KH1/KH7Z> -08".
+func parseMultiStream(norm string, mode Mode) (Message, bool) {
+	first, second, ok := strings.Cut(norm, ";")
+	if !ok {
+		return Message{}, false
+	}
+	head := strings.Fields(first)
+	if len(head) != 2 || head[1] != "RR73" {
+		return Message{}, false
+	}
+	done, _, ok := parseCallToken(head[0])
+	if !ok {
+		return Message{}, false
+	}
+
+	msg := parseDirected(strings.Fields(second), mode)
+	if msg.Kind == KindFreeText {
+		return Message{}, false
+	}
+	msg.Completed = []string{done}
+	return msg, true
+}
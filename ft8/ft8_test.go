@@ -0,0 +1,90 @@
+package ft8
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text string
+		mode Mode
+		want Message
+	}{
+		{"CQ BH1XYZ OM89", ModeNone, Message{Kind: KindCQ, IsCQ: true, From: "BH1XYZ", Grid: "OM89"}},
+		{"CQ DX BH1XYZ OM89", ModeNone, Message{Kind: KindCQ, IsCQ: true, CQModifier: "DX", From: "BH1XYZ", Grid: "OM89"}},
+		{"CQ 145 K1ABC FN42", ModeNone, Message{Kind: KindCQ, IsCQ: true, CQModifier: "145", From: "K1ABC", Grid: "FN42"}},
+		{"  cq  bh1xyz  om89 ", ModeNone, Message{Kind: KindCQ, IsCQ: true, From: "BH1XYZ", Grid: "OM89"}},
+		{"JA1ABC BH1XYZ", ModeNone, Message{Kind: KindCalls, To: "JA1ABC", From: "BH1XYZ"}},
+		{"JA1ABC BH1XYZ OM89", ModeNone, Message{Kind: KindGrid, To: "JA1ABC", From: "BH1XYZ", Grid: "OM89"}},
+		{"JA1ABC BH1XYZ -12", ModeNone, Message{Kind: KindReport, To: "JA1ABC", From: "BH1XYZ", Report: -12, HasReport: true}},
+		{"JA1ABC BH1XYZ R+05", ModeNone, Message{Kind: KindRogerReport, To: "JA1ABC", From: "BH1XYZ", Report: 5, HasReport: true, Roger: true}},
+		{"JA1ABC BH1XYZ RRR", ModeNone, Message{Kind: KindRRR, To: "JA1ABC", From: "BH1XYZ", Roger: true}},
+		{"JA1ABC BH1XYZ RR73", ModeNone, Message{Kind: KindRR73, To: "JA1ABC", From: "BH1XYZ", Roger: true}},
+		{"JA1ABC BH1XYZ 73", ModeNone, Message{Kind: Kind73, To: "JA1ABC", From: "BH1XYZ"}},
+		{"<PJ4/K1ABC> W9XYZ", ModeNone, Message{Kind: KindCalls, To: "PJ4/K1ABC", ToHashed: true, From: "W9XYZ"}},
+		{"W9XYZ <...> -10", ModeNone, Message{Kind: KindReport, To: "W9XYZ", FromHashed: true, Report: -10, HasReport: true}},
+		{"K1ABC W9XYZ 6A WI", ModeNone, Message{Kind: KindContest, To: "K1ABC", From: "W9XYZ",
+			Exchange: &Exchange{Mode: ModeFieldDay, Class: "6A", Section: "WI"}}},
+		{"K1ABC W9XYZ R 2B EMA", ModeNone, Message{Kind: KindContest, To: "K1ABC", From: "W9XYZ", Roger: true,
+			Exchange: &Exchange{Mode: ModeFieldDay, Class: "2B", Section: "EMA"}}},
+		{"K1ABC W9XYZ 579 MA", ModeNone, Message{Kind: KindContest, To: "K1ABC", From: "W9XYZ",
+			Exchange: &Exchange{Mode: ModeRTTYRoundup, RST: "579", State: "MA"}}},
+		{"TU; K1ABC W9XYZ 579 0012", ModeNone, Message{Kind: KindContest, To: "K1ABC", From: "W9XYZ", ThankYou: true,
+			Exchange: &Exchange{Mode: ModeRTTYRoundup, RST: "579", Serial: 12}}},
+		{"K1ABC W9XYZ 570123 IO91NP", ModeNone, Message{Kind: KindContest, To: "K1ABC", From: "W9XYZ", Grid: "IO91NP",
+			Exchange: &Exchange{Mode: ModeEUVHF, RST: "57", Serial: 123}}},
+		{"K1ABC W9XYZ R FN42", ModeNAVHF, Message{Kind: KindGrid, To: "K1ABC", From: "W9XYZ", Grid: "FN42", Roger: true,
+			Exchange: &Exchange{Mode: ModeNAVHF}}},
+		{"K1ABC W9XYZ EN37", ModeWWDigi, Message{Kind: KindGrid, To: "K1ABC", From: "W9XYZ", Grid: "EN37",
+			Exchange: &Exchange{Mode: ModeWWDigi}}},
+		{"K1ABC RR73; W9XYZ <KH1/KH7Z> -08", ModeNone, Message{Kind: KindReport, To: "W9XYZ", From: "KH1/KH7Z",
+			FromHashed: true, Report: -8, HasReport: true, Completed: []string{"K1ABC"}}},
+		{"TNX BOB 73 GL", ModeNone, Message{Kind: KindFreeText}},
+		{"", ModeNone, Message{Kind: KindFreeText}},
+	}
+	for _, tt := range tests {
+		got := Parse(tt.text, tt.mode)
+		tt.want.Raw = tt.text
+		if len(got.Completed) == 0 {
+			got.Completed = nil
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCallsign(t *testing.T) {
+	tests := []struct {
+		s        string
+		call     bool
+		base     string
+		portable bool
+		grid     bool
+	}{
+		{"BH1XYZ", true, "BH1XYZ", false, false},
+		{"3DA0XYZ", true, "3DA0XYZ", false, false},
+		{"K1ABC/P", true, "K1ABC", true, false},
+		{"PJ4/K1ABC", true, "K1ABC", false, false},
+		{"VP2E/K1ABC/MM", true, "K1ABC", true, false},
+		{"HELLO", false, "HELLO", false, false},
+		{"K1", false, "K1", false, false},
+		{"OM89", false, "OM89", false, true},
+		{"RR73", false, "RR73", false, false},
+	}
+	for _, tt := range tests {
+		if got := IsCallsign(tt.s); got != tt.call {
+			t.Errorf("IsCallsign(%q) = %v, want %v", tt.s, got, tt.call)
+		}
+		if got := BaseCall(tt.s); got != tt.base {
+			t.Errorf("BaseCall(%q) = %q, want %q", tt.s, got, tt.base)
+		}
+		if got := IsPortable(tt.s); got != tt.portable {
+			t.Errorf("IsPortable(%q) = %v, want %v", tt.s, got, tt.portable)
+		}
+		if got := IsGrid(tt.s); got != tt.grid {
+			t.Errorf("IsGrid(%q) = %v, want %v", tt.s, got, tt.grid)
+		}
+	}
+}
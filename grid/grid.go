@@ -0,0 +1,152 @@
+// Package grid validates Maidenhead locators and computes positions, great-circle
+// distances and bearings between them.
+//
+//	p, _ := grid.PathBetween("PM01aa", "FN31pr")
+//	// p.DistanceKm, p.Bearing, p.LongPathBearing
+package grid
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+var ErrInvalidLocator = errors.New("invalid maidenhead locator")
+
+// LatLon is a position in decimal degrees; north and east are positive.
+type LatLon struct {
+	Lat float64
+	Lon float64
+}
+
+// Box is the area a locator covers.
+type Box struct {
+	SouthWest LatLon
+	NorthEast LatLon
+}
+
+func (b Box) Center() LatLon {
+	return LatLon{
+		Lat: (b.SouthWest.Lat + b.NorthEast.Lat) / 2,
+		Lon: (b.SouthWest.Lon + b.NorthEast.Lon) / 2,
+	}
+}
+
+func (b Box) Contains(p LatLon) bool {
+	return p.Lat >= b.SouthWest.Lat && p.Lat < b.NorthEast.Lat &&
+		p.Lon >= b.SouthWest.Lon && p.Lon < b.NorthEast.Lon
+}
+
+// Each locator pair divides the previous cell: 18 fields of 20x10 degrees, then 10
+// squares, 24 subsquares and 10 extended squares.
+var (
+	pairDivs = [4]float64{18, 10, 24, 10}
+	lonSizes = [4]float64{20, 2, 2.0 / 24, 2.0 / 240}
+	latSizes = [4]float64{10, 1, 1.0 / 24, 1.0 / 240}
+)
+
+// Valid reports whether s is a 2, 4, 6 or 8 character locator, in any case.
+func Valid(s string) bool {
+	_, err := Normalize(s)
+	return err == nil
+}
+
+// Normalize validates s and returns it in the usual "AB12cd34" case.
+func Normalize(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if n := len(s); n == 0 || n > 8 || n%2 != 0 {
+		return "", fmt.Errorf("%w: %q", ErrInvalidLocator, s)
+	}
+	b := []byte(s)
+	for i := 0; i < len(b); i += 2 {
+		for j := i; j < i+2; j++ {
+			c, ok := pairDigit(i/2, b[j])
+			if !ok {
+				return "", fmt.Errorf("%w: %q", ErrInvalidLocator, s)
+			}
+			b[j] = pairChar(i/2, c)
+		}
+	}
+	return string(b), nil
+}
+
+// pairDigit returns the value of c in locator pair n (0-based).
+func pairDigit(n int, c byte) (int, bool) {
+	var v int
+	switch {
+	case n%2 == 1 && c >= '0' && c <= '9':
+		v = int(c - '0')
+	case n%2 == 0 && c >= 'A' && c <= 'Z':
+		v = int(c - 'A')
+	case n%2 == 0 && c >= 'a' && c <= 'z':
+		v = int(c - 'a')
+	default:
+		return 0, false
+	}
+	return v, v < int(pairDivs[n])
+}
+
+func pairChar(n, v int) byte {
+	switch {
+	case n%2 == 1:
+		return byte('0' + v)
+	case n == 0:
+		return byte('A' + v)
+	default:
+		return byte('a' + v)
+	}
+}
+
+// Bounds returns the area covered by a locator.
+func Bounds(loc string) (Box, error) {
+	norm, err := Normalize(loc)
+	if err != nil {
+		return Box{}, err
+	}
+	lon, lat := -180.0, -90.0
+	pairs := len(norm) / 2
+	for n := 0; n < pairs; n++ {
+		x, _ := pairDigit(n, norm[2*n])
+		y, _ := pairDigit(n, norm[2*n+1])
+		lon += float64(x) * lonSizes[n]
+		lat += float64(y) * latSizes[n]
+	}
+	last := pairs - 1
+	return Box{
+		SouthWest: LatLon{lat, lon},
+		NorthEast: LatLon{lat + latSizes[last], lon + lonSizes[last]},
+	}, nil
+}
+
+// Center returns the center of a locator.
+func Center(loc string) (LatLon, error) {
+	box, err := Bounds(loc)
+	if err != nil {
+		return LatLon{}, err
+	}
+	return box.Center(), nil
+}
+
+// FromLatLon returns the locator of p with 2, 4, 6 or 8 characters.
+func FromLatLon(p LatLon, chars int) (string, error) {
+	if chars <= 0 || chars > 8 || chars%2 != 0 {
+		return "", fmt.Errorf("locator length must be 2, 4, 6 or 8, got %d", chars)
+	}
+	if math.IsNaN(p.Lat) || math.IsNaN(p.Lon) || p.Lat < -90 || p.Lat > 90 || p.Lon < -180 || p.Lon > 180 {
+		return "", fmt.Errorf("position out of range: %v", p)
+	}
+	// The north pole and antimeridian belong to the last cell.
+	lon := math.Min(p.Lon+180, 360-1e-9)
+	lat := math.Min(p.Lat+90, 180-1e-9)
+
+	out := make([]byte, 0, chars)
+	for n := 0; n < chars/2; n++ {
+		x := int(lon / lonSizes[n])
+		y := int(lat / latSizes[n])
+		lon -= float64(x) * lonSizes[n]
+		lat -= float64(y) * latSizes[n]
+		out = append(out, pairChar(n, x), pairChar(n, y))
+	}
+	return string(out), nil
+}
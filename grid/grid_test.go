@@ -0,0 +1,155 @@
+package grid
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/SydneyOwl/clh-plugin-go-sdk/ft8"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"FN31pr", "FN31pr", true},
+		{"fn31PR", "FN31pr", true},
+		{" PM01 ", "PM01", true},
+		{"JN", "JN", true},
+		{"JN58td25", "JN58td25", true},
+		{"RR73", "RR73", true},
+		{"SS73", "", false}, // fields stop at R
+		{"FN3", "", false},
+		{"FN31py", "", false}, // subsquares stop at x
+		{"31FN", "", false},
+		{"FN31pr2", "", false},
+		{"FN31pr25xx", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.in)
+		if tt.ok != (err == nil) || got != tt.want {
+			t.Errorf("Normalize(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidLocator) {
+			t.Errorf("Normalize(%q) error %v is not ErrInvalidLocator", tt.in, err)
+		}
+		if Valid(tt.in) != tt.ok {
+			t.Errorf("Valid(%q) = %v", tt.in, !tt.ok)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	points := []LatLon{
+		{41.714, -72.727},
+		{48.146, 11.608},
+		{-33.868, 151.209},
+		{0, 0},
+		{-90, -180},
+		{90, 180},
+	}
+	for _, p := range points {
+		for chars := 2; chars <= 8; chars += 2 {
+			loc, err := FromLatLon(p, chars)
+			if err != nil {
+				t.Fatalf("FromLatLon(%v, %d): %v", p, chars, err)
+			}
+			box, err := Bounds(loc)
+			if err != nil {
+				t.Fatalf("Bounds(%q): %v", loc, err)
+			}
+			// The poles and the antimeridian sit on the closed edge of the last cell.
+			inside := box.Contains(p) ||
+				(near(p.Lat, box.NorthEast.Lat, 1e-9) || near(p.Lon, box.NorthEast.Lon, 1e-9))
+			if !inside {
+				t.Errorf("Bounds(%q) = %+v does not contain %v", loc, box, p)
+			}
+			if again, _ := FromLatLon(box.Center(), chars); again != loc {
+				t.Errorf("FromLatLon(center of %q) = %q", loc, again)
+			}
+		}
+	}
+	if loc, _ := FromLatLon(LatLon{41.714, -72.727}, 6); loc != "FN31pr" {
+		t.Errorf("FromLatLon(Hartford) = %q, want FN31pr", loc)
+	}
+	for _, chars := range []int{0, 3, 10} {
+		if _, err := FromLatLon(LatLon{}, chars); err == nil {
+			t.Errorf("FromLatLon with %d chars succeeded", chars)
+		}
+	}
+	if _, err := FromLatLon(LatLon{Lat: 91}, 4); err == nil {
+		t.Error("FromLatLon(lat 91) succeeded")
+	}
+}
+
+func TestCenter(t *testing.T) {
+	c, err := Center("FN31pr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(c.Lat, 41.729167, 1e-6) || !near(c.Lon, -72.708333, 1e-6) {
+		t.Errorf("Center(FN31pr) = %+v", c)
+	}
+	if _, err := Center("ZZ"); err == nil {
+		t.Error("Center(ZZ) succeeded")
+	}
+}
+
+func TestPath(t *testing.T) {
+	quarter := math.Pi / 2 * EarthRadiusKm
+	tests := []struct {
+		a, b     LatLon
+		km       float64
+		bearing  float64
+		longPath float64
+	}{
+		{LatLon{0, 0}, LatLon{0, 90}, quarter, 90, 270},
+		{LatLon{0, 0}, LatLon{0, -90}, quarter, 270, 90},
+		{LatLon{0, 0}, LatLon{90, 0}, quarter, 0, 180},
+		{LatLon{10, 20}, LatLon{-10, 20}, 2 * math.Pi / 18 * EarthRadiusKm, 180, 0},
+	}
+	for _, tt := range tests {
+		p := PathFrom(tt.a, tt.b)
+		if !near(p.DistanceKm, tt.km, 1e-6) || !near(p.Bearing, tt.bearing, 1e-6) || !near(p.LongPathBearing, tt.longPath, 1e-6) {
+			t.Errorf("PathFrom(%v, %v) = %+v", tt.a, tt.b, p)
+		}
+		if !near(p.DistanceKm+p.LongPathKm, 2*math.Pi*EarthRadiusKm, 1e-6) {
+			t.Errorf("PathFrom(%v, %v): short and long path do not add up", tt.a, tt.b)
+		}
+	}
+
+	// Munich to Sydney, checked against published great-circle calculators.
+	p, err := PathBetween("JN58td", "QF56od")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(p.DistanceKm, 16324, 10) || !near(p.Bearing, 79.6, 0.5) {
+		t.Errorf("PathBetween(JN58td, QF56od) = %+v", p)
+	}
+	if !near(p.DistanceMi(), p.DistanceKm/1.609344, 1e-9) {
+		t.Errorf("DistanceMi() = %v", p.DistanceMi())
+	}
+	if _, err := PathBetween("JN58td", "bad"); err == nil {
+		t.Error("PathBetween with an invalid locator succeeded")
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	spot := Enrich(ft8.Parse("CQ K1ABC FN31", ft8.ModeNone), "JN58td")
+	if !spot.HasPath || spot.From != "K1ABC" || spot.Path.DistanceKm < 6000 {
+		t.Errorf("Enrich(CQ with grid) = %+v", spot)
+	}
+	if spot := Enrich(ft8.Parse("K1ABC W9XYZ -10", ft8.ModeNone), "JN58td"); spot.HasPath {
+		t.Errorf("Enrich(report) has a path: %+v", spot)
+	}
+	if spot := Enrich(ft8.Parse("CQ K1ABC FN31", ft8.ModeNone), ""); spot.HasPath {
+		t.Errorf("Enrich without my grid has a path: %+v", spot)
+	}
+}
+
+func near(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
@@ -0,0 +1,74 @@
+package grid
+
+import "math"
+
+const (
+	// EarthRadiusKm is the mean Earth radius used for distances.
+	EarthRadiusKm = 6371.0088
+	kmPerMile     = 1.609344
+)
+
+// Path is the great-circle path between two positions. Bearings are degrees from true
+// north, in [0, 360).
+type Path struct {
+	DistanceKm      float64
+	Bearing         float64
+	LongPathKm      float64
+	LongPathBearing float64
+}
+
+func (p Path) DistanceMi() float64 { return p.DistanceKm / kmPerMile }
+func (p Path) LongPathMi() float64 { return p.LongPathKm / kmPerMile }
+
+// DistanceKm returns the great-circle distance between a and b.
+func DistanceKm(a, b LatLon) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat := lat2 - lat1
+	dLon := radians(b.Lon - a.Lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Bearing returns the initial short-path bearing from a to b.
+func Bearing(a, b LatLon) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLon := radians(b.Lon - a.Lon)
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	return normalizeBearing(math.Atan2(y, x) * 180 / math.Pi)
+}
+
+// PathFrom computes the short and long path from a to b.
+func PathFrom(a, b LatLon) Path {
+	d := DistanceKm(a, b)
+	bearing := Bearing(a, b)
+	return Path{
+		DistanceKm:      d,
+		Bearing:         bearing,
+		LongPathKm:      2*math.Pi*EarthRadiusKm - d,
+		LongPathBearing: normalizeBearing(bearing + 180),
+	}
+}
+
+// PathBetween computes the path between the centers of two locators.
+func PathBetween(from, to string) (Path, error) {
+	a, err := Center(from)
+	if err != nil {
+		return Path{}, err
+	}
+	b, err := Center(to)
+	if err != nil {
+		return Path{}, err
+	}
+	return PathFrom(a, b), nil
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+
+func normalizeBearing(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	return deg
+}
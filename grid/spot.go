@@ -0,0 +1,24 @@
+package grid
+
+import "github.com/SydneyOwl/clh-plugin-go-sdk/ft8"
+
+// Spot is a parsed FT8/FT4 message with the path from the local station to the grid it
+// carries. HasPath is false when the message has no grid or either locator is invalid.
+type Spot struct {
+	ft8.Message
+	Path    Path
+	HasPath bool
+}
+
+// Enrich computes the path from myGrid, typically SettingsSnapshot.MyMaidenheadGrid, to
+// the grid in msg.
+func Enrich(msg ft8.Message, myGrid string) Spot {
+	spot := Spot{Message: msg}
+	if msg.Grid == "" || myGrid == "" {
+		return spot
+	}
+	if p, err := PathBetween(myGrid, msg.Grid); err == nil {
+		spot.Path, spot.HasPath = p, true
+	}
+	return spot
+}
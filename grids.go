@@ -0,0 +1,18 @@
+package clhplugin
+
+import "github.com/SydneyOwl/clh-plugin-go-sdk/grid"
+
+// Path returns the path from DEGrid to DXGrid.
+func (s WsjtxStatus) Path() (grid.Path, error) {
+	return grid.PathBetween(s.DEGrid, s.DXGrid)
+}
+
+// Path returns the path from MyGrid to DXGrid.
+func (q WsjtxQSOLogged) Path() (grid.Path, error) {
+	return grid.PathBetween(q.MyGrid, q.DXGrid)
+}
+
+// Path returns the path from MyGrid to DXGrid.
+func (d QSODetail) Path() (grid.Path, error) {
+	return grid.PathBetween(d.MyGrid, d.DXGrid)
+}
@@ -0,0 +1,150 @@
+package clhplugin
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	pb "github.com/SydneyOwl/clh-proto/gen/go/v20260312"
+)
+
+const minHeartbeatInterval = time.Second
+
+// Health describes the liveness of the current connection to CLH. A heartbeat sent when
+// nothing has arrived from CLH for an interval is followed by a QueryServerInfo ping whose
+// round trip is reported as RTT; when pings fail and nothing arrives for KeepaliveWindow,
+// the connection is treated as dead.
+type Health struct {
+	Interval        time.Duration
+	KeepaliveWindow time.Duration
+	LastHeartbeat   time.Time
+	LastInbound     time.Time
+	LastPing        time.Time
+	RTT             time.Duration // of the last successful ping
+	MissedPings     int           // consecutive failed pings
+}
+
+type healthState struct {
+	mu     sync.Mutex
+	health Health
+}
+
+func (h *healthState) reset(interval, window time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.health = Health{Interval: interval, KeepaliveWindow: window, LastInbound: time.Now()}
+}
+
+func (h *healthState) update(fn func(*Health)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fn(&h.health)
+}
+
+func (h *healthState) snapshot() Health {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.health
+}
+
+func (c *Client) Health() Health {
+	return c.health.snapshot()
+}
+
+// heartbeatTiming returns the heartbeat interval and keepalive window for a session. With
+// HeartbeatFromKeepalive the interval is a third of the server's keepalive timeout.
+func (c *Client) heartbeatTiming(resp RegisterResponse) (time.Duration, time.Duration) {
+	interval := c.cfg.HeartbeatInterval
+	keepalive := time.Duration(resp.ServerInfo.KeepaliveTimeoutSec) * time.Second
+	if c.cfg.HeartbeatFromKeepalive && keepalive > 0 {
+		interval = max(keepalive/3, minHeartbeatInterval)
+	}
+	window := keepalive
+	if window <= 0 {
+		window = 3 * interval
+	}
+	return interval, window
+}
+
+func (c *Client) heartbeatLoop(s *session, interval, window time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.done:
+			return
+		case <-c.stopCh:
+			return
+		}
+
+		err := c.sendAnyMessage(&pb.PipeHeartbeat{
+			Uuid:      c.manifest.UUID,
+			Timestamp: nowTimestamp(),
+		})
+		if err != nil {
+			c.metrics.heartbeatFailures.Add(1)
+			c.log.Warn("heartbeat failed", "error", err)
+			c.failSession(s, err)
+			return
+		}
+		c.metrics.heartbeats.Add(1)
+		c.health.update(func(h *Health) { h.LastHeartbeat = time.Now() })
+		c.log.Debug("heartbeat sent")
+
+		if time.Since(c.Health().LastInbound) < interval {
+			continue // CLH is evidently alive
+		}
+		if c.ping(interval/2) || c.closed.Load() {
+			continue
+		}
+		if idle := time.Since(c.Health().LastInbound); idle >= window {
+			c.log.Error("clh unresponsive", "idle", idle, "keepalive_window", window)
+			c.failSession(s, ErrHeartbeatTimeout)
+			return
+		}
+	}
+}
+
+// ping sends QueryServerInfo directly, bypassing interceptors, retries and the request
+// metrics, logs and spans, and records its round trip. Any reply, even an unsuccessful
+// one, proves CLH is alive.
+func (c *Client) ping(timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := c.newRequest(EnvelopeKindQuery, EnvelopeTopicQueryServerInfo, nil, nil, nil)
+	if err != nil {
+		return false
+	}
+	started := time.Now()
+	_, err = c.roundTrip(ctx, req)
+	rtt := time.Since(started)
+
+	var missed int
+	c.health.update(func(h *Health) {
+		h.LastPing = started
+		if err != nil {
+			h.MissedPings++
+		} else {
+			h.RTT = rtt
+			h.MissedPings = 0
+		}
+		missed = h.MissedPings
+	})
+	if err != nil {
+		c.metrics.heartbeatMisses.Add(1)
+		c.log.Warn("heartbeat ping failed", "missed", missed, "error", err)
+		return false
+	}
+	c.metrics.heartbeatRTT.Store(int64(rtt))
+	c.log.Debug("heartbeat ping", "rtt", rtt)
+	return true
+}
+
+// failSession ends s as a heartbeat failure; the read loop then hands over to reconnect
+// or finishes the client.
+func (c *Client) failSession(s *session, err error) {
+	s.fail(ConnectionStateHeartbeatFailed, err)
+	_ = s.conn.Close()
+}
@@ -0,0 +1,89 @@
+package clhplugin_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	clhplugin "github.com/SydneyOwl/clh-plugin-go-sdk"
+	"github.com/SydneyOwl/clh-plugin-go-sdk/clhtest"
+)
+
+func serverInfoQueries(host *clhtest.Host) int {
+	n := 0
+	for _, req := range host.Requests() {
+		if req.Topic == clhplugin.EnvelopeTopicQueryServerInfo {
+			n++
+		}
+	}
+	return n
+}
+
+func TestHeartbeatPing(t *testing.T) {
+	host := startHost(t)
+	client := connectClient(t, host, clhplugin.WithHeartbeatInterval(20*time.Millisecond))
+
+	if err := host.WaitForHeartbeats(testContext(t), testManifest.UUID, 3); err != nil {
+		t.Fatal(err)
+	}
+	// Nothing else arrives from the host, so heartbeats are followed by pings.
+	waitUntil(t, func() bool { return client.Health().RTT > 0 })
+
+	health := client.Health()
+	if health.Interval != 20*time.Millisecond || health.LastHeartbeat.IsZero() || health.LastPing.IsZero() || health.MissedPings != 0 {
+		t.Fatalf("health = %+v", health)
+	}
+	if serverInfoQueries(host) == 0 {
+		t.Fatal("host saw no ping")
+	}
+	m := client.Metrics()
+	if m.Heartbeats < 3 || m.HeartbeatRTT <= 0 {
+		t.Fatalf("metrics = %+v", m)
+	}
+	if _, ok := m.Requests[clhplugin.EnvelopeTopicQueryServerInfo]; ok {
+		t.Fatal("pings counted as requests")
+	}
+}
+
+func TestHeartbeatSkipsPingWhenBusy(t *testing.T) {
+	host := startHost(t)
+	client := connectClient(t, host, clhplugin.WithHeartbeatInterval(50*time.Millisecond))
+	sub := clhplugin.EventSubscription{Topics: []clhplugin.EnvelopeTopic{clhplugin.EnvelopeTopicEventRigData}}
+	if _, err := client.SubscribeEvents(testContext(t), sub); err != nil {
+		t.Fatal(err)
+	}
+
+	// Steady inbound traffic proves CLH is alive between heartbeats.
+	deadline := time.Now().Add(300 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		if _, err := host.PushRigData(clhplugin.RigData{Frequency: 7074000}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := host.Heartbeats(testManifest.UUID); n < 3 {
+		t.Fatalf("%d heartbeats, want at least 3", n)
+	}
+	if n := serverInfoQueries(host); n != 0 {
+		t.Fatalf("%d pings while traffic was flowing", n)
+	}
+}
+
+func TestHeartbeatTimeout(t *testing.T) {
+	// A zero keepalive timeout makes the client's window three intervals.
+	host := startHost(t, clhtest.WithServerInfo(clhplugin.ServerInfo{InstanceID: "clh", Version: "1"}))
+	host.Handle(clhplugin.EnvelopeTopicQueryServerInfo, func(ctx context.Context, _ clhtest.Request) clhtest.Response {
+		<-ctx.Done()
+		return clhtest.Fail("gone", "unresponsive")
+	})
+	client := connectClient(t, host, clhplugin.WithHeartbeatInterval(20*time.Millisecond))
+
+	change := waitState(t, client, clhplugin.ConnectionStateHeartbeatFailed)
+	if !errors.Is(change.Err, clhplugin.ErrHeartbeatTimeout) {
+		t.Fatalf("state change error = %v", change.Err)
+	}
+	if m := client.Metrics(); m.HeartbeatMisses == 0 {
+		t.Fatalf("metrics = %+v", m)
+	}
+}
@@ -0,0 +1,91 @@
+package clhplugin
+
+import (
+	"context"
+
+	pb "github.com/SydneyOwl/clh-proto/gen/go/v20260312"
+	"google.golang.org/protobuf/proto"
+)
+
+// Request is a query or command on its way to CLH, as request interceptors see it.
+// Attributes is a copy the interceptor may change, e.g. to add auth attributes.
+type Request struct {
+	Kind         EnvelopeKind
+	Topic        EnvelopeTopic
+	Attributes   map[string]string
+	Payload      proto.Message
+	Subscription *EventSubscription
+}
+
+// RequestHandler sends a request and returns CLH's reply. A reply with Success false
+// comes back together with a *RemoteError.
+type RequestHandler func(ctx context.Context, req Request) (Envelope, error)
+
+// RequestInterceptor wraps every query and command, including the typed wrappers. It may
+// inspect or change req, call next any number of times, or answer without calling next.
+// The typed wrappers decode the Envelope the outermost interceptor returns; one carrying
+// the ID of the reply from next is taken to be that reply unchanged.
+type RequestInterceptor func(ctx context.Context, req Request, next RequestHandler) (Envelope, error)
+
+type InboundHandler func(Message)
+
+// InboundInterceptor sees every inbound message before the message handler, typed
+// handlers, subscriptions and WaitMessage. Not calling next drops the message. Replies
+// to pending requests are matched before the chain runs, so dropping them is safe.
+// Interceptors run on the read loop and also on the goroutines of a WsjtxBridge, so they
+// may be called concurrently and must be safe for concurrent use. They should return quickly.
+type InboundInterceptor func(msg Message, next InboundHandler)
+
+// intercept runs the request interceptor chain around requestRaw. The raw reply is
+// returned as is when the chain passes it through, so payloads are not re-encoded.
+func (c *Client) intercept(ctx context.Context, req Request) (*pb.PipeEnvelope, error) {
+	var raw *pb.PipeEnvelope
+	handler := RequestHandler(func(ctx context.Context, req Request) (Envelope, error) {
+		resp, err := c.requestRaw(ctx, req.Kind, req.Topic, req.Attributes, req.Payload, req.Subscription)
+		if err != nil {
+			return Envelope{}, err
+		}
+		raw = resp
+		env := fromPBEnvelope(resp)
+		if !resp.Success {
+			return env, remoteError(req.Topic, resp)
+		}
+		return env, nil
+	})
+	interceptors := c.interceptors
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(ctx context.Context, req Request) (Envelope, error) {
+			return interceptor(ctx, req, next)
+		}
+	}
+
+	env, err := handler(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if raw != nil && raw.Id == env.ID {
+		return raw, nil
+	}
+	return toPBEnvelope(env)
+}
+
+// buildRequestChain appends the retry policy as the innermost request interceptor.
+func (c *Client) buildRequestChain() {
+	c.interceptors = append([]RequestInterceptor(nil), c.cfg.RequestInterceptors...)
+	if c.cfg.Retry != nil {
+		c.interceptors = append(c.interceptors, c.cfg.Retry.interceptor(c.doneCh))
+	}
+}
+
+func (c *Client) buildInboundChain() {
+	handler := InboundHandler(c.deliverInbound)
+	interceptors := c.cfg.InboundInterceptors
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(msg Message) {
+			interceptor(msg, next)
+		}
+	}
+	c.inbound = handler
+}
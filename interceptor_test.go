@@ -0,0 +1,229 @@
+package clhplugin_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	clhplugin "github.com/SydneyOwl/clh-plugin-go-sdk"
+	"github.com/SydneyOwl/clh-plugin-go-sdk/clhtest"
+)
+
+// callLog records interceptor calls from any goroutine.
+type callLog struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (l *callLog) add(call string) {
+	l.mu.Lock()
+	l.calls = append(l.calls, call)
+	l.mu.Unlock()
+}
+
+func (l *callLog) get() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.calls)
+}
+
+func (l *callLog) reset() {
+	l.mu.Lock()
+	l.calls = nil
+	l.mu.Unlock()
+}
+
+func recordingRequest(log *callLog, name string) clhplugin.RequestInterceptor {
+	return func(ctx context.Context, req clhplugin.Request, next clhplugin.RequestHandler) (clhplugin.Envelope, error) {
+		log.add(name + ">")
+		req.Attributes[name] = "1"
+		env, err := next(ctx, req)
+		log.add("<" + name)
+		return env, err
+	}
+}
+
+func TestRequestInterceptorOrder(t *testing.T) {
+	host := startHost(t, clhtest.WithServerInfo(clhplugin.ServerInfo{InstanceID: "clh-1"}))
+	var log callLog
+	client := connectClient(t, host,
+		clhplugin.WithRequestInterceptor(recordingRequest(&log, "a"), recordingRequest(&log, "b")),
+		clhplugin.WithRequestInterceptor(recordingRequest(&log, "c")),
+	)
+	log.reset() // drop the calls made while connecting
+
+	info, err := client.QueryServerInfo(testContext(t))
+	if err != nil || info.InstanceID != "clh-1" {
+		t.Fatalf("QueryServerInfo = %+v, %v", info, err)
+	}
+	if want := []string{"a>", "b>", "c>", "<c", "<b", "<a"}; !slices.Equal(log.get(), want) {
+		t.Fatalf("calls = %v, want %v", log.get(), want)
+	}
+	reqs := host.Requests()
+	last := reqs[len(reqs)-1]
+	for _, name := range []string{"a", "b", "c"} {
+		if last.Attributes[name] != "1" {
+			t.Fatalf("attributes = %v, missing %s", last.Attributes, name)
+		}
+	}
+}
+
+func TestRequestInterceptorShortCircuit(t *testing.T) {
+	host := startHost(t)
+	var log callLog
+	client := connectClient(t, host,
+		clhplugin.WithRequestInterceptor(func(ctx context.Context, req clhplugin.Request, next clhplugin.RequestHandler) (clhplugin.Envelope, error) {
+			if req.Topic == clhplugin.EnvelopeTopicQueryServerInfo {
+				return clhplugin.Envelope{
+					Kind:    clhplugin.EnvelopeKindResponse,
+					Topic:   req.Topic,
+					Success: true,
+					Payload: clhplugin.ServerInfo{InstanceID: "cached"},
+				}, nil
+			}
+			return next(ctx, req)
+		}),
+		clhplugin.WithRequestInterceptor(recordingRequest(&log, "inner")),
+	)
+	log.reset()
+	before := len(host.Requests())
+
+	info, err := client.QueryServerInfo(testContext(t))
+	if err != nil || info.InstanceID != "cached" {
+		t.Fatalf("QueryServerInfo = %+v, %v", info, err)
+	}
+	if calls := log.get(); len(calls) != 0 {
+		t.Fatalf("inner interceptor called: %v", calls)
+	}
+	if n := len(host.Requests()); n != before {
+		t.Fatalf("host got %d requests, want none", n-before)
+	}
+}
+
+func TestRequestInterceptorErrors(t *testing.T) {
+	host := startHost(t)
+	host.Handle(clhplugin.EnvelopeTopicQueryRigSnapshot, func(context.Context, clhtest.Request) clhtest.Response {
+		return clhtest.Fail("rig_offline", "no rig")
+	})
+	errDenied := errors.New("denied")
+	seen := make(chan error, 1)
+	client := connectClient(t, host, clhplugin.WithRequestInterceptor(
+		func(ctx context.Context, req clhplugin.Request, next clhplugin.RequestHandler) (clhplugin.Envelope, error) {
+			if req.Topic == clhplugin.EnvelopeTopicQueryUDPSnapshot {
+				return clhplugin.Envelope{}, errDenied
+			}
+			env, err := next(ctx, req)
+			if req.Topic == clhplugin.EnvelopeTopicQueryRigSnapshot {
+				seen <- err
+			}
+			return env, err
+		},
+	))
+	ctx := testContext(t)
+
+	// A failed reply reaches the interceptor and the caller as a *RemoteError.
+	_, err := client.QueryRigSnapshot(ctx)
+	var remote *clhplugin.RemoteError
+	if !errors.As(err, &remote) || remote.Code != "rig_offline" {
+		t.Fatalf("QueryRigSnapshot err = %v", err)
+	}
+	if err := <-seen; !errors.As(err, &remote) {
+		t.Fatalf("interceptor saw %v", err)
+	}
+
+	// An error returned by the interceptor itself reaches the caller unchanged.
+	before := len(host.Requests())
+	if _, err := client.QueryUDPSnapshot(ctx); !errors.Is(err, errDenied) {
+		t.Fatalf("QueryUDPSnapshot err = %v", err)
+	}
+	if n := len(host.Requests()); n != before {
+		t.Fatal("rejected request was sent")
+	}
+}
+
+func rigFrequency(msg clhplugin.Message) uint64 {
+	if msg.RigData != nil {
+		return msg.RigData.Frequency
+	}
+	if msg.Envelope != nil {
+		if data, ok := msg.Envelope.Payload.(clhplugin.RigData); ok {
+			return data.Frequency
+		}
+	}
+	return 0
+}
+
+func TestInboundInterceptors(t *testing.T) {
+	host := startHost(t)
+	var log callLog
+	recording := func(name string) clhplugin.InboundInterceptor {
+		return func(msg clhplugin.Message, next clhplugin.InboundHandler) {
+			if rigFrequency(msg) == 0 {
+				next(msg)
+				return
+			}
+			log.add(name + ">")
+			next(msg)
+			log.add("<" + name)
+		}
+	}
+	// The middle interceptor drops 40m rig data; the rest passes through.
+	dropping := func(msg clhplugin.Message, next clhplugin.InboundHandler) {
+		if rigFrequency(msg) == 7074000 {
+			log.add("drop")
+			return
+		}
+		next(msg)
+	}
+	client := connectClient(t, host, clhplugin.WithInboundInterceptor(recording("a"), dropping, recording("b")))
+	ctx := testContext(t)
+
+	rig := make(chan clhplugin.RigData, 4)
+	client.OnRigData(func(data clhplugin.RigData) {
+		log.add("handler")
+		rig <- data
+	})
+	sub := clhplugin.EventSubscription{Topics: []clhplugin.EnvelopeTopic{clhplugin.EnvelopeTopicEventRigData}}
+	if _, err := client.SubscribeEvents(ctx, sub); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := host.PushRigData(clhplugin.RigData{Frequency: 7074000}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := host.PushRigData(clhplugin.RigData{Frequency: 14074000}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case data := <-rig:
+		if data.Frequency != 14074000 {
+			t.Fatalf("dropped rig data delivered: %+v", data)
+		}
+	case <-ctx.Done():
+		t.Fatal("rig data not delivered")
+	}
+
+	// Handlers may run on the dispatcher after the chain returns, so only the order of
+	// the interceptor calls is fixed.
+	waitUntil(t, func() bool { return len(log.get()) == 8 })
+	calls := slices.DeleteFunc(log.get(), func(c string) bool { return c == "handler" })
+	if want := []string{"a>", "drop", "<a", "a>", "b>", "<b", "<a"}; !slices.Equal(calls, want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+
+	// A dropped message does not reach WaitMessage either.
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	for {
+		msg, err := client.WaitMessage(waitCtx)
+		if err != nil {
+			break
+		}
+		if rigFrequency(msg) == 7074000 {
+			t.Fatal("dropped message reached WaitMessage")
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package clhplugin
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	pb "github.com/SydneyOwl/clh-proto/gen/go/v20260312"
+)
+
+// discardHandler drops every record; it is the default until WithLogger is used.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+func newLogger(cfg Config, manifest PluginManifest) *slog.Logger {
+	if cfg.Logger == nil {
+		return slog.New(discardHandler{})
+	}
+	return cfg.Logger.With(slog.String("plugin_uuid", manifest.UUID))
+}
+
+func (c *Client) endpoint() string {
+	if c.cfg.Dialer != nil {
+		return "custom"
+	}
+	return c.cfg.PipePath
+}
+
+func (c *Client) logStateChange(change StateChange) {
+	level := slog.LevelInfo
+	switch change.To {
+	case ConnectionStateDialing, ConnectionStateRegistering:
+		level = slog.LevelDebug
+	case ConnectionStateRemoteClosed, ConnectionStateHeartbeatFailed, ConnectionStateDisconnected:
+		level = slog.LevelWarn
+	}
+	attrs := []slog.Attr{
+		slog.String("from", string(change.From)),
+		slog.String("to", string(change.To)),
+	}
+	if change.Err != nil {
+		attrs = append(attrs, slog.Any("error", change.Err))
+	}
+	c.log.LogAttrs(context.Background(), level, "connection state changed", attrs...)
+}
+
+func (c *Client) logRequest(kind EnvelopeKind, topic EnvelopeTopic, id string, resp *pb.PipeEnvelope, err error, latency time.Duration) {
+	if err == nil && !c.log.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("id", id),
+		slog.Int("kind", int(kind)),
+		slog.Int("topic", int(topic)),
+		slog.Duration("latency", latency),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+		c.log.LogAttrs(context.Background(), slog.LevelWarn, "request failed", attrs...)
+		return
+	}
+	attrs = append(attrs, slog.Bool("success", resp.Success))
+	if !resp.Success {
+		attrs = append(attrs, slog.String("code", resp.ErrorCode), slog.String("message", resp.Message))
+	}
+	c.log.LogAttrs(context.Background(), slog.LevelDebug, "request", attrs...)
+}
+
+func (c *Client) logDrop(where string, msg Message) {
+	if !c.log.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	attrs := []slog.Attr{slog.String("queue", where), slog.String("kind", string(msg.Kind))}
+	if msg.Envelope != nil {
+		attrs = append(attrs, slog.Int("topic", int(msg.Envelope.Topic)))
+	}
+	c.log.LogAttrs(context.Background(), slog.LevelDebug, "inbound message dropped", attrs...)
+}
@@ -0,0 +1,162 @@
+package clhplugin
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/SydneyOwl/clh-proto/gen/go/v20260312"
+)
+
+// RequestLatencyBuckets are the upper bounds of the request latency histograms.
+var RequestLatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Metrics is a point-in-time view of the client's activity since NewClient. Requests are
+// counted per attempt, so retries show up as separate requests.
+type Metrics struct {
+	Connected         bool
+	MessagesByKind    map[InboundKind]uint64
+	MessagesByTopic   map[EnvelopeTopic]uint64
+	Requests          map[EnvelopeTopic]RequestMetrics
+	RemoteErrors      map[string]uint64 // unsuccessful replies by error code
+	Heartbeats        uint64
+	HeartbeatFailures uint64
+	HeartbeatMisses   uint64        // failed liveness pings
+	HeartbeatRTT      time.Duration // of the last successful liveness ping
+	ReconnectAttempts uint64
+	Reconnects        uint64
+	Queues            Stats
+}
+
+type RequestMetrics struct {
+	Count uint64
+	// Errors counts requests that failed locally or got an unsuccessful reply.
+	Errors  uint64
+	Latency LatencyHistogram
+}
+
+// LatencyHistogram holds one count per bucket of Bounds plus a final count for slower
+// requests. Counts are per bucket, not cumulative.
+type LatencyHistogram struct {
+	Bounds []time.Duration
+	Counts []uint64
+	Sum    time.Duration
+}
+
+type requestCounters struct {
+	count   uint64
+	errors  uint64
+	buckets []uint64
+	sum     time.Duration
+}
+
+type metrics struct {
+	heartbeats        atomic.Uint64
+	heartbeatFailures atomic.Uint64
+	heartbeatMisses   atomic.Uint64
+	heartbeatRTT      atomic.Int64
+	reconnectAttempts atomic.Uint64
+	reconnects        atomic.Uint64
+
+	mu           sync.Mutex
+	byKind       map[InboundKind]uint64
+	byTopic      map[EnvelopeTopic]uint64
+	requests     map[EnvelopeTopic]*requestCounters
+	remoteErrors map[string]uint64
+}
+
+func (m *metrics) message(msg Message) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.byKind == nil {
+		m.byKind = map[InboundKind]uint64{}
+		m.byTopic = map[EnvelopeTopic]uint64{}
+	}
+	m.byKind[msg.Kind]++
+	if msg.Envelope != nil {
+		m.byTopic[msg.Envelope.Topic]++
+	}
+}
+
+func (m *metrics) request(topic EnvelopeTopic, resp *pb.PipeEnvelope, err error, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.requests == nil {
+		m.requests = map[EnvelopeTopic]*requestCounters{}
+		m.remoteErrors = map[string]uint64{}
+	}
+	rc := m.requests[topic]
+	if rc == nil {
+		rc = &requestCounters{buckets: make([]uint64, len(RequestLatencyBuckets)+1)}
+		m.requests[topic] = rc
+	}
+	rc.count++
+	rc.sum += latency
+	i, _ := slices.BinarySearch(RequestLatencyBuckets, latency)
+	rc.buckets[i]++
+	switch {
+	case err != nil:
+		rc.errors++
+	case !resp.Success:
+		rc.errors++
+		m.remoteErrors[resp.ErrorCode]++
+	}
+}
+
+func (m *metrics) snapshot() Metrics {
+	out := Metrics{
+		MessagesByKind:    map[InboundKind]uint64{},
+		MessagesByTopic:   map[EnvelopeTopic]uint64{},
+		Requests:          map[EnvelopeTopic]RequestMetrics{},
+		RemoteErrors:      map[string]uint64{},
+		Heartbeats:        m.heartbeats.Load(),
+		HeartbeatFailures: m.heartbeatFailures.Load(),
+		HeartbeatMisses:   m.heartbeatMisses.Load(),
+		HeartbeatRTT:      time.Duration(m.heartbeatRTT.Load()),
+		ReconnectAttempts: m.reconnectAttempts.Load(),
+		Reconnects:        m.reconnects.Load(),
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k, v := range m.byKind {
+		out.MessagesByKind[k] = v
+	}
+	for k, v := range m.byTopic {
+		out.MessagesByTopic[k] = v
+	}
+	for k, v := range m.remoteErrors {
+		out.RemoteErrors[k] = v
+	}
+	for topic, rc := range m.requests {
+		out.Requests[topic] = RequestMetrics{
+			Count:  rc.count,
+			Errors: rc.errors,
+			Latency: LatencyHistogram{
+				Bounds: RequestLatencyBuckets,
+				Counts: append([]uint64(nil), rc.buckets...),
+				Sum:    rc.sum,
+			},
+		}
+	}
+	return out
+}
+
+func (c *Client) Metrics() Metrics {
+	m := c.metrics.snapshot()
+	m.Connected = c.IsConnected()
+	m.Queues = c.Stats()
+	return m
+}
@@ -0,0 +1,44 @@
+package clhplugin_test
+
+import (
+	"context"
+	"testing"
+
+	clhplugin "github.com/SydneyOwl/clh-plugin-go-sdk"
+	"github.com/SydneyOwl/clh-plugin-go-sdk/clhtest"
+)
+
+func TestMetrics(t *testing.T) {
+	host := startHost(t)
+	client := connectClient(t, host)
+	ctx := testContext(t)
+
+	if _, err := client.QueryServerInfo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	host.Handle(clhplugin.EnvelopeTopicQueryServerInfo, func(context.Context, clhtest.Request) clhtest.Response {
+		return clhtest.Fail("busy", "try later")
+	})
+	if _, err := client.QueryServerInfo(ctx); err == nil {
+		t.Fatal("query succeeded")
+	}
+
+	m := client.Metrics()
+	if !m.Connected {
+		t.Error("metrics report disconnected")
+	}
+	req := m.Requests[clhplugin.EnvelopeTopicQueryServerInfo]
+	if req.Count != 2 || req.Errors != 1 || m.RemoteErrors["busy"] != 1 {
+		t.Fatalf("requests = %+v, remote errors = %v", req, m.RemoteErrors)
+	}
+	if len(req.Latency.Bounds) != len(clhplugin.RequestLatencyBuckets) || len(req.Latency.Counts) != len(req.Latency.Bounds)+1 {
+		t.Fatalf("latency histogram = %+v", req.Latency)
+	}
+	var n uint64
+	for _, c := range req.Latency.Counts {
+		n += c
+	}
+	if n != req.Count {
+		t.Fatalf("histogram holds %d requests, want %d", n, req.Count)
+	}
+}
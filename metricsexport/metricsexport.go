@@ -0,0 +1,183 @@
+// Package metricsexport serves clhplugin.Client metrics in the Prometheus text format
+// and through expvar. It lives outside the SDK so that importing the SDK does not
+// register expvar's /debug/vars handler on http.DefaultServeMux.
+package metricsexport
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	clhplugin "github.com/SydneyOwl/clh-plugin-go-sdk"
+)
+
+// Handler serves the client's Metrics in the Prometheus text format.
+func Handler(c *clhplugin.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = WritePrometheus(w, c.Metrics())
+	})
+}
+
+// PublishExpvar publishes the client's Metrics under name in the process-wide expvar
+// registry.
+func PublishExpvar(c *clhplugin.Client, name string) error {
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("expvar %q is already published", name)
+	}
+	expvar.Publish(name, expvar.Func(func() any { return c.Metrics() }))
+	return nil
+}
+
+// Serve listens on addr, e.g. "127.0.0.1:9464", and serves /metrics in the Prometheus
+// text format and /debug/vars from expvar (see PublishExpvar). The listener closes when
+// the client finishes.
+func Serve(c *clhplugin.Client, addr string) (net.Addr, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler(c))
+	mux.Handle("/debug/vars", expvar.Handler())
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() { _ = srv.Serve(l) }()
+	go func() {
+		<-c.Done()
+		_ = srv.Close()
+	}()
+	return l.Addr(), nil
+}
+
+// WritePrometheus writes m in the Prometheus text exposition format.
+func WritePrometheus(w io.Writer, m clhplugin.Metrics) error {
+	bw := bufio.NewWriter(w)
+	p := promWriter{w: bw}
+
+	p.header("clh_plugin_connected", "gauge", "Whether the client is registered with CLH.")
+	p.sample("clh_plugin_connected", "", boolValue(m.Connected))
+
+	p.header("clh_plugin_messages_total", "counter", "Inbound messages by kind.")
+	for _, kind := range sortedKeys(m.MessagesByKind) {
+		p.sample("clh_plugin_messages_total", label("kind", string(kind)), float64(m.MessagesByKind[kind]))
+	}
+	p.header("clh_plugin_envelope_messages_total", "counter", "Inbound envelopes by topic.")
+	for _, topic := range sortedKeys(m.MessagesByTopic) {
+		p.sample("clh_plugin_envelope_messages_total", topicLabel(topic), float64(m.MessagesByTopic[topic]))
+	}
+
+	topics := sortedKeys(m.Requests)
+	p.header("clh_plugin_request_duration_seconds", "histogram", "Request round trip time by topic.")
+	for _, topic := range topics {
+		r := m.Requests[topic]
+		var cumulative uint64
+		for i, bound := range r.Latency.Bounds {
+			cumulative += r.Latency.Counts[i]
+			p.sample("clh_plugin_request_duration_seconds_bucket",
+				topicLabel(topic)+","+label("le", strconv.FormatFloat(bound.Seconds(), 'g', -1, 64)), float64(cumulative))
+		}
+		p.sample("clh_plugin_request_duration_seconds_bucket", topicLabel(topic)+","+label("le", "+Inf"), float64(r.Count))
+		p.sample("clh_plugin_request_duration_seconds_sum", topicLabel(topic), r.Latency.Sum.Seconds())
+		p.sample("clh_plugin_request_duration_seconds_count", topicLabel(topic), float64(r.Count))
+	}
+	p.header("clh_plugin_request_errors_total", "counter", "Failed requests by topic.")
+	for _, topic := range topics {
+		p.sample("clh_plugin_request_errors_total", topicLabel(topic), float64(m.Requests[topic].Errors))
+	}
+	p.header("clh_plugin_remote_errors_total", "counter", "Unsuccessful replies by error code.")
+	for _, code := range sortedKeys(m.RemoteErrors) {
+		p.sample("clh_plugin_remote_errors_total", label("code", code), float64(m.RemoteErrors[code]))
+	}
+
+	p.header("clh_plugin_heartbeats_total", "counter", "Heartbeats sent.")
+	p.sample("clh_plugin_heartbeats_total", "", float64(m.Heartbeats))
+	p.header("clh_plugin_heartbeat_failures_total", "counter", "Heartbeats that could not be sent.")
+	p.sample("clh_plugin_heartbeat_failures_total", "", float64(m.HeartbeatFailures))
+	p.header("clh_plugin_heartbeat_misses_total", "counter", "Liveness pings that got no reply.")
+	p.sample("clh_plugin_heartbeat_misses_total", "", float64(m.HeartbeatMisses))
+	p.header("clh_plugin_heartbeat_rtt_seconds", "gauge", "Round trip of the last successful liveness ping.")
+	p.sample("clh_plugin_heartbeat_rtt_seconds", "", m.HeartbeatRTT.Seconds())
+	p.header("clh_plugin_reconnect_attempts_total", "counter", "Reconnect attempts.")
+	p.sample("clh_plugin_reconnect_attempts_total", "", float64(m.ReconnectAttempts))
+	p.header("clh_plugin_reconnects_total", "counter", "Successful reconnects.")
+	p.sample("clh_plugin_reconnects_total", "", float64(m.Reconnects))
+
+	queues := []struct {
+		name  string
+		stats clhplugin.QueueStats
+	}{
+		{"callback", m.Queues.Callback},
+		{"subscription", m.Queues.Subscriptions},
+		{"wait", m.Queues.Wait},
+	}
+	for _, counter := range []string{"enqueued", "dropped"} {
+		name := "clh_plugin_queue_" + counter + "_total"
+		p.header(name, "counter", "Inbound queue messages "+counter+".")
+		for _, q := range queues {
+			v := q.stats.Enqueued
+			if counter == "dropped" {
+				v = q.stats.Dropped
+			}
+			p.sample(name, label("queue", q.name), float64(v))
+		}
+	}
+
+	if p.err != nil {
+		return p.err
+	}
+	return bw.Flush()
+}
+
+type promWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (p *promWriter) header(name, typ, help string) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+}
+
+func (p *promWriter) sample(name, labels string, v float64) {
+	if p.err != nil {
+		return
+	}
+	if labels != "" {
+		name += "{" + labels + "}"
+	}
+	_, p.err = fmt.Fprintf(p.w, "%s %s\n", name, strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func label(name, value string) string {
+	return name + `="` + promEscaper.Replace(value) + `"`
+}
+
+func topicLabel(topic clhplugin.EnvelopeTopic) string {
+	return label("topic", strconv.Itoa(int(topic)))
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func sortedKeys[K ~string | ~int32, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,184 @@
+package metricsexport_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	clhplugin "github.com/SydneyOwl/clh-plugin-go-sdk"
+	"github.com/SydneyOwl/clh-plugin-go-sdk/clhtest"
+	"github.com/SydneyOwl/clh-plugin-go-sdk/metricsexport"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	m := clhplugin.Metrics{
+		Connected:       true,
+		MessagesByKind:  map[clhplugin.InboundKind]uint64{clhplugin.InboundKindEnvelope: 5},
+		MessagesByTopic: map[clhplugin.EnvelopeTopic]uint64{clhplugin.EnvelopeTopicEventRigData: 5},
+		Requests: map[clhplugin.EnvelopeTopic]clhplugin.RequestMetrics{
+			clhplugin.EnvelopeTopicQueryServerInfo: {
+				Count:  4,
+				Errors: 1,
+				Latency: clhplugin.LatencyHistogram{
+					Bounds: []time.Duration{10 * time.Millisecond, 100 * time.Millisecond},
+					Counts: []uint64{1, 2, 1},
+					Sum:    350 * time.Millisecond,
+				},
+			},
+		},
+		RemoteErrors: map[string]uint64{`bad "code"`: 2},
+		Heartbeats:   7,
+		HeartbeatRTT: 1500 * time.Microsecond,
+		Queues:       clhplugin.Stats{Callback: clhplugin.QueueStats{Enqueued: 9, Dropped: 3}},
+	}
+	var sb strings.Builder
+	if err := metricsexport.WritePrometheus(&sb, m); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+
+	for _, want := range []string{
+		`clh_plugin_connected 1`,
+		`clh_plugin_messages_total{kind="envelope"} 5`,
+		`clh_plugin_envelope_messages_total{topic="6"} 5`,
+		`clh_plugin_request_duration_seconds_bucket{topic="100",le="0.01"} 1`,
+		`clh_plugin_request_duration_seconds_bucket{topic="100",le="0.1"} 3`,
+		`clh_plugin_request_duration_seconds_bucket{topic="100",le="+Inf"} 4`,
+		`clh_plugin_request_duration_seconds_sum{topic="100"} 0.35`,
+		`clh_plugin_request_duration_seconds_count{topic="100"} 4`,
+		`clh_plugin_request_errors_total{topic="100"} 1`,
+		`clh_plugin_remote_errors_total{code="bad \"code\""} 2`,
+		`clh_plugin_heartbeats_total 7`,
+		`clh_plugin_heartbeat_rtt_seconds 0.0015`,
+		`clh_plugin_queue_enqueued_total{queue="callback"} 9`,
+		`clh_plugin_queue_dropped_total{queue="callback"} 3`,
+		`clh_plugin_queue_dropped_total{queue="wait"} 0`,
+	} {
+		if !containsLine(out, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+
+	// Every sample belongs to the family declared by the last TYPE line.
+	var family string
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		if name, ok := strings.CutPrefix(line, "# TYPE "); ok {
+			family = strings.Fields(name)[0]
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, family) {
+			t.Errorf("sample %q outside family %s", line, family)
+		}
+	}
+}
+
+func containsLine(out, line string) bool {
+	for _, l := range strings.Split(out, "\n") {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+func TestHandler(t *testing.T) {
+	host, err := clhtest.NewHost(clhtest.WithMemoryTransport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer host.Close()
+	client, err := clhplugin.NewClient(clhplugin.PluginManifest{UUID: "metrics", Name: "metrics", Version: "1.0.0"}, host.ClientOption())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	defer client.Close(ctx)
+	if _, err := client.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.QueryServerInfo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	host.Handle(clhplugin.EnvelopeTopicQueryServerInfo, func(context.Context, clhtest.Request) clhtest.Response {
+		return clhtest.Fail("busy", "try later")
+	})
+	if _, err := client.QueryServerInfo(ctx); err == nil {
+		t.Fatal("query succeeded")
+	}
+
+	rec := httptest.NewRecorder()
+	metricsexport.Handler(client).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("content type = %q", ct)
+	}
+	out := rec.Body.String()
+	for _, want := range []string{
+		`clh_plugin_connected 1`,
+		`clh_plugin_request_duration_seconds_count{topic="100"} 2`,
+		`clh_plugin_request_errors_total{topic="100"} 1`,
+		`clh_plugin_remote_errors_total{code="busy"} 1`,
+	} {
+		if !containsLine(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+	host, err := clhtest.NewHost(clhtest.WithMemoryTransport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer host.Close()
+	client, err := clhplugin.NewClient(clhplugin.PluginManifest{UUID: "serve", Name: "serve", Version: "1.0.0"}, host.ClientOption())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	addr, err := metricsexport.Serve(client, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get("http://" + addr.String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if !containsLine(string(body), "clh_plugin_connected 1") {
+		t.Fatalf("/metrics = %s", body)
+	}
+
+	// The listener goes away with the client.
+	if err := client.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.Dial("tcp", addr.String())
+		if err != nil {
+			break
+		}
+		_ = conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("metrics listener still open after Close")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
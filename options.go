@@ -2,6 +2,7 @@ package clhplugin
 
 import (
 	"errors"
+	"log/slog"
 	"runtime"
 
 	"time"
@@ -26,7 +27,23 @@ type Config struct {
 	HeartbeatInterval time.Duration
 	RequestTimeout    time.Duration
 	WaitBufferSize    int
+	WaitOverflow      OverflowPolicy
+	WaitBlockTimeout  time.Duration
 	OnMessage         MessageHandler
+	Reconnect         *ReconnectPolicy
+	Retry             *RetryPolicy
+	OnStateChange     StateHandler
+	Dispatch          *DispatchConfig
+	Dialer            Dialer
+	Logger            *slog.Logger
+	Tracer            Tracer
+
+	RequestInterceptors []RequestInterceptor
+	InboundInterceptors []InboundInterceptor
+
+	// HeartbeatFromKeepalive replaces HeartbeatInterval with a third of the keepalive
+	// timeout CLH reports at registration. WithHeartbeatInterval turns it off.
+	HeartbeatFromKeepalive bool
 }
 
 func defaultConfig() Config {
@@ -39,25 +56,65 @@ func defaultConfig() Config {
 		HeartbeatInterval: defaultHeartbeat,
 		RequestTimeout:    defaultRequestTimeout,
 		WaitBufferSize:    defaultWaitBuffer,
+		WaitOverflow:      OverflowDropOldest,
+
+		HeartbeatFromKeepalive: true,
 	}
 }
 
+// WithPipePath sets the CLH endpoint. Besides a plain socket/pipe path it accepts
+// tcp://host:port, unix:///path/to/socket and pipe://name URLs.
 func WithPipePath(pipePath string) Option {
 	return func(cfg *Config) error {
 		if pipePath == "" {
 			return errors.New("pipe path cannot be empty")
 		}
+		if err := validateEndpoint(pipePath); err != nil {
+			return err
+		}
 		cfg.PipePath = pipePath
 		return nil
 	}
 }
 
+// WithDialer replaces endpoint dialing entirely, e.g. for tunnels or NewMemoryListener.
+func WithDialer(dialer Dialer) Option {
+	return func(cfg *Config) error {
+		if dialer == nil {
+			return errors.New("dialer cannot be nil")
+		}
+		cfg.Dialer = dialer
+		return nil
+	}
+}
+
+// WithHeartbeatInterval fixes the heartbeat interval instead of deriving it from CLH's
+// keepalive timeout. 0 disables heartbeats and liveness pings.
 func WithHeartbeatInterval(interval time.Duration) Option {
 	return func(cfg *Config) error {
 		if interval < 0 {
 			return errors.New("heartbeat interval cannot be negative")
 		}
 		cfg.HeartbeatInterval = interval
+		cfg.HeartbeatFromKeepalive = false
+		return nil
+	}
+}
+
+// WithLogger enables structured logs: connection lifecycle and shutdown reasons at Info or
+// Warn, and every request, heartbeat and dropped message at Debug.
+func WithLogger(logger *slog.Logger) Option {
+	return func(cfg *Config) error {
+		cfg.Logger = logger
+		return nil
+	}
+}
+
+// WithTracer opens a span per query and command, propagated to CLH as a W3C traceparent
+// attribute, and a span per inbound event linked to the traceparent CLH sent with it.
+func WithTracer(tracer Tracer) Option {
+	return func(cfg *Config) error {
+		cfg.Tracer = tracer
 		return nil
 	}
 }
@@ -69,6 +126,15 @@ func WithMessageHandler(handler MessageHandler) Option {
 	}
 }
 
+// WithStateHandler registers a callback for connection state transitions. It runs on the
+// goroutine that caused the transition, so it should return quickly.
+func WithStateHandler(handler StateHandler) Option {
+	return func(cfg *Config) error {
+		cfg.OnStateChange = handler
+		return nil
+	}
+}
+
 func WithRequestTimeout(timeout time.Duration) Option {
 	return func(cfg *Config) error {
 		if timeout <= 0 {
@@ -88,3 +154,96 @@ func WithWaitBufferSize(size int) Option {
 		return nil
 	}
 }
+
+// WithWaitOverflow sets what happens when the WaitMessage buffer is full. OverflowBlock
+// stalls the read loop for up to blockTimeout (0 waits until a consumer catches up) and
+// then drops the message. Drops are counted in Stats.
+func WithWaitOverflow(policy OverflowPolicy, blockTimeout time.Duration) Option {
+	return func(cfg *Config) error {
+		if err := policy.validate(); err != nil {
+			return err
+		}
+		if blockTimeout < 0 {
+			return errors.New("wait block timeout cannot be negative")
+		}
+		cfg.WaitOverflow = policy
+		cfg.WaitBlockTimeout = blockTimeout
+		return nil
+	}
+}
+
+// WithAutoReconnect keeps the client alive across CLH restarts: the pipe is redialed with
+// exponential backoff, the plugin is registered again and the last SubscribeEvents topics
+// are restored. WaitMessage and the message handler keep working across the gap.
+func WithAutoReconnect(policy ReconnectPolicy) Option {
+	return func(cfg *Config) error {
+		normalized, err := policy.normalize()
+		if err != nil {
+			return err
+		}
+		cfg.Reconnect = &normalized
+		return nil
+	}
+}
+
+// WithRetryPolicy retries queries, and commands the policy considers idempotent, after
+// transient failures such as a request timeout while CLH is busy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(cfg *Config) error {
+		normalized, err := policy.normalize()
+		if err != nil {
+			return err
+		}
+		cfg.Retry = &normalized
+		return nil
+	}
+}
+
+// WithOrderedDispatch runs WithMessageHandler and the typed On* handlers on a bounded
+// worker pool instead of one goroutine per message. Dropped messages are counted in Stats.
+func WithOrderedDispatch(dispatch DispatchConfig) Option {
+	return func(cfg *Config) error {
+		if dispatch.Workers <= 0 {
+			dispatch.Workers = 1
+		}
+		if dispatch.QueueSize <= 0 {
+			dispatch.QueueSize = defaultDispatchQueue
+		}
+		if dispatch.Overflow == "" {
+			dispatch.Overflow = OverflowBlock
+		}
+		if err := dispatch.Overflow.validate(); err != nil {
+			return err
+		}
+		cfg.Dispatch = &dispatch
+		return nil
+	}
+}
+
+// WithRequestInterceptor adds interceptors around every query and command. The first
+// one added is the outermost.
+func WithRequestInterceptor(interceptors ...RequestInterceptor) Option {
+	return func(cfg *Config) error {
+		for _, interceptor := range interceptors {
+			if interceptor == nil {
+				return errors.New("request interceptor cannot be nil")
+			}
+		}
+		cfg.RequestInterceptors = append(cfg.RequestInterceptors, interceptors...)
+		return nil
+	}
+}
+
+// WithInboundInterceptor adds interceptors in front of inbound message routing. The first
+// one added is the outermost.
+func WithInboundInterceptor(interceptors ...InboundInterceptor) Option {
+	return func(cfg *Config) error {
+		for _, interceptor := range interceptors {
+			if interceptor == nil {
+				return errors.New("inbound interceptor cannot be nil")
+			}
+		}
+		cfg.InboundInterceptors = append(cfg.InboundInterceptors, interceptors...)
+		return nil
+	}
+}
@@ -1,6 +1,8 @@
 package clhplugin
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	pb "github.com/SydneyOwl/clh-proto/gen/go/v20260312"
@@ -67,6 +69,28 @@ func toPBSettingsPatch(in SettingsPatch) *pb.PipeSettingsPatch {
 	return out
 }
 
+func fromPBNotification(in *pb.PipeNotificationCommand) NotificationCommand {
+	if in == nil {
+		return NotificationCommand{}
+	}
+	return NotificationCommand{
+		Level:   NotificationLevel(in.Level),
+		Title:   in.Title,
+		Message: in.Message,
+	}
+}
+
+func fromPBSettingsPatch(in *pb.PipeSettingsPatch) SettingsPatch {
+	out := SettingsPatch{Values: map[string]string{}}
+	if in == nil {
+		return out
+	}
+	for k, v := range in.Values {
+		out.Values[k] = v
+	}
+	return out
+}
+
 func toPBManifest(in PluginManifest) *pb.PipeRegisterPluginReq {
 	out := &pb.PipeRegisterPluginReq{
 		Uuid:              in.UUID,
@@ -609,6 +633,38 @@ func fromPBEnvelope(in *pb.PipeEnvelope) Envelope {
 	return out
 }
 
+func toPBEnvelope(in Envelope) (*pb.PipeEnvelope, error) {
+	out := &pb.PipeEnvelope{
+		Id:            in.ID,
+		CorrelationId: in.CorrelationID,
+		Kind:          pb.PipeEnvelopeKind(in.Kind),
+		Topic:         pb.PipeEnvelopeTopic(in.Topic),
+		Success:       in.Success,
+		Message:       in.Message,
+		ErrorCode:     in.ErrorCode,
+		Attributes:    map[string]string{},
+		Subscription:  toPBEventSubscription(in.Subscription),
+		Timestamp:     toTimestamp(in.Timestamp),
+	}
+	for k, v := range in.Attributes {
+		out.Attributes[k] = v
+	}
+	if unknown, ok := in.Payload.(*UnknownMessage); ok {
+		out.Payload = &anypb.Any{TypeUrl: unknown.TypeURL, Value: unknown.Raw}
+		return out, nil
+	}
+	msg, err := toPBPayload(in.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if msg != nil {
+		if out.Payload, err = anypb.New(msg); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
 func decodeEnvelopePayload(payload *anypb.Any) any {
 	if payload == nil {
 		return nil
@@ -652,6 +708,10 @@ func convertPayloadMessage(msg proto.Message) any {
 	case *pb.PipeEventSubscription:
 		model := fromPBEventSubscription(typed)
 		return model
+	case *pb.PipeSettingsPatch:
+		return fromPBSettingsPatch(typed)
+	case *pb.PipeNotificationCommand:
+		return fromPBNotification(typed)
 	case *pb.ClhServerStatusChanged:
 		return fromPBServerStatusChanged(typed)
 	case *pb.ClhPluginLifecycleChanged:
@@ -728,3 +788,486 @@ func fromAnyMessage(anyMsg *anypb.Any) (proto.Message, Message, error) {
 	}
 	return msg, out, nil
 }
+
+func toPBServerInfo(in ServerInfo) *pb.PipeServerInfo {
+	return &pb.PipeServerInfo{
+		ClhInstanceId:        in.InstanceID,
+		ClhVersion:           in.Version,
+		KeepaliveTimeoutSec:  in.KeepaliveTimeoutSec,
+		ConnectedPluginCount: in.ConnectedPluginCount,
+		UptimeSec:            in.UptimeSec,
+	}
+}
+
+func toPBPluginTelemetry(in PluginTelemetry) *pb.PipePluginTelemetry {
+	return &pb.PipePluginTelemetry{
+		PluginUuid:           in.PluginUUID,
+		ReceivedMessageCount: in.ReceivedMessageCount,
+		SentMessageCount:     in.SentMessageCount,
+		ControlRequestCount:  in.ControlRequestCount,
+		ControlErrorCount:    in.ControlErrorCount,
+		LastRoundtripMs:      in.LastRoundtripMs,
+		UpdatedAt:            toTimestamp(in.UpdatedAt),
+	}
+}
+
+func toPBRigSnapshot(in RigSnapshot) *pb.PipeRigStatusSnapshot {
+	return &pb.PipeRigStatusSnapshot{
+		Provider:       in.Provider,
+		Endpoint:       in.Endpoint,
+		ServiceRunning: in.ServiceRunning,
+		RigModel:       in.RigModel,
+		TxFrequencyHz:  in.TXFrequencyHz,
+		RxFrequencyHz:  in.RXFrequencyHz,
+		TxMode:         in.TXMode,
+		RxMode:         in.RXMode,
+		Split:          in.Split,
+		Power:          in.Power,
+		SampledAt:      toTimestamp(in.SampledAt),
+	}
+}
+
+func toPBUDPSnapshot(in UDPSnapshot) *pb.PipeUdpStatusSnapshot {
+	return &pb.PipeUdpStatusSnapshot{
+		ServerRunning: in.ServerRunning,
+		BindAddress:   in.BindAddress,
+		SampledAt:     toTimestamp(in.SampledAt),
+	}
+}
+
+func toPBQSODetail(in QSODetail) *pb.ClhQSODetail {
+	out := &pb.ClhQSODetail{
+		UploadedServices:             map[string]bool{},
+		UploadedServicesErrorMessage: map[string]string{},
+		OriginalCountryName:          in.OriginalCountryName,
+		CqZone:                       in.CQZone,
+		ItuZone:                      in.ITUZone,
+		Continent:                    in.Continent,
+		Latitude:                     in.Latitude,
+		Longitude:                    in.Longitude,
+		GmtOffset:                    in.GMTOffset,
+		Dxcc:                         in.DXCC,
+		DateTimeOff:                  toTimestamp(in.DateTimeOff),
+		DxCall:                       in.DXCall,
+		DxGrid:                       in.DXGrid,
+		TxFrequencyInHz:              in.TXFrequencyHz,
+		TxFrequencyInMeters:          in.TXFrequencyMeters,
+		Mode:                         in.Mode,
+		ParentMode:                   in.ParentMode,
+		ReportSent:                   in.ReportSent,
+		ReportReceived:               in.ReportReceived,
+		TxPower:                      in.TXPower,
+		Comments:                     in.Comments,
+		Name:                         in.Name,
+		DateTimeOn:                   toTimestamp(in.DateTimeOn),
+		OperatorCall:                 in.OperatorCall,
+		MyCall:                       in.MyCall,
+		MyGrid:                       in.MyGrid,
+		ExchangeSent:                 in.ExchangeSent,
+		ExchangeReceived:             in.ExchangeReceived,
+		AdifPropagationMode:          in.ADIFPropagationMode,
+		ClientId:                     in.ClientID,
+		RawData:                      in.RawData,
+		FailReason:                   in.FailReason,
+		UploadStatus:                 pb.ClhUploadStatus(in.UploadStatus),
+		ForcedUpload:                 in.ForcedUpload,
+		Uuid:                         in.UUID,
+	}
+	for k, v := range in.UploadedServices {
+		out.UploadedServices[k] = v
+	}
+	for k, v := range in.UploadedServicesErrorMessage {
+		out.UploadedServicesErrorMessage[k] = v
+	}
+	return out
+}
+
+func toPBQSOQueueSnapshot(in QSOQueueSnapshot) *pb.PipeQsoQueueSnapshot {
+	out := &pb.PipeQsoQueueSnapshot{SampledAt: toTimestamp(in.SampledAt)}
+	for _, detail := range in.Details {
+		out.Details = append(out.Details, toPBQSODetail(detail))
+	}
+	return out
+}
+
+func toPBSettingsSnapshot(in SettingsSnapshot) *pb.PipeMainSettingsSnapshot {
+	return &pb.PipeMainSettingsSnapshot{
+		InstanceName:         in.InstanceName,
+		Language:             in.Language,
+		EnablePlugin:         in.EnablePlugin,
+		DisableAllCharts:     in.DisableAllCharts,
+		MyMaidenheadGrid:     in.MyMaidenheadGrid,
+		AutoQsoUploadEnabled: in.AutoQSOUploadEnabled,
+		AutoRigUploadEnabled: in.AutoRigUploadEnabled,
+		EnableUdpServer:      in.EnableUDPServer,
+		SampledAt:            toTimestamp(in.SampledAt),
+	}
+}
+
+func toPBRuntimeSnapshot(in RuntimeSnapshot) *pb.PipeRuntimeSnapshot {
+	out := &pb.PipeRuntimeSnapshot{
+		ServerInfo:       toPBServerInfo(in.ServerInfo),
+		RigSnapshot:      toPBRigSnapshot(in.RigSnapshot),
+		UdpSnapshot:      toPBUDPSnapshot(in.UDPSnapshot),
+		SettingsSnapshot: toPBSettingsSnapshot(in.SettingsSnapshot),
+		SampledAt:        toTimestamp(in.SampledAt),
+	}
+	for _, item := range in.PluginTelemetry {
+		out.PluginTelemetry = append(out.PluginTelemetry, toPBPluginTelemetry(item))
+	}
+	return out
+}
+
+func toPBPluginInfo(in PluginInfo) *pb.PipePluginInfo {
+	out := &pb.PipePluginInfo{
+		Uuid:              in.UUID,
+		Name:              in.Name,
+		Version:           in.Version,
+		Description:       in.Description,
+		Metadata:          map[string]string{},
+		RegisteredAt:      toTimestamp(in.RegisteredAt),
+		LastHeartbeat:     toTimestamp(in.LastHeartbeat),
+		EventSubscription: toPBEventSubscription(&in.EventSubscription),
+		Telemetry:         toPBPluginTelemetry(in.Telemetry),
+	}
+	for k, v := range in.Metadata {
+		out.Metadata[k] = v
+	}
+	return out
+}
+
+func toPBPluginList(in PluginList) *pb.PipePluginList {
+	out := &pb.PipePluginList{}
+	for _, item := range in.Plugins {
+		out.Plugins = append(out.Plugins, toPBPluginInfo(item))
+	}
+	return out
+}
+
+func toPBRigData(in RigData) *pb.RigData {
+	return &pb.RigData{
+		Uuid:        in.UUID,
+		Provider:    in.Provider,
+		RigName:     in.RigName,
+		Frequency:   in.Frequency,
+		Mode:        in.Mode,
+		FrequencyRx: in.FrequencyRX,
+		ModeRx:      in.ModeRX,
+		Split:       in.Split,
+		Power:       in.Power,
+		Timestamp:   toTimestamp(in.Timestamp),
+	}
+}
+
+func toPBServerStatusChanged(in *ServerStatusChanged) *pb.ClhServerStatusChanged {
+	return &pb.ClhServerStatusChanged{
+		ClhInstanceId:        in.InstanceID,
+		ClhVersion:           in.Version,
+		ConnectedPluginCount: in.ConnectedPluginCount,
+		EventTime:            toTimestamp(in.EventTime),
+	}
+}
+
+func toPBPluginLifecycle(in *PluginLifecycleChanged) *pb.ClhPluginLifecycleChanged {
+	return &pb.ClhPluginLifecycleChanged{
+		PluginUuid:    in.PluginUUID,
+		PluginName:    in.PluginName,
+		PluginVersion: in.PluginVersion,
+		Reason:        in.Reason,
+		EventType:     pb.ClhPluginLifecycleEventType(in.EventType),
+		EventTime:     toTimestamp(in.EventTime),
+	}
+}
+
+func toPBQSOUploadStatus(in *QSOUploadStatusChanged) *pb.ClhQSOUploadStatusChanged {
+	out := &pb.ClhQSOUploadStatusChanged{}
+	if in.Detail != nil {
+		out.Detail = toPBQSODetail(*in.Detail)
+	}
+	return out
+}
+
+func toPBQSOQueueStatus(in *QSOQueueStatusChanged) *pb.ClhQsoQueueStatusChanged {
+	return &pb.ClhQsoQueueStatusChanged{
+		PendingCount:  in.PendingCount,
+		UploadedTotal: in.UploadedTotal,
+		FailedTotal:   in.FailedTotal,
+		EventTime:     toTimestamp(in.EventTime),
+	}
+}
+
+func toPBSettingsChanged(in *SettingsChanged) *pb.ClhSettingsChanged {
+	return &pb.ClhSettingsChanged{
+		ChangedPart: in.ChangedPart,
+		Summary:     in.Summary,
+		EventTime:   toTimestamp(in.EventTime),
+	}
+}
+
+func toPBPluginTelemetryChanged(in *PluginTelemetryChanged) *pb.ClhPluginTelemetryChanged {
+	return &pb.ClhPluginTelemetryChanged{
+		PluginUuid:           in.PluginUUID,
+		ReceivedMessageCount: in.ReceivedMessageCount,
+		SentMessageCount:     in.SentMessageCount,
+		ControlRequestCount:  in.ControlRequestCount,
+		ControlErrorCount:    in.ControlErrorCount,
+		LastRoundtripMs:      in.LastRoundtripMs,
+		EventTime:            toTimestamp(in.EventTime),
+	}
+}
+
+// internalEvent maps the populated member of a CLHInternalMessage to its event topic
+// and protobuf payload.
+func internalEvent(in CLHInternalMessage) (EnvelopeTopic, proto.Message, error) {
+	switch {
+	case in.QSOUploadStatus != nil:
+		return EnvelopeTopicEventQsoUploadStatus, toPBQSOUploadStatus(in.QSOUploadStatus), nil
+	case in.PluginLifecycle != nil:
+		return EnvelopeTopicEventPluginLifecycle, toPBPluginLifecycle(in.PluginLifecycle), nil
+	case in.ServerStatus != nil:
+		return EnvelopeTopicEventServerStatus, toPBServerStatusChanged(in.ServerStatus), nil
+	case in.QSOQueueStatus != nil:
+		return EnvelopeTopicEventQSOQueueStatus, toPBQSOQueueStatus(in.QSOQueueStatus), nil
+	case in.SettingsChanged != nil:
+		return EnvelopeTopicEventSettingsChanged, toPBSettingsChanged(in.SettingsChanged), nil
+	case in.PluginTelemetry != nil:
+		return EnvelopeTopicEventPluginTelemetry, toPBPluginTelemetryChanged(in.PluginTelemetry), nil
+	default:
+		return EnvelopeTopicUnspecified, nil, errors.New("internal message has no payload")
+	}
+}
+
+func toPBInternal(in CLHInternalMessage) *pb.ClhInternalMessage {
+	out := &pb.ClhInternalMessage{Timestamp: toTimestamp(in.Timestamp)}
+	switch {
+	case in.QSOUploadStatus != nil:
+		out.Payload = &pb.ClhInternalMessage_QsoUploadStatus{QsoUploadStatus: toPBQSOUploadStatus(in.QSOUploadStatus)}
+	case in.PluginLifecycle != nil:
+		out.Payload = &pb.ClhInternalMessage_PluginLifecycle{PluginLifecycle: toPBPluginLifecycle(in.PluginLifecycle)}
+	case in.ServerStatus != nil:
+		out.Payload = &pb.ClhInternalMessage_ServerStatus{ServerStatus: toPBServerStatusChanged(in.ServerStatus)}
+	case in.QSOQueueStatus != nil:
+		out.Payload = &pb.ClhInternalMessage_QsoQueueStatus{QsoQueueStatus: toPBQSOQueueStatus(in.QSOQueueStatus)}
+	case in.SettingsChanged != nil:
+		out.Payload = &pb.ClhInternalMessage_SettingsChanged{SettingsChanged: toPBSettingsChanged(in.SettingsChanged)}
+	case in.PluginTelemetry != nil:
+		out.Payload = &pb.ClhInternalMessage_PluginTelemetry{PluginTelemetry: toPBPluginTelemetryChanged(in.PluginTelemetry)}
+	}
+	return out
+}
+
+func toPBWsjtxDecode(in WsjtxDecode) *pb.WsjtxDecode {
+	return &pb.WsjtxDecode{
+		IsNew:          in.IsNew,
+		Time:           toTimestamp(in.Time),
+		Snr:            in.SNR,
+		DeltaTime:      in.DeltaTime,
+		DeltaFrequency: in.DeltaFrequency,
+		Mode:           in.Mode,
+		Message:        in.Message,
+		LowConfidence:  in.LowConfidence,
+		OffAir:         in.OffAir,
+	}
+}
+
+func toPBWsjtxMessage(in WsjtxMessage) *pb.WsjtxMessage {
+	out := &pb.WsjtxMessage{
+		Header: &pb.WsjtxMessageHeader{
+			MagicNumber:  in.Header.MagicNumber,
+			SchemaNumber: in.Header.SchemaNumber,
+			Type:         pb.WsjtxMessageType(in.Header.Type),
+			Id:           in.Header.ID,
+		},
+		Timestamp: toTimestamp(in.Timestamp),
+	}
+
+	switch {
+	case in.Heartbeat != nil:
+		out.Payload = &pb.WsjtxMessage_Heartbeat{Heartbeat: &pb.WsjtxHeartbeat{
+			MaxSchemaNumber: in.Heartbeat.MaxSchemaNumber,
+			Version:         in.Heartbeat.Version,
+			Revision:        in.Heartbeat.Revision,
+		}}
+	case in.Status != nil:
+		st := in.Status
+		status := &pb.WsjtxStatus{
+			DialFrequency:      st.DialFrequency,
+			Mode:               st.Mode,
+			DxCall:             st.DXCall,
+			Report:             st.Report,
+			TxMode:             st.TXMode,
+			TxEnabled:          st.TXEnabled,
+			Transmitting:       st.Transmitting,
+			Decoding:           st.Decoding,
+			RxDf:               st.RXDF,
+			TxDf:               st.TXDF,
+			DeCall:             st.DECall,
+			DeGrid:             st.DEGrid,
+			DxGrid:             st.DXGrid,
+			TxWatchdog:         st.TXWatchdog,
+			SubMode:            st.SubMode,
+			FastMode:           st.FastMode,
+			FrequencyTolerance: st.FrequencyTolerance,
+			TrPeriod:           st.TRPeriod,
+			ConfigName:         st.ConfigName,
+			TxMessage:          st.TXMessage,
+		}
+		if st.SpecialOpMode != nil {
+			mode := pb.SpecialOperationMode(*st.SpecialOpMode)
+			status.SpecialOpMode = &mode
+		}
+		out.Payload = &pb.WsjtxMessage_Status{Status: status}
+	case in.Decode != nil:
+		out.Payload = &pb.WsjtxMessage_Decode{Decode: toPBWsjtxDecode(*in.Decode)}
+	case in.Clear != nil:
+		out.Payload = &pb.WsjtxMessage_Clear{Clear: &pb.WsjtxClear{Window: pb.ClearWindow(in.Clear.Window)}}
+	case in.Reply != nil:
+		rep := in.Reply
+		out.Payload = &pb.WsjtxMessage_Reply{Reply: &pb.WsjtxReply{
+			Time:           toTimestamp(rep.Time),
+			Snr:            rep.SNR,
+			DeltaTime:      rep.DeltaTime,
+			DeltaFrequency: rep.DeltaFrequency,
+			Mode:           rep.Mode,
+			Message:        rep.Message,
+			LowConfidence:  rep.LowConfidence,
+			Modifiers:      rep.Modifiers,
+		}}
+	case in.QSOLogged != nil:
+		qso := in.QSOLogged
+		out.Payload = &pb.WsjtxMessage_QsoLogged{QsoLogged: &pb.WsjtxQsoLogged{
+			DatetimeOff:         toTimestamp(qso.DateTimeOff),
+			DxCall:              qso.DXCall,
+			DxGrid:              qso.DXGrid,
+			TxFrequency:         qso.TXFrequency,
+			Mode:                qso.Mode,
+			ReportSent:          qso.ReportSent,
+			ReportReceived:      qso.ReportReceived,
+			TxPower:             qso.TXPower,
+			Comments:            qso.Comments,
+			DatetimeOn:          toTimestamp(qso.DateTimeOn),
+			OperatorCall:        qso.OperatorCall,
+			MyCall:              qso.MyCall,
+			MyGrid:              qso.MyGrid,
+			ExchangeSent:        qso.ExchangeSent,
+			ExchangeReceived:    qso.ExchangeReceived,
+			AdifPropagationMode: qso.ADIFPropagationMode,
+		}}
+	case in.Close != nil:
+		out.Payload = &pb.WsjtxMessage_Close{Close: &pb.WsjtxClose{}}
+	case in.HaltTx != nil:
+		out.Payload = &pb.WsjtxMessage_HaltTx{HaltTx: &pb.WsjtxHaltTx{AutoTxOnly: in.HaltTx.AutoTXOnly}}
+	case in.FreeText != nil:
+		out.Payload = &pb.WsjtxMessage_FreeText{FreeText: &pb.WsjtxFreeText{
+			Text: in.FreeText.Text,
+			Send: in.FreeText.Send,
+		}}
+	case in.WSPRDecode != nil:
+		w := in.WSPRDecode
+		out.Payload = &pb.WsjtxMessage_WsprDecode{WsprDecode: &pb.WsjtxWsprDecode{
+			IsNew:     w.IsNew,
+			Time:      toTimestamp(w.Time),
+			Snr:       w.SNR,
+			DeltaTime: w.DeltaTime,
+			Frequency: w.Frequency,
+			Drift:     w.Drift,
+			Callsign:  w.Callsign,
+			Grid:      w.Grid,
+			Power:     w.Power,
+			OffAir:    w.OffAir,
+		}}
+	case in.Location != nil:
+		out.Payload = &pb.WsjtxMessage_Location{Location: &pb.WsjtxLocation{Location: in.Location.Location}}
+	case in.LoggedADIF != nil:
+		out.Payload = &pb.WsjtxMessage_LoggedAdif{LoggedAdif: &pb.WsjtxLoggedAdif{AdifText: in.LoggedADIF.ADIFText}}
+	case in.HighlightCallsign != nil:
+		hl := in.HighlightCallsign
+		out.Payload = &pb.WsjtxMessage_HighlightCallsign{HighlightCallsign: &pb.WsjtxHighlightCallsign{
+			Callsign:        hl.Callsign,
+			BackgroundColor: hl.BackgroundColor,
+			ForegroundColor: hl.ForegroundColor,
+			HighlightLast:   hl.HighlightLast,
+		}}
+	case in.SwitchConfiguration != nil:
+		out.Payload = &pb.WsjtxMessage_SwitchConfiguration{SwitchConfiguration: &pb.WsjtxSwitchConfiguration{
+			ConfigName: in.SwitchConfiguration.ConfigName,
+		}}
+	case in.Configure != nil:
+		cfg := in.Configure
+		out.Payload = &pb.WsjtxMessage_Configure{Configure: &pb.WsjtxConfigure{
+			Mode:               cfg.Mode,
+			FrequencyTolerance: cfg.FrequencyTolerance,
+			SubMode:            cfg.SubMode,
+			FastMode:           cfg.FastMode,
+			TrPeriod:           cfg.TRPeriod,
+			RxDf:               cfg.RXDF,
+			DxCall:             cfg.DXCall,
+			DxGrid:             cfg.DXGrid,
+			GenerateMessages:   cfg.GenerateMessages,
+		}}
+	}
+	return out
+}
+
+func toPBPackedDecode(in PackedDecodeMessage) *pb.PackedDecodeMessage {
+	out := &pb.PackedDecodeMessage{Timestamp: toTimestamp(in.Timestamp)}
+	for _, item := range in.Messages {
+		out.Messages = append(out.Messages, toPBWsjtxDecode(item))
+	}
+	return out
+}
+
+// toPBPayload accepts either a protobuf message or one of the SDK models that travel
+// in envelope payloads.
+func toPBPayload(payload any) (proto.Message, error) {
+	switch typed := payload.(type) {
+	case nil:
+		return nil, nil
+	case proto.Message:
+		return typed, nil
+	case ServerInfo:
+		return toPBServerInfo(typed), nil
+	case PluginList:
+		return toPBPluginList(typed), nil
+	case RuntimeSnapshot:
+		return toPBRuntimeSnapshot(typed), nil
+	case RigSnapshot:
+		return toPBRigSnapshot(typed), nil
+	case UDPSnapshot:
+		return toPBUDPSnapshot(typed), nil
+	case QSOQueueSnapshot:
+		return toPBQSOQueueSnapshot(typed), nil
+	case SettingsSnapshot:
+		return toPBSettingsSnapshot(typed), nil
+	case PluginTelemetry:
+		return toPBPluginTelemetry(typed), nil
+	case EventSubscription:
+		return toPBEventSubscription(&typed), nil
+	case SettingsPatch:
+		return toPBSettingsPatch(typed), nil
+	case NotificationCommand:
+		return toPBNotification(typed), nil
+	case RigData:
+		return toPBRigData(typed), nil
+	case WsjtxMessage:
+		return toPBWsjtxMessage(typed), nil
+	case PackedDecodeMessage:
+		return toPBPackedDecode(typed), nil
+	case CLHInternalMessage:
+		return toPBInternal(typed), nil
+	case *ServerStatusChanged:
+		return toPBServerStatusChanged(typed), nil
+	case *PluginLifecycleChanged:
+		return toPBPluginLifecycle(typed), nil
+	case *QSOUploadStatusChanged:
+		return toPBQSOUploadStatus(typed), nil
+	case *QSOQueueStatusChanged:
+		return toPBQSOQueueStatus(typed), nil
+	case *SettingsChanged:
+		return toPBSettingsChanged(typed), nil
+	case *PluginTelemetryChanged:
+		return toPBPluginTelemetryChanged(typed), nil
+	default:
+		return nil, fmt.Errorf("unsupported payload type %T", payload)
+	}
+}
@@ -0,0 +1,252 @@
+package clhplugin
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+	"unicode/utf16"
+)
+
+// Qt QDataStream primitives as WSJT-X uses them: big-endian integers, doubles, strings
+// as UTF-8 QByteArray (length-prefixed, 0xffffffff for null), QTime as milliseconds
+// since midnight, QDateTime as Julian day + QTime + time spec, QColor as spec + four
+// 16-bit channels.
+
+const (
+	qNull          = 0xffffffff
+	qJulianEpoch   = 2440588 // Julian day of 1970-01-01
+	qNullJulianDay = math.MinInt64
+
+	qTimeSpecLocal    = 0
+	qTimeSpecUTC      = 1
+	qTimeSpecOffset   = 2
+	qTimeSpecTimeZone = 3
+
+	qColorInvalid = 0
+	qColorRgb     = 1
+)
+
+var errShortWsjtxMessage = errors.New("wsjtx message truncated")
+
+type qdsWriter struct {
+	buf []byte
+}
+
+func (w *qdsWriter) u8(v uint8)   { w.buf = append(w.buf, v) }
+func (w *qdsWriter) u16(v uint16) { w.buf = binary.BigEndian.AppendUint16(w.buf, v) }
+func (w *qdsWriter) u32(v uint32) { w.buf = binary.BigEndian.AppendUint32(w.buf, v) }
+func (w *qdsWriter) u64(v uint64) { w.buf = binary.BigEndian.AppendUint64(w.buf, v) }
+func (w *qdsWriter) i32(v int32)  { w.u32(uint32(v)) }
+func (w *qdsWriter) f64(v float64) {
+	w.u64(math.Float64bits(v))
+}
+
+func (w *qdsWriter) bool(v bool) {
+	if v {
+		w.u8(1)
+	} else {
+		w.u8(0)
+	}
+}
+
+func (w *qdsWriter) str(s string) {
+	w.u32(uint32(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *qdsWriter) qtime(t time.Time) {
+	if t.IsZero() {
+		w.u32(qNull)
+		return
+	}
+	t = t.UTC()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	w.u32(uint32(t.Sub(midnight).Milliseconds()))
+}
+
+func (w *qdsWriter) qdatetime(t time.Time) {
+	if t.IsZero() {
+		jd := int64(qNullJulianDay)
+		w.u64(uint64(jd))
+		w.u32(qNull)
+		w.u8(qTimeSpecUTC)
+		return
+	}
+	t = t.UTC()
+	days := t.Unix() / 86400
+	if t.Unix() < 0 && t.Unix()%86400 != 0 {
+		days--
+	}
+	w.u64(uint64(days + qJulianEpoch))
+	w.qtime(t)
+	w.u8(qTimeSpecUTC)
+}
+
+// qcolor writes 0xAARRGGBB. Zero is an invalid color, which WSJT-X treats as "no
+// highlight"; a zero alpha with a non-zero color means opaque.
+func (w *qdsWriter) qcolor(argb uint32) {
+	if argb == 0 {
+		w.u8(qColorInvalid)
+		w.u16(0xffff)
+		w.u16(0)
+		w.u16(0)
+		w.u16(0)
+		w.u16(0)
+		return
+	}
+	a := uint16(argb >> 24)
+	if a == 0 {
+		a = 0xff
+	}
+	w.u8(qColorRgb)
+	w.u16(a * 0x101)
+	w.u16(uint16(argb>>16&0xff) * 0x101)
+	w.u16(uint16(argb>>8&0xff) * 0x101)
+	w.u16(uint16(argb&0xff) * 0x101)
+	w.u16(0)
+}
+
+// qdsReader reads fields in order; the first error sticks and later reads return zero.
+type qdsReader struct {
+	data []byte
+	pos  int
+	err  error
+	now  time.Time // reference for bare QTime values
+}
+
+// qtimeSkew is how far a bare QTime may be ahead of the reader's clock before it is taken
+// to be from the previous UTC day, e.g. a decode from 23:59:45 read just after midnight.
+const qtimeSkew = 5 * time.Minute
+
+func (r *qdsReader) more() bool {
+	return r.err == nil && r.pos < len(r.data)
+}
+
+func (r *qdsReader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > len(r.data)-r.pos {
+		r.err = errShortWsjtxMessage
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *qdsReader) u8() uint8 {
+	if b := r.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *qdsReader) u16() uint16 {
+	if b := r.take(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *qdsReader) u32() uint32 {
+	if b := r.take(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *qdsReader) u64() uint64 {
+	if b := r.take(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *qdsReader) i32() int32    { return int32(r.u32()) }
+func (r *qdsReader) f64() float64  { return math.Float64frombits(r.u64()) }
+func (r *qdsReader) bool() bool    { return r.u8() != 0 }
+func (r *qdsReader) bytes() []byte { return r.take(r.length()) }
+
+func (r *qdsReader) length() int {
+	n := r.u32()
+	if n == qNull {
+		return 0
+	}
+	if int64(n) > int64(len(r.data)-r.pos) {
+		if r.err == nil {
+			r.err = errShortWsjtxMessage
+		}
+		return 0
+	}
+	return int(n)
+}
+
+func (r *qdsReader) str() string {
+	return string(r.bytes())
+}
+
+// qstring reads a UTF-16 QString; WSJT-X only sends these inside QTimeZone.
+func (r *qdsReader) qstring() string {
+	b := r.bytes()
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+func (r *qdsReader) qtime() time.Time {
+	ms := r.u32()
+	if ms == qNull || r.err != nil {
+		return time.Time{}
+	}
+	n := r.now
+	t := time.Date(n.Year(), n.Month(), n.Day(), 0, 0, 0, 0, time.UTC).Add(time.Duration(ms) * time.Millisecond)
+	if t.After(n.Add(qtimeSkew)) {
+		t = t.AddDate(0, 0, -1)
+	}
+	return t
+}
+
+func (r *qdsReader) qdatetime() time.Time {
+	jd := int64(r.u64())
+	ms := r.u32()
+	spec := r.u8()
+	loc := time.UTC
+	switch spec {
+	case qTimeSpecLocal:
+		loc = time.Local
+	case qTimeSpecOffset:
+		loc = time.FixedZone("", int(r.i32()))
+	case qTimeSpecTimeZone:
+		if l, err := time.LoadLocation(r.qstring()); err == nil {
+			loc = l
+		}
+	}
+	if r.err != nil || jd == qNullJulianDay || ms == qNull {
+		return time.Time{}
+	}
+	day := time.Unix((jd-qJulianEpoch)*86400, 0).UTC()
+	t := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc).Add(time.Duration(ms) * time.Millisecond)
+	return t.UTC()
+}
+
+func (r *qdsReader) qcolor() uint32 {
+	spec := r.u8()
+	a, red, green, blue := r.u16(), r.u16(), r.u16(), r.u16()
+	_ = r.u16()
+	switch spec {
+	case qColorInvalid:
+		return 0
+	case qColorRgb:
+		return uint32(a>>8)<<24 | uint32(red>>8)<<16 | uint32(green>>8)<<8 | uint32(blue>>8)
+	default:
+		if r.err == nil {
+			r.err = fmt.Errorf("unsupported QColor spec %d", spec)
+		}
+		return 0
+	}
+}
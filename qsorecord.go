@@ -0,0 +1,104 @@
+package clhplugin
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/SydneyOwl/clh-plugin-go-sdk/adif"
+	"github.com/SydneyOwl/clh-plugin-go-sdk/bandplan"
+)
+
+// QSORecord is a typed ADIF record; see the adif package for reading and writing files.
+type QSORecord = adif.Record
+
+// UploadQSOs encodes records as ADIF and sends them with UploadExternalQSO.
+func (c *Client) UploadQSOs(ctx context.Context, records []QSORecord) (Envelope, error) {
+	if len(records) == 0 {
+		return Envelope{}, errors.New("at least one record is required")
+	}
+	return c.UploadExternalQSO(ctx, adif.Encode(records))
+}
+
+// QSORecord converts a WSJT-X QSO Logged message. Modes that ADIF treats as submodes,
+// such as FT4, are split into MODE and SUBMODE.
+func (q WsjtxQSOLogged) QSORecord() QSORecord {
+	mode, submode := bandplan.ADIFMode(q.Mode)
+	rec := QSORecord{
+		Call:            q.DXCall,
+		TimeOn:          q.DateTimeOn,
+		TimeOff:         q.DateTimeOff,
+		FreqHz:          q.TXFrequency,
+		Mode:            mode,
+		Submode:         submode,
+		RSTSent:         q.ReportSent,
+		RSTRcvd:         q.ReportReceived,
+		Gridsquare:      q.DXGrid,
+		MyGridsquare:    q.MyGrid,
+		Comment:         q.Comments,
+		Operator:        q.OperatorCall,
+		StationCallsign: q.MyCall,
+		TXPwr:           q.TXPower,
+	}
+	if q.ExchangeSent != nil {
+		rec.STXString = *q.ExchangeSent
+	}
+	if q.ExchangeReceived != nil {
+		rec.SRXString = *q.ExchangeReceived
+	}
+	if q.ADIFPropagationMode != nil {
+		rec.PropMode = *q.ADIFPropagationMode
+	}
+	return rec
+}
+
+// QSORecord converts a QSO from CLH's upload queue. ParentMode is used as the ADIF mode
+// when it differs from Mode. Location details CLH resolved are kept as ADIF fields.
+func (d QSODetail) QSORecord() QSORecord {
+	mode, submode := bandplan.ADIFMode(d.Mode)
+	if d.ParentMode != "" && d.ParentMode != d.Mode {
+		mode, submode = d.ParentMode, d.Mode
+	}
+	rec := QSORecord{
+		Call:            d.DXCall,
+		TimeOn:          d.DateTimeOn,
+		TimeOff:         d.DateTimeOff,
+		Band:            d.TXFrequencyMeters,
+		FreqHz:          d.TXFrequencyHz,
+		Mode:            mode,
+		Submode:         submode,
+		RSTSent:         d.ReportSent,
+		RSTRcvd:         d.ReportReceived,
+		Gridsquare:      d.DXGrid,
+		MyGridsquare:    d.MyGrid,
+		Name:            d.Name,
+		Comment:         d.Comments,
+		Operator:        d.OperatorCall,
+		StationCallsign: d.MyCall,
+		TXPwr:           d.TXPower,
+		SRXString:       d.ExchangeReceived,
+		STXString:       d.ExchangeSent,
+		PropMode:        d.ADIFPropagationMode,
+		Extra:           map[string]string{},
+	}
+	if rec.Band != "" && bandplan.BandName(d.TXFrequencyHz) != "" {
+		// TXFrequencyMeters may be a display string; trust the frequency when it is known.
+		rec.Band = ""
+	}
+	if d.CQZone > 0 {
+		rec.Extra["CQZ"] = strconv.Itoa(int(d.CQZone))
+	}
+	if d.ITUZone > 0 {
+		rec.Extra["ITUZ"] = strconv.Itoa(int(d.ITUZone))
+	}
+	if d.Continent != "" {
+		rec.Extra["CONT"] = d.Continent
+	}
+	if d.DXCC != "" {
+		rec.Extra["DXCC"] = d.DXCC
+	}
+	if d.OriginalCountryName != "" {
+		rec.Extra["COUNTRY"] = d.OriginalCountryName
+	}
+	return rec
+}
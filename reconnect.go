@@ -0,0 +1,164 @@
+package clhplugin
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	defaultReconnectInitialDelay = 500 * time.Millisecond
+	defaultReconnectMaxDelay     = 30 * time.Second
+	defaultReconnectMultiplier   = 2.0
+	defaultReconnectJitter       = 0.2
+)
+
+// ReconnectPolicy controls how the client redials after the connection to CLH is lost.
+// MaxAttempts <= 0 means retry until Close is called.
+type ReconnectPolicy struct {
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+	Jitter       float64
+	MaxAttempts  int
+}
+
+func DefaultReconnectPolicy() ReconnectPolicy {
+	return ReconnectPolicy{
+		InitialDelay: defaultReconnectInitialDelay,
+		MaxDelay:     defaultReconnectMaxDelay,
+		Multiplier:   defaultReconnectMultiplier,
+		Jitter:       defaultReconnectJitter,
+	}
+}
+
+func (p ReconnectPolicy) normalize() (ReconnectPolicy, error) {
+	if p.InitialDelay < 0 || p.MaxDelay < 0 {
+		return p, errors.New("reconnect delays cannot be negative")
+	}
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return p, errors.New("reconnect multiplier must be at least 1")
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return p, errors.New("reconnect jitter must be between 0 and 1")
+	}
+	if p.InitialDelay == 0 {
+		p.InitialDelay = defaultReconnectInitialDelay
+	}
+	if p.MaxDelay == 0 {
+		p.MaxDelay = defaultReconnectMaxDelay
+	}
+	if p.MaxDelay < p.InitialDelay {
+		p.MaxDelay = p.InitialDelay
+	}
+	if p.Multiplier == 0 {
+		p.Multiplier = defaultReconnectMultiplier
+	}
+	return p, nil
+}
+
+// Backoff returns the delay before the given (zero based) reconnect attempt.
+func (p ReconnectPolicy) Backoff(attempt int) time.Duration {
+	return backoff(p.InitialDelay, p.MaxDelay, p.Multiplier, p.Jitter, attempt)
+}
+
+func backoff(initial, maxDelay time.Duration, multiplier, jitter float64, attempt int) time.Duration {
+	delay := float64(initial)
+	for i := 0; i < attempt && delay < float64(maxDelay); i++ {
+		delay *= multiplier
+	}
+	if delay > float64(maxDelay) {
+		delay = float64(maxDelay)
+	}
+	if jitter > 0 {
+		delay += delay * jitter * (rand.Float64()*2 - 1)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return time.Duration(delay)
+}
+
+func (c *Client) reconnectLoop() {
+	policy := *c.cfg.Reconnect
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-c.closingCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var lastErr error
+	for attempt := 0; policy.MaxAttempts <= 0 || attempt < policy.MaxAttempts; attempt++ {
+		c.setState(ConnectionStateReconnecting, lastErr)
+		delay := policy.Backoff(attempt)
+		c.log.Info("reconnecting", "attempt", attempt+1, "delay", delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-c.stopCh:
+			timer.Stop()
+			c.finish()
+			return
+		}
+
+		c.metrics.reconnectAttempts.Add(1)
+		if err := c.reconnectOnce(ctx); err != nil {
+			if errors.Is(err, ErrClientClosed) {
+				c.finish()
+				return
+			}
+			lastErr = err
+			continue
+		}
+
+		c.metrics.reconnects.Add(1)
+		c.restoreSubscription(ctx)
+		return
+	}
+
+	c.log.Error("reconnect gave up", "attempts", policy.MaxAttempts, "error", lastErr)
+	c.setState(ConnectionStateDisconnected, lastErr)
+	c.finish()
+}
+
+func (c *Client) reconnectOnce(ctx context.Context) error {
+	if c.cfg.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.RequestTimeout)
+		defer cancel()
+	}
+
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	if c.closed.Load() {
+		return ErrClientClosed
+	}
+
+	conn, resp, err := c.dialAndRegister(ctx)
+	if err != nil {
+		if c.closed.Load() {
+			return ErrClientClosed
+		}
+		return err
+	}
+	c.startSession(conn, resp)
+	return nil
+}
+
+func (c *Client) restoreSubscription(ctx context.Context) {
+	c.subMu.Lock()
+	sub := c.subscription
+	c.subMu.Unlock()
+	if sub == nil {
+		return
+	}
+	if _, err := c.SubscribeEvents(ctx, *sub); err != nil {
+		c.log.Warn("restoring event subscription failed", "error", err)
+	}
+}
@@ -0,0 +1,170 @@
+package clhplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultRetryMaxAttempts  = 3
+	defaultRetryInitialDelay = 200 * time.Millisecond
+	defaultRetryMaxDelay     = 2 * time.Second
+	defaultRetryMultiplier   = 2.0
+	defaultRetryJitter       = 0.2
+)
+
+// RetryPolicy retries requests that fail transiently. Queries are always retried; commands
+// only when Idempotent reports true, so an ADIF upload or a reupload is never sent twice.
+// Each attempt gets WithRequestTimeout unless ctx carries its own deadline, in which case
+// the deadline covers all attempts.
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt too; 0 means 3.
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+	Jitter       float64
+	// Retryable reports whether err may succeed on another attempt; nil means IsTransient.
+	Retryable func(error) bool
+	// Idempotent reports whether a command may be sent again; nil means IsIdempotentCommand.
+	Idempotent func(Request) bool
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  defaultRetryMaxAttempts,
+		InitialDelay: defaultRetryInitialDelay,
+		MaxDelay:     defaultRetryMaxDelay,
+		Multiplier:   defaultRetryMultiplier,
+		Jitter:       defaultRetryJitter,
+	}
+}
+
+func (p RetryPolicy) normalize() (RetryPolicy, error) {
+	if p.MaxAttempts < 0 {
+		return p, errors.New("retry attempts cannot be negative")
+	}
+	if p.InitialDelay < 0 || p.MaxDelay < 0 {
+		return p, errors.New("retry delays cannot be negative")
+	}
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return p, errors.New("retry multiplier must be at least 1")
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return p, errors.New("retry jitter must be between 0 and 1")
+	}
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = defaultRetryMaxAttempts
+	}
+	if p.InitialDelay == 0 {
+		p.InitialDelay = defaultRetryInitialDelay
+	}
+	if p.MaxDelay == 0 {
+		p.MaxDelay = defaultRetryMaxDelay
+	}
+	if p.MaxDelay < p.InitialDelay {
+		p.MaxDelay = p.InitialDelay
+	}
+	if p.Multiplier == 0 {
+		p.Multiplier = defaultRetryMultiplier
+	}
+	if p.Retryable == nil {
+		p.Retryable = IsTransient
+	}
+	if p.Idempotent == nil {
+		p.Idempotent = IsIdempotentCommand
+	}
+	return p, nil
+}
+
+// Backoff returns the delay after the given (zero based) failed attempt.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	return backoff(p.InitialDelay, p.MaxDelay, p.Multiplier, p.Jitter, attempt)
+}
+
+// RetryError is returned for a request that was retried and still failed. Attempts counts
+// every request sent, the first one included, and is always more than one. A request that
+// failed on its first attempt returns that error unwrapped.
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("request failed after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// IsTransient reports whether err is a request timeout or a connection that was lost or
+// is not re-established yet.
+func IsTransient(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrConnectionLost) ||
+		errors.Is(err, ErrNotConnected)
+}
+
+// IsIdempotentCommand reports whether sending req twice has the same effect as once:
+// showing or hiding the main window, toggles with an explicit enabled attribute,
+// switching the rig backend, replacing the event subscription and updating settings.
+func IsIdempotentCommand(req Request) bool {
+	switch req.Topic {
+	case EnvelopeTopicCommandShowMainWindow,
+		EnvelopeTopicCommandHideMainWindow,
+		EnvelopeTopicCommandSwitchRigBackend,
+		EnvelopeTopicCommandSubscribeEvents,
+		EnvelopeTopicCommandUpdateSettings:
+		return true
+	case EnvelopeTopicCommandToggleUDPServer, EnvelopeTopicCommandToggleRigBackend:
+		_, ok := req.Attributes["enabled"]
+		return ok
+	}
+	return false
+}
+
+// interceptor runs innermost in the request chain, so user interceptors see one call
+// with the final outcome. Requests that may not be retried pass through unchanged.
+func (p RetryPolicy) interceptor(done <-chan struct{}) RequestInterceptor {
+	return func(ctx context.Context, req Request, next RequestHandler) (Envelope, error) {
+		switch req.Kind {
+		case EnvelopeKindQuery:
+		case EnvelopeKindCommand:
+			if !p.Idempotent(req) {
+				return next(ctx, req)
+			}
+		default:
+			return next(ctx, req)
+		}
+
+		for attempt := 1; ; attempt++ {
+			env, err := next(ctx, req)
+			if err == nil {
+				return env, nil
+			}
+			if attempt >= p.MaxAttempts || ctx.Err() != nil || !p.Retryable(err) {
+				return env, retryError(attempt, err)
+			}
+			timer := time.NewTimer(p.Backoff(attempt - 1))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return env, retryError(attempt, err)
+			case <-done:
+				timer.Stop()
+				return env, retryError(attempt, err)
+			}
+		}
+	}
+}
+
+func retryError(attempts int, err error) error {
+	if attempts <= 1 {
+		return err
+	}
+	return &RetryError{Attempts: attempts, Err: err}
+}
@@ -0,0 +1,204 @@
+package clhplugin_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	clhplugin "github.com/SydneyOwl/clh-plugin-go-sdk"
+	"github.com/SydneyOwl/clh-plugin-go-sdk/clhtest"
+)
+
+func fastRetry() clhplugin.RetryPolicy {
+	return clhplugin.RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+}
+
+// slowHandler answers after the client's request timeout for the first n calls.
+func slowHandler(calls *atomic.Int32, n int32, payload any) clhtest.Handler {
+	return func(ctx context.Context, _ clhtest.Request) clhtest.Response {
+		if calls.Add(1) <= n {
+			select {
+			case <-ctx.Done():
+			case <-time.After(200 * time.Millisecond):
+			}
+		}
+		return clhtest.Reply(payload)
+	}
+}
+
+func TestRetryTransient(t *testing.T) {
+	host := startHost(t)
+	var calls atomic.Int32
+	host.Handle(clhplugin.EnvelopeTopicQueryRigSnapshot, slowHandler(&calls, 2, clhplugin.RigSnapshot{TXFrequencyHz: 7074000}))
+	client := connectClient(t, host,
+		clhplugin.WithRequestTimeout(50*time.Millisecond),
+		clhplugin.WithRetryPolicy(fastRetry()))
+
+	// WithRequestTimeout only applies to a ctx without a deadline.
+	rig, err := client.QueryRigSnapshot(context.Background())
+	if err != nil || rig.TXFrequencyHz != 7074000 {
+		t.Fatalf("QueryRigSnapshot = %+v, %v", rig, err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("%d attempts, want 3", n)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	host := startHost(t)
+	var calls atomic.Int32
+	host.Handle(clhplugin.EnvelopeTopicQueryRigSnapshot, slowHandler(&calls, 10, clhplugin.RigSnapshot{}))
+	client := connectClient(t, host,
+		clhplugin.WithRequestTimeout(50*time.Millisecond),
+		clhplugin.WithRetryPolicy(fastRetry()))
+
+	_, err := client.QueryRigSnapshot(context.Background())
+	var retryErr *clhplugin.RetryError
+	if !errors.As(err, &retryErr) || retryErr.Attempts != 3 {
+		t.Fatalf("err = %v, want a RetryError after 3 attempts", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v does not wrap the timeout", err)
+	}
+}
+
+func TestRetryNotRetried(t *testing.T) {
+	enabled := true
+	tests := []struct {
+		name     string
+		topic    clhplugin.EnvelopeTopic
+		handler  func(calls *atomic.Int32) clhtest.Handler
+		call     func(context.Context, *clhplugin.Client) error
+		attempts int32
+		check    func(error) bool
+	}{
+		{
+			name:  "remote error",
+			topic: clhplugin.EnvelopeTopicQueryRigSnapshot,
+			handler: func(calls *atomic.Int32) clhtest.Handler {
+				return func(context.Context, clhtest.Request) clhtest.Response {
+					calls.Add(1)
+					return clhtest.Fail("rig_offline", "no rig")
+				}
+			},
+			call: func(ctx context.Context, c *clhplugin.Client) error {
+				_, err := c.QueryRigSnapshot(ctx)
+				return err
+			},
+			attempts: 1,
+			check: func(err error) bool {
+				var remote *clhplugin.RemoteError
+				return errors.As(err, &remote) && remote.Code == "rig_offline"
+			},
+		},
+		{
+			name:  "toggle without explicit state",
+			topic: clhplugin.EnvelopeTopicCommandToggleUDPServer,
+			handler: func(calls *atomic.Int32) clhtest.Handler {
+				return slowHandler(calls, 10, clhplugin.UDPSnapshot{})
+			},
+			call: func(ctx context.Context, c *clhplugin.Client) error {
+				_, err := c.ToggleUDPServer(ctx, nil)
+				return err
+			},
+			attempts: 1,
+			check:    func(err error) bool { return errors.Is(err, context.DeadlineExceeded) },
+		},
+		{
+			name:  "toggle with explicit state",
+			topic: clhplugin.EnvelopeTopicCommandToggleUDPServer,
+			handler: func(calls *atomic.Int32) clhtest.Handler {
+				return slowHandler(calls, 10, clhplugin.UDPSnapshot{})
+			},
+			call: func(ctx context.Context, c *clhplugin.Client) error {
+				_, err := c.ToggleUDPServer(ctx, &enabled)
+				return err
+			},
+			attempts: 3,
+			check: func(err error) bool {
+				var retryErr *clhplugin.RetryError
+				return errors.As(err, &retryErr) && retryErr.Attempts == 3
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host := startHost(t)
+			var calls atomic.Int32
+			host.Handle(tt.topic, tt.handler(&calls))
+			client := connectClient(t, host,
+				clhplugin.WithRequestTimeout(50*time.Millisecond),
+				clhplugin.WithRetryPolicy(fastRetry()))
+
+			// WithRequestTimeout only applies to a ctx without a deadline.
+			err := tt.call(context.Background(), client)
+			if !tt.check(err) {
+				t.Fatalf("err = %v (%T)", err, err)
+			}
+			if tt.attempts == 1 {
+				var retryErr *clhplugin.RetryError
+				if errors.As(err, &retryErr) {
+					t.Fatalf("single attempt wrapped in %v", err)
+				}
+			}
+			// A timed-out attempt may still be running in the host.
+			waitUntil(t, func() bool { return calls.Load() >= tt.attempts })
+			time.Sleep(20 * time.Millisecond)
+			if n := calls.Load(); n != tt.attempts {
+				t.Fatalf("%d attempts, want %d", n, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := clhplugin.RetryPolicy{InitialDelay: 100 * time.Millisecond, MaxDelay: time.Second, Multiplier: 2}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for attempt, w := range want {
+		if got := p.Backoff(attempt); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", attempt, got, w)
+		}
+	}
+
+	p.Jitter = 0.5
+	for range 100 {
+		if got := p.Backoff(1); got < 100*time.Millisecond || got > 300*time.Millisecond {
+			t.Fatalf("jittered Backoff(1) = %v", got)
+		}
+	}
+}
+
+func TestRetryPolicyInvalid(t *testing.T) {
+	for _, p := range []clhplugin.RetryPolicy{
+		{MaxAttempts: -1},
+		{InitialDelay: -time.Second},
+		{Multiplier: 0.5},
+		{Jitter: 1.5},
+	} {
+		if _, err := clhplugin.NewClient(testManifest, clhplugin.WithRetryPolicy(p)); err == nil {
+			t.Errorf("policy %+v accepted", p)
+		}
+	}
+}
+
+func TestIsIdempotentCommand(t *testing.T) {
+	tests := []struct {
+		req  clhplugin.Request
+		want bool
+	}{
+		{clhplugin.Request{Topic: clhplugin.EnvelopeTopicCommandShowMainWindow}, true},
+		{clhplugin.Request{Topic: clhplugin.EnvelopeTopicCommandUpdateSettings}, true},
+		{clhplugin.Request{Topic: clhplugin.EnvelopeTopicCommandToggleUDPServer}, false},
+		{clhplugin.Request{Topic: clhplugin.EnvelopeTopicCommandToggleRigBackend, Attributes: map[string]string{"enabled": "false"}}, true},
+		{clhplugin.Request{Topic: clhplugin.EnvelopeTopicCommandUploadExternalQSO}, false},
+		{clhplugin.Request{Topic: clhplugin.EnvelopeTopicCommandTriggerQSOReupload}, false},
+		{clhplugin.Request{Topic: clhplugin.EnvelopeTopicCommandSendNotification}, false},
+	}
+	for _, tt := range tests {
+		if got := clhplugin.IsIdempotentCommand(tt.req); got != tt.want {
+			t.Errorf("IsIdempotentCommand(%d) = %v, want %v", tt.req.Topic, got, tt.want)
+		}
+	}
+}
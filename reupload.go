@@ -0,0 +1,211 @@
+package clhplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// qsoIDSeparator joins IDs in the qsoIds attribute of TriggerQSOReupload.
+const qsoIDSeparator = ";;;"
+
+// reuploadChunkBytes caps the joined qsoIds of one request, leaving room under
+// maxDelimitedMessageSize for the rest of the envelope.
+const reuploadChunkBytes = maxDelimitedMessageSize - 64<<10
+
+type ReuploadOption func(*ReuploadConfig) error
+
+type ReuploadConfig struct {
+	// ChunkSize limits IDs per request; 0 only splits to stay under the message size limit.
+	ChunkSize int
+	// CheckQueue rejects IDs that are not the UUID of a QSODetail in QueryQSOQueueSnapshot.
+	CheckQueue bool
+}
+
+func WithReuploadChunkSize(n int) ReuploadOption {
+	return func(cfg *ReuploadConfig) error {
+		if n < 0 {
+			return errors.New("reupload chunk size cannot be negative")
+		}
+		cfg.ChunkSize = n
+		return nil
+	}
+}
+
+// WithReuploadQueueCheck queries the QSO queue first and fails on IDs it does not contain.
+func WithReuploadQueueCheck() ReuploadOption {
+	return func(cfg *ReuploadConfig) error {
+		cfg.CheckQueue = true
+		return nil
+	}
+}
+
+// ReuploadChunk is the outcome of one TriggerQSOReupload request.
+type ReuploadChunk struct {
+	IDs      []string
+	Envelope Envelope
+	Err      error
+}
+
+type ReuploadResult struct {
+	Chunks []ReuploadChunk
+}
+
+// Succeeded returns the IDs of chunks the server accepted.
+func (r ReuploadResult) Succeeded() []string {
+	return r.ids(false)
+}
+
+// Failed returns the IDs of chunks that failed or were not sent.
+func (r ReuploadResult) Failed() []string {
+	return r.ids(true)
+}
+
+func (r ReuploadResult) ids(failed bool) []string {
+	var out []string
+	for _, chunk := range r.Chunks {
+		if (chunk.Err != nil) == failed {
+			out = append(out, chunk.IDs...)
+		}
+	}
+	return out
+}
+
+// Err joins the errors of all failed chunks.
+func (r ReuploadResult) Err() error {
+	var errs []error
+	for _, chunk := range r.Chunks {
+		if chunk.Err != nil {
+			errs = append(errs, chunk.Err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ReuploadQSOs asks CLH to upload the QSOs with the given QSODetail.UUID values again.
+// Duplicate IDs are dropped. IDs are only checked against QSODetail.UUID when
+// WithReuploadQueueCheck is set; otherwise they are sent as given. Large lists are split
+// into several requests; a failed chunk does not stop the rest, and the returned error is
+// ReuploadResult.Err.
+func (c *Client) ReuploadQSOs(ctx context.Context, ids []string, opts ...ReuploadOption) (ReuploadResult, error) {
+	var cfg ReuploadConfig
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&cfg); err != nil {
+			return ReuploadResult{}, err
+		}
+	}
+
+	ids, err := normalizeQSOIDs(ids)
+	if err != nil {
+		return ReuploadResult{}, err
+	}
+	if cfg.CheckQueue {
+		snapshot, err := c.QueryQSOQueueSnapshot(ctx)
+		if err != nil {
+			return ReuploadResult{}, err
+		}
+		known := make(map[string]bool, len(snapshot.Details))
+		for _, d := range snapshot.Details {
+			known[d.UUID] = true
+		}
+		var unknown []string
+		for _, id := range ids {
+			if !known[id] {
+				unknown = append(unknown, id)
+			}
+		}
+		if len(unknown) > 0 {
+			return ReuploadResult{}, fmt.Errorf("qso ids not in queue: %s", strings.Join(unknown, ", "))
+		}
+	}
+
+	var result ReuploadResult
+	for _, chunk := range chunkQSOIDs(ids, cfg.ChunkSize, reuploadChunkBytes) {
+		rc := ReuploadChunk{IDs: chunk}
+		if err := ctx.Err(); err != nil {
+			rc.Err = err
+		} else {
+			rc.Envelope, rc.Err = c.TriggerQSOReupload(ctx, map[string]string{
+				"qsoIds": strings.Join(chunk, qsoIDSeparator),
+			})
+		}
+		result.Chunks = append(result.Chunks, rc)
+	}
+	return result, result.Err()
+}
+
+// ReuploadFailedQSOs reuploads every queued QSO whose UploadStatus is UploadStatusFail.
+// It returns an empty result when there is nothing to retry.
+func (c *Client) ReuploadFailedQSOs(ctx context.Context, opts ...ReuploadOption) (ReuploadResult, error) {
+	snapshot, err := c.QueryQSOQueueSnapshot(ctx)
+	if err != nil {
+		return ReuploadResult{}, err
+	}
+	var ids []string
+	for _, d := range snapshot.Details {
+		if d.UploadStatus == UploadStatusFail && d.UUID != "" {
+			ids = append(ids, d.UUID)
+		}
+	}
+	if len(ids) == 0 {
+		return ReuploadResult{}, nil
+	}
+	return c.ReuploadQSOs(ctx, ids, opts...)
+}
+
+// normalizeQSOIDs trims and de-duplicates ids, rejecting values that could not be a
+// QSODetail.UUID or would corrupt the joined attribute.
+func normalizeQSOIDs(ids []string) ([]string, error) {
+	seen := make(map[string]bool, len(ids))
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			return nil, errors.New("qso id cannot be empty")
+		}
+		if strings.Contains(id, qsoIDSeparator) || strings.IndexFunc(id, unicode.IsSpace) >= 0 {
+			return nil, fmt.Errorf("invalid qso id %q", id)
+		}
+		if len(id) > reuploadChunkBytes {
+			return nil, fmt.Errorf("qso id too long: %d bytes", len(id))
+		}
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	if len(out) == 0 {
+		return nil, ErrNoQSOIDs
+	}
+	return out, nil
+}
+
+// chunkQSOIDs splits ids so each chunk has at most maxCount IDs (0 = no limit) and
+// joins to at most maxBytes.
+func chunkQSOIDs(ids []string, maxCount, maxBytes int) [][]string {
+	var (
+		chunks [][]string
+		start  int
+		size   int
+	)
+	for i, id := range ids {
+		n := len(id)
+		if i > start {
+			n += len(qsoIDSeparator)
+		}
+		if i > start && (size+n > maxBytes || (maxCount > 0 && i-start >= maxCount)) {
+			chunks = append(chunks, ids[start:i])
+			start, size, n = i, 0, len(id)
+		}
+		size += n
+	}
+	if start < len(ids) {
+		chunks = append(chunks, ids[start:])
+	}
+	return chunks
+}
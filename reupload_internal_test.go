@@ -0,0 +1,43 @@
+package clhplugin
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestChunkQSOIDs(t *testing.T) {
+	ids := []string{"aaaa", "bb", "cccccc", "d", "ee"}
+	tests := []struct {
+		maxCount int
+		maxBytes int
+		want     [][]string
+	}{
+		{0, 100, [][]string{ids}},
+		{2, 100, [][]string{{"aaaa", "bb"}, {"cccccc", "d"}, {"ee"}}},
+		// "aaaa;;;bb" is 9 bytes; adding ";;;cccccc" would exceed 12.
+		{0, 12, [][]string{{"aaaa", "bb"}, {"cccccc", "d"}, {"ee"}}},
+		{0, 6, [][]string{{"aaaa"}, {"bb"}, {"cccccc"}, {"d", "ee"}}},
+		{1, 100, [][]string{{"aaaa"}, {"bb"}, {"cccccc"}, {"d"}, {"ee"}}},
+	}
+	for _, tt := range tests {
+		got := chunkQSOIDs(ids, tt.maxCount, tt.maxBytes)
+		if !slices.EqualFunc(got, tt.want, slices.Equal[[]string]) {
+			t.Errorf("chunkQSOIDs(%d, %d) = %v, want %v", tt.maxCount, tt.maxBytes, got, tt.want)
+		}
+		for _, chunk := range got {
+			if n := len(strings.Join(chunk, qsoIDSeparator)); len(chunk) > 1 && n > tt.maxBytes {
+				t.Errorf("chunk %v joins to %d bytes, over %d", chunk, n, tt.maxBytes)
+			}
+		}
+	}
+	if got := chunkQSOIDs(nil, 2, 100); len(got) != 0 {
+		t.Errorf("chunkQSOIDs(nil) = %v", got)
+	}
+}
+
+func TestNormalizeQSOIDsLength(t *testing.T) {
+	if _, err := normalizeQSOIDs([]string{strings.Repeat("x", reuploadChunkBytes+1)}); err == nil {
+		t.Fatal("id longer than a chunk accepted")
+	}
+}
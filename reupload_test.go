@@ -0,0 +1,159 @@
+package clhplugin_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	clhplugin "github.com/SydneyOwl/clh-plugin-go-sdk"
+	"github.com/SydneyOwl/clh-plugin-go-sdk/clhtest"
+)
+
+// reuploadHost records the qsoIds of every TriggerQSOReupload and fails requests that
+// contain an ID in fail.
+type reuploadHost struct {
+	*clhtest.Host
+
+	mu     sync.Mutex
+	chunks [][]string
+}
+
+func startReuploadHost(t *testing.T, queue clhplugin.QSOQueueSnapshot, fail ...string) *reuploadHost {
+	t.Helper()
+	h := &reuploadHost{Host: startHost(t)}
+	h.Handle(clhplugin.EnvelopeTopicCommandTriggerQSOReupload, func(_ context.Context, req clhtest.Request) clhtest.Response {
+		ids := strings.Split(req.Attributes["qsoIds"], ";;;")
+		h.mu.Lock()
+		h.chunks = append(h.chunks, ids)
+		h.mu.Unlock()
+		for _, id := range ids {
+			if slices.Contains(fail, id) {
+				return clhtest.Fail("reupload_failed", "cannot reupload "+id)
+			}
+		}
+		return clhtest.Reply(nil)
+	})
+	h.Handle(clhplugin.EnvelopeTopicQueryQSOQueueSnapshot, func(context.Context, clhtest.Request) clhtest.Response {
+		return clhtest.Reply(queue)
+	})
+	return h
+}
+
+func (h *reuploadHost) received() [][]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.chunks)
+}
+
+func TestReuploadChunks(t *testing.T) {
+	host := startReuploadHost(t, clhplugin.QSOQueueSnapshot{})
+	client := connectClient(t, host.Host)
+
+	ids := []string{"a", "b", "c", "d", "e"}
+	result, err := client.ReuploadQSOs(testContext(t), ids, clhplugin.WithReuploadChunkSize(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}
+	if got := host.received(); !slices.EqualFunc(got, want, slices.Equal[[]string]) {
+		t.Fatalf("host received %v, want %v", got, want)
+	}
+	if len(result.Chunks) != 3 || !slices.Equal(result.Succeeded(), ids) || len(result.Failed()) != 0 {
+		t.Fatalf("result = %+v", result)
+	}
+}
+
+func TestReuploadPartialFailure(t *testing.T) {
+	host := startReuploadHost(t, clhplugin.QSOQueueSnapshot{}, "c")
+	client := connectClient(t, host.Host)
+
+	result, err := client.ReuploadQSOs(testContext(t), []string{"a", "b", "c", "d", "e"}, clhplugin.WithReuploadChunkSize(2))
+	var remote *clhplugin.RemoteError
+	if !errors.As(err, &remote) || remote.Code != "reupload_failed" {
+		t.Fatalf("err = %v, want the failed chunk's RemoteError", err)
+	}
+	// The failed chunk does not stop the next one.
+	if n := len(host.received()); n != 3 {
+		t.Fatalf("%d requests, want 3", n)
+	}
+	if got := result.Succeeded(); !slices.Equal(got, []string{"a", "b", "e"}) {
+		t.Errorf("succeeded = %v", got)
+	}
+	if got := result.Failed(); !slices.Equal(got, []string{"c", "d"}) {
+		t.Errorf("failed = %v", got)
+	}
+	if result.Chunks[1].Err == nil || result.Chunks[0].Err != nil || result.Chunks[2].Err != nil {
+		t.Errorf("chunk errors = %v, %v, %v", result.Chunks[0].Err, result.Chunks[1].Err, result.Chunks[2].Err)
+	}
+}
+
+func TestReuploadIDs(t *testing.T) {
+	host := startReuploadHost(t, clhplugin.QSOQueueSnapshot{})
+	client := connectClient(t, host.Host)
+	ctx := testContext(t)
+
+	result, err := client.ReuploadQSOs(ctx, []string{"a", " b ", "a", "b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := host.received(); len(got) != 1 || !slices.Equal(got[0], []string{"a", "b", "c"}) {
+		t.Fatalf("host received %v", got)
+	}
+	if !slices.Equal(result.Succeeded(), []string{"a", "b", "c"}) {
+		t.Fatalf("succeeded = %v", result.Succeeded())
+	}
+
+	for _, ids := range [][]string{
+		nil,
+		{"a", ""},
+		{"a b"},
+		{"a;;;b"},
+	} {
+		if _, err := client.ReuploadQSOs(ctx, ids); err == nil {
+			t.Errorf("ReuploadQSOs(%q) succeeded", ids)
+		}
+	}
+	if _, err := client.ReuploadQSOs(ctx, nil); !errors.Is(err, clhplugin.ErrNoQSOIDs) {
+		t.Errorf("empty ids: err = %v", err)
+	}
+	if _, err := client.ReuploadQSOs(ctx, []string{"a"}, clhplugin.WithReuploadChunkSize(-1)); err == nil {
+		t.Error("negative chunk size accepted")
+	}
+	if n := len(host.received()); n != 1 {
+		t.Fatalf("%d requests after rejected calls, want 1", n)
+	}
+}
+
+func TestReuploadQueueCheck(t *testing.T) {
+	queue := clhplugin.QSOQueueSnapshot{Details: []clhplugin.QSODetail{
+		{UUID: "q1", UploadStatus: clhplugin.UploadStatusSuccess},
+		{UUID: "q2", UploadStatus: clhplugin.UploadStatusFail},
+		{UUID: "q3", UploadStatus: clhplugin.UploadStatusFail},
+	}}
+	host := startReuploadHost(t, queue)
+	client := connectClient(t, host.Host)
+	ctx := testContext(t)
+
+	_, err := client.ReuploadQSOs(ctx, []string{"q1", "x9"}, clhplugin.WithReuploadQueueCheck())
+	if err == nil || !strings.Contains(err.Error(), "x9") {
+		t.Fatalf("err = %v, want the unknown id reported", err)
+	}
+	if n := len(host.received()); n != 0 {
+		t.Fatalf("%d requests sent despite unknown ids", n)
+	}
+	// Without the check the ID is sent as is.
+	if _, err := client.ReuploadQSOs(ctx, []string{"x9"}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := client.ReuploadFailedQSOs(ctx, clhplugin.WithReuploadQueueCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(result.Succeeded(), []string{"q2", "q3"}) {
+		t.Fatalf("succeeded = %v", result.Succeeded())
+	}
+}
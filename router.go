@@ -0,0 +1,222 @@
+package clhplugin
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type handlerEntry[T any] struct {
+	id uint64
+	fn func(T)
+}
+
+// handlerSet keeps handlers in registration order. count, when set, tracks the number of
+// handlers registered across several sets.
+type handlerSet[T any] struct {
+	mu      sync.RWMutex
+	seq     uint64
+	entries []handlerEntry[T]
+}
+
+func (s *handlerSet[T]) add(count *atomic.Int64, fn func(T)) func() {
+	if fn == nil {
+		return func() {}
+	}
+
+	s.mu.Lock()
+	s.seq++
+	id := s.seq
+	s.entries = append(s.entries, handlerEntry[T]{id: id, fn: fn})
+	s.mu.Unlock()
+	if count != nil {
+		count.Add(1)
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			for i, e := range s.entries {
+				if e.id == id {
+					s.entries = append(s.entries[:i:i], s.entries[i+1:]...)
+					if count != nil {
+						count.Add(-1)
+					}
+					return
+				}
+			}
+		})
+	}
+}
+
+func (s *handlerSet[T]) emit(v T) {
+	s.mu.RLock()
+	entries := s.entries
+	s.mu.RUnlock()
+	for _, e := range entries {
+		e.fn(v)
+	}
+}
+
+// router fans typed payloads out of inbound messages to per-topic handlers.
+type router struct {
+	count atomic.Int64
+
+	rigData          handlerSet[RigData]
+	wsjtxMessage     handlerSet[WsjtxMessage]
+	wsjtxDecode      handlerSet[WsjtxDecode]
+	wsjtxStatus      handlerSet[WsjtxStatus]
+	packedDecode     handlerSet[PackedDecodeMessage]
+	qsoUploadStatus  handlerSet[QSOUploadStatusChanged]
+	qsoQueueStatus   handlerSet[QSOQueueStatusChanged]
+	pluginLifecycle  handlerSet[PluginLifecycleChanged]
+	serverStatus     handlerSet[ServerStatusChanged]
+	settingsChanged  handlerSet[SettingsChanged]
+	pluginTelemetry  handlerSet[PluginTelemetryChanged]
+	connectionClosed handlerSet[ConnectionClosed]
+}
+
+func (r *router) active() bool {
+	return r.count.Load() > 0
+}
+
+func (r *router) route(msg Message) {
+	switch msg.Kind {
+	case InboundKindRigData:
+		if msg.RigData != nil {
+			r.rigData.emit(*msg.RigData)
+		}
+	case InboundKindCLHInternal:
+		if msg.CLHInternal != nil {
+			r.routeInternal(*msg.CLHInternal)
+		}
+	case InboundKindConnectionClosed:
+		if msg.ConnectionClosed != nil {
+			r.connectionClosed.emit(*msg.ConnectionClosed)
+		}
+	case InboundKindEnvelope:
+		if msg.Envelope != nil && msg.Envelope.Kind == EnvelopeKindEvent {
+			r.routePayload(msg.Envelope.Payload)
+		}
+	}
+}
+
+func (r *router) routePayload(payload any) {
+	switch typed := payload.(type) {
+	case RigData:
+		r.rigData.emit(typed)
+	case WsjtxMessage:
+		r.routeWsjtx(typed)
+	case PackedDecodeMessage:
+		r.packedDecode.emit(typed)
+	case CLHInternalMessage:
+		r.routeInternal(typed)
+	case *QSOUploadStatusChanged:
+		if typed != nil {
+			r.qsoUploadStatus.emit(*typed)
+		}
+	case *QSOQueueStatusChanged:
+		if typed != nil {
+			r.qsoQueueStatus.emit(*typed)
+		}
+	case *PluginLifecycleChanged:
+		if typed != nil {
+			r.pluginLifecycle.emit(*typed)
+		}
+	case *ServerStatusChanged:
+		if typed != nil {
+			r.serverStatus.emit(*typed)
+		}
+	case *SettingsChanged:
+		if typed != nil {
+			r.settingsChanged.emit(*typed)
+		}
+	case *PluginTelemetryChanged:
+		if typed != nil {
+			r.pluginTelemetry.emit(*typed)
+		}
+	}
+}
+
+func (r *router) routeWsjtx(msg WsjtxMessage) {
+	r.wsjtxMessage.emit(msg)
+	if msg.Decode != nil {
+		r.wsjtxDecode.emit(*msg.Decode)
+	}
+	if msg.Status != nil {
+		r.wsjtxStatus.emit(*msg.Status)
+	}
+}
+
+func (r *router) routeInternal(msg CLHInternalMessage) {
+	if msg.QSOUploadStatus != nil {
+		r.qsoUploadStatus.emit(*msg.QSOUploadStatus)
+	}
+	if msg.PluginLifecycle != nil {
+		r.pluginLifecycle.emit(*msg.PluginLifecycle)
+	}
+	if msg.ServerStatus != nil {
+		r.serverStatus.emit(*msg.ServerStatus)
+	}
+	if msg.QSOQueueStatus != nil {
+		r.qsoQueueStatus.emit(*msg.QSOQueueStatus)
+	}
+	if msg.SettingsChanged != nil {
+		r.settingsChanged.emit(*msg.SettingsChanged)
+	}
+	if msg.PluginTelemetry != nil {
+		r.pluginTelemetry.emit(*msg.PluginTelemetry)
+	}
+}
+
+// The On* methods register typed handlers for inbound events, whether they arrive as bare
+// messages or as event envelopes. Each returns a func that removes the handler.
+
+func (c *Client) OnRigData(fn func(RigData)) func() {
+	return c.router.rigData.add(&c.router.count, fn)
+}
+
+func (c *Client) OnWsjtxMessage(fn func(WsjtxMessage)) func() {
+	return c.router.wsjtxMessage.add(&c.router.count, fn)
+}
+
+func (c *Client) OnWsjtxDecode(fn func(WsjtxDecode)) func() {
+	return c.router.wsjtxDecode.add(&c.router.count, fn)
+}
+
+func (c *Client) OnWsjtxStatus(fn func(WsjtxStatus)) func() {
+	return c.router.wsjtxStatus.add(&c.router.count, fn)
+}
+
+func (c *Client) OnPackedDecode(fn func(PackedDecodeMessage)) func() {
+	return c.router.packedDecode.add(&c.router.count, fn)
+}
+
+func (c *Client) OnQSOUploadStatus(fn func(QSOUploadStatusChanged)) func() {
+	return c.router.qsoUploadStatus.add(&c.router.count, fn)
+}
+
+func (c *Client) OnQSOQueueStatus(fn func(QSOQueueStatusChanged)) func() {
+	return c.router.qsoQueueStatus.add(&c.router.count, fn)
+}
+
+func (c *Client) OnPluginLifecycle(fn func(PluginLifecycleChanged)) func() {
+	return c.router.pluginLifecycle.add(&c.router.count, fn)
+}
+
+func (c *Client) OnServerStatus(fn func(ServerStatusChanged)) func() {
+	return c.router.serverStatus.add(&c.router.count, fn)
+}
+
+func (c *Client) OnSettingsChanged(fn func(SettingsChanged)) func() {
+	return c.router.settingsChanged.add(&c.router.count, fn)
+}
+
+func (c *Client) OnPluginTelemetry(fn func(PluginTelemetryChanged)) func() {
+	return c.router.pluginTelemetry.add(&c.router.count, fn)
+}
+
+func (c *Client) OnConnectionClosed(fn func(ConnectionClosed)) func() {
+	return c.router.connectionClosed.add(&c.router.count, fn)
+}
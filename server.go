@@ -0,0 +1,857 @@
+package clhplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/SydneyOwl/clh-proto/gen/go/v20260312"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	defaultServerKeepalive       = 30 * time.Second
+	defaultServerRegisterTimeout = 10 * time.Second
+	defaultServerWriteTimeout    = 10 * time.Second
+
+	ServerCodeUnsupportedTopic = "unsupported_topic"
+	ServerCodeInternal         = "internal_error"
+	ServerCodeNotFound         = "not_found"
+)
+
+// ServerRequest is a query or command received from a plugin. Payload holds the decoded
+// SDK model (for example SettingsPatch or NotificationCommand) or an *UnknownMessage.
+type ServerRequest struct {
+	Plugin       PluginInfo
+	ID           string
+	Kind         EnvelopeKind
+	Topic        EnvelopeTopic
+	Attributes   map[string]string
+	Subscription *EventSubscription
+	Payload      any
+	Timestamp    time.Time
+}
+
+// ServerHandler answers one request. The returned payload may be a protobuf message or an
+// SDK model such as ServerInfo or RigSnapshot. Returning a *RemoteError sets the error code
+// sent to the plugin; any other error is reported as ServerCodeInternal.
+type ServerHandler func(ctx context.Context, req ServerRequest) (any, error)
+
+type ServerOption func(*ServerConfig) error
+
+type ServerConfig struct {
+	InstanceID       string
+	Version          string
+	KeepaliveTimeout time.Duration
+	OnRegister       func(PluginInfo) error
+	OnLifecycle      func(PluginLifecycleChanged)
+	OnHeartbeat      func(PluginInfo)
+	OnRequest        func(ServerRequest)
+
+	RegisterTimeout time.Duration
+	WriteTimeout    time.Duration
+}
+
+func defaultServerConfig() ServerConfig {
+	return ServerConfig{
+		InstanceID:       "clh-plugin-go-sdk",
+		Version:          defaultSDKVersion,
+		KeepaliveTimeout: defaultServerKeepalive,
+		RegisterTimeout:  defaultServerRegisterTimeout,
+		WriteTimeout:     defaultServerWriteTimeout,
+	}
+}
+
+func WithServerInstance(instanceID, version string) ServerOption {
+	return func(cfg *ServerConfig) error {
+		if instanceID == "" {
+			return errors.New("instance id cannot be empty")
+		}
+		cfg.InstanceID = instanceID
+		cfg.Version = version
+		return nil
+	}
+}
+
+// WithKeepaliveTimeout sets how long a plugin may go without a heartbeat before it is
+// dropped. Zero disables enforcement. Plugins are told the timeout in whole seconds,
+// rounded up.
+func WithKeepaliveTimeout(timeout time.Duration) ServerOption {
+	return func(cfg *ServerConfig) error {
+		if timeout < 0 {
+			return errors.New("keepalive timeout cannot be negative")
+		}
+		cfg.KeepaliveTimeout = timeout
+		return nil
+	}
+}
+
+// WithRegisterTimeout bounds the register handshake of a new connection. Zero disables it.
+func WithRegisterTimeout(timeout time.Duration) ServerOption {
+	return func(cfg *ServerConfig) error {
+		if timeout < 0 {
+			return errors.New("register timeout cannot be negative")
+		}
+		cfg.RegisterTimeout = timeout
+		return nil
+	}
+}
+
+// WithWriteTimeout bounds every write to a plugin; a plugin that stops reading is
+// disconnected instead of stalling broadcasts. Zero disables it.
+func WithWriteTimeout(timeout time.Duration) ServerOption {
+	return func(cfg *ServerConfig) error {
+		if timeout < 0 {
+			return errors.New("write timeout cannot be negative")
+		}
+		cfg.WriteTimeout = timeout
+		return nil
+	}
+}
+
+// WithRegisterHook is called before a plugin is accepted; returning an error rejects it.
+func WithRegisterHook(hook func(PluginInfo) error) ServerOption {
+	return func(cfg *ServerConfig) error {
+		cfg.OnRegister = hook
+		return nil
+	}
+}
+
+func WithLifecycleHandler(handler func(PluginLifecycleChanged)) ServerOption {
+	return func(cfg *ServerConfig) error {
+		cfg.OnLifecycle = handler
+		return nil
+	}
+}
+
+func WithHeartbeatHandler(handler func(PluginInfo)) ServerOption {
+	return func(cfg *ServerConfig) error {
+		cfg.OnHeartbeat = handler
+		return nil
+	}
+}
+
+// WithRequestObserver sees every request before it is handled, including unsupported ones.
+func WithRequestObserver(observer func(ServerRequest)) ServerOption {
+	return func(cfg *ServerConfig) error {
+		cfg.OnRequest = observer
+		return nil
+	}
+}
+
+type serverPlugin struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+
+	// guarded by Server.mu
+	info PluginInfo
+}
+
+// Server hosts the CLH plugin protocol: it accepts plugin connections, performs the
+// register handshake, enforces heartbeats and routes queries and commands to handlers.
+type Server struct {
+	cfg     ServerConfig
+	started time.Time
+	seq     atomic.Uint64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu        sync.Mutex
+	closed    bool
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	plugins   map[string]*serverPlugin
+	handlers  map[EnvelopeTopic]ServerHandler
+}
+
+func NewServer(opts ...ServerOption) (*Server, error) {
+	cfg := defaultServerConfig()
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Server{
+		cfg:       cfg,
+		started:   time.Now(),
+		listeners: map[net.Listener]struct{}{},
+		conns:     map[net.Conn]struct{}{},
+		plugins:   map[string]*serverPlugin{},
+		handlers:  map[EnvelopeTopic]ServerHandler{},
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	if cfg.KeepaliveTimeout > 0 {
+		s.wg.Add(1)
+		go s.keepaliveLoop()
+	}
+	return s, nil
+}
+
+// Handle installs the handler for a query or command topic. A nil handler removes it,
+// falling back to the built-in handler if there is one.
+func (s *Server) Handle(topic EnvelopeTopic, handler ServerHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if handler == nil {
+		delete(s.handlers, topic)
+		return
+	}
+	s.handlers[topic] = handler
+}
+
+// ListenAndServe listens on endpoint (a plain pipe path or a tcp://, unix:// or pipe://
+// URL) and serves until Close is called.
+func (s *Server) ListenAndServe(endpoint string) error {
+	if err := validateEndpoint(endpoint); err != nil {
+		return err
+	}
+	l, err := listenEndpoint(endpoint)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts plugin connections on l until Close is called. It always returns a
+// non-nil error; after Close that error is ErrServerClosed.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = l.Close()
+		return ErrServerClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return ErrServerClosed
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+// Close notifies every plugin with PipeConnectionClosed, closes all listeners and
+// connections and waits for handlers to return.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	plugins := make([]*serverPlugin, 0, len(s.plugins))
+	for _, p := range s.plugins {
+		plugins = append(plugins, p)
+	}
+	listeners := make([]net.Listener, 0, len(s.listeners))
+	for l := range s.listeners {
+		listeners = append(listeners, l)
+	}
+	s.mu.Unlock()
+
+	s.cancel()
+	for _, p := range plugins {
+		_ = s.send(p, &pb.PipeConnectionClosed{Timestamp: nowTimestamp()})
+	}
+
+	var err error
+	for _, l := range listeners {
+		if cerr := l.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	s.mu.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) Info() ServerInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.serverInfoLocked()
+}
+
+func (s *Server) Plugins() []PluginInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]PluginInfo, 0, len(s.plugins))
+	for _, p := range s.plugins {
+		out = append(out, clonePluginInfo(p.info))
+	}
+	return out
+}
+
+func (s *Server) Plugin(uuid string) (PluginInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.plugins[uuid]
+	if !ok {
+		return PluginInfo{}, false
+	}
+	return clonePluginInfo(p.info), true
+}
+
+// Broadcast sends an event envelope to every plugin subscribed to topic and returns how
+// many plugins it reached.
+func (s *Server) Broadcast(topic EnvelopeTopic, payload any) (int, error) {
+	env, err := s.eventEnvelope(topic, payload)
+	if err != nil {
+		return 0, err
+	}
+	delivered := 0
+	for _, p := range s.subscribers(topic) {
+		if s.send(p, env) == nil {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// BroadcastInternal sends the populated member of msg on its matching event topic.
+func (s *Server) BroadcastInternal(msg CLHInternalMessage) (int, error) {
+	topic, payload, err := internalEvent(msg)
+	if err != nil {
+		return 0, err
+	}
+	return s.Broadcast(topic, payload)
+}
+
+// Send delivers an event envelope to one plugin regardless of its subscription.
+func (s *Server) Send(uuid string, topic EnvelopeTopic, payload any) error {
+	env, err := s.eventEnvelope(topic, payload)
+	if err != nil {
+		return err
+	}
+	p, err := s.lookup(uuid)
+	if err != nil {
+		return err
+	}
+	return s.send(p, env)
+}
+
+// SendRaw writes payload as a top level message, e.g. bare RigData or CLHInternalMessage.
+func (s *Server) SendRaw(uuid string, payload any) error {
+	msg, err := toPBPayload(payload)
+	if err != nil {
+		return err
+	}
+	if msg == nil {
+		return errors.New("payload is required")
+	}
+	p, err := s.lookup(uuid)
+	if err != nil {
+		return err
+	}
+	return s.send(p, msg)
+}
+
+// Disconnect sends PipeConnectionClosed to the plugin and closes its connection.
+func (s *Server) Disconnect(uuid string) error {
+	p, err := s.lookup(uuid)
+	if err != nil {
+		return err
+	}
+	_ = s.send(p, &pb.PipeConnectionClosed{Timestamp: nowTimestamp()})
+	return p.conn.Close()
+}
+
+// Drop closes the plugin's connection without notifying it.
+func (s *Server) Drop(uuid string) error {
+	p, err := s.lookup(uuid)
+	if err != nil {
+		return err
+	}
+	return p.conn.Close()
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		_ = conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	p, err := s.register(conn)
+	if err != nil {
+		return
+	}
+
+	reason, eventType := "connection closed", PluginLifecycleEventDisconnected
+	defer func() {
+		s.unregister(p, reason, eventType)
+	}()
+
+	for {
+		anyMsg := &anypb.Any{}
+		if err = readDelimitedMessage(conn, anyMsg); err != nil {
+			if s.isTimedOut(p) {
+				reason, eventType = "keepalive timeout", PluginLifecycleEventTimeout
+			}
+			return
+		}
+		msg, err := anypb.UnmarshalNew(anyMsg, proto.UnmarshalOptions{})
+		s.mu.Lock()
+		p.info.Telemetry.ReceivedMessageCount++
+		p.info.Telemetry.UpdatedAt = time.Now().UTC()
+		s.mu.Unlock()
+		if err != nil {
+			continue
+		}
+
+		switch typed := msg.(type) {
+		case *pb.PipeHeartbeat:
+			s.mu.Lock()
+			p.info.LastHeartbeat = time.Now().UTC()
+			info := clonePluginInfo(p.info)
+			s.mu.Unlock()
+			if s.cfg.OnHeartbeat != nil {
+				s.cfg.OnHeartbeat(info)
+			}
+		case *pb.PipeDeregisterPluginReq:
+			reason = typed.Reason
+			if reason == "" {
+				reason = "deregistered"
+			}
+			return
+		case *pb.PipeEnvelope:
+			kind := EnvelopeKind(typed.Kind)
+			if kind != EnvelopeKindQuery && kind != EnvelopeKindCommand {
+				continue
+			}
+			s.wg.Add(1)
+			go s.serveRequest(p, typed)
+		}
+	}
+}
+
+func (s *Server) register(conn net.Conn) (*serverPlugin, error) {
+	if s.cfg.RegisterTimeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(s.cfg.RegisterTimeout))
+	}
+	req := &pb.PipeRegisterPluginReq{}
+	if err := readDelimitedMessage(conn, req); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	info := PluginInfo{
+		UUID:              req.Uuid,
+		Name:              req.Name,
+		Version:           req.Version,
+		Description:       req.Description,
+		Metadata:          map[string]string{},
+		RegisteredAt:      now,
+		LastHeartbeat:     now,
+		EventSubscription: fromPBEventSubscription(req.EventSubscription),
+		Telemetry:         PluginTelemetry{PluginUUID: req.Uuid, UpdatedAt: now},
+	}
+	for k, v := range req.Metadata {
+		info.Metadata[k] = v
+	}
+
+	var regErr error
+	if info.UUID == "" || info.Name == "" || info.Version == "" {
+		regErr = fmt.Errorf("%w: uuid/name/version are required", ErrInvalidManifest)
+	} else if s.cfg.OnRegister != nil {
+		regErr = s.cfg.OnRegister(clonePluginInfo(info))
+	}
+	if regErr != nil {
+		_ = writeDelimitedMessage(conn, &pb.PipeRegisterPluginResp{
+			Success:   false,
+			Message:   regErr.Error(),
+			Timestamp: nowTimestamp(),
+		})
+		return nil, regErr
+	}
+
+	// Hold writeMu until the response is out and the handshake deadline is cleared, so a
+	// Broadcast to the newly listed plugin neither overtakes the response nor loses its
+	// write deadline.
+	p := &serverPlugin{conn: conn, info: info}
+	p.writeMu.Lock()
+
+	s.mu.Lock()
+	old := s.plugins[info.UUID]
+	s.plugins[info.UUID] = p
+	serverInfo := s.serverInfoLocked()
+	s.mu.Unlock()
+
+	if old != nil {
+		_ = s.send(old, &pb.PipeConnectionClosed{Timestamp: nowTimestamp()})
+		_ = old.conn.Close()
+		s.emitLifecycle(old.info, "replaced by a new connection", PluginLifecycleEventReplaced)
+	}
+
+	err := writeDelimitedMessage(conn, &pb.PipeRegisterPluginResp{
+		Success:       true,
+		Message:       "registered",
+		ClhInstanceId: serverInfo.InstanceID,
+		ServerInfo:    toPBServerInfo(serverInfo),
+		Timestamp:     nowTimestamp(),
+	})
+	if s.cfg.RegisterTimeout > 0 {
+		_ = conn.SetDeadline(time.Time{})
+	}
+	p.writeMu.Unlock()
+	if err != nil {
+		s.unregister(p, "register response failed", PluginLifecycleEventDisconnected)
+		return nil, err
+	}
+
+	s.emitLifecycle(info, "registered", PluginLifecycleEventConnected)
+	return p, nil
+}
+
+func (s *Server) unregister(p *serverPlugin, reason string, eventType PluginLifecycleEventType) {
+	s.mu.Lock()
+	current := s.plugins[p.info.UUID] == p
+	if current {
+		delete(s.plugins, p.info.UUID)
+	}
+	info := clonePluginInfo(p.info)
+	s.mu.Unlock()
+
+	if current {
+		s.emitLifecycle(info, reason, eventType)
+	}
+}
+
+func (s *Server) emitLifecycle(info PluginInfo, reason string, eventType PluginLifecycleEventType) {
+	ev := PluginLifecycleChanged{
+		PluginUUID:    info.UUID,
+		PluginName:    info.Name,
+		PluginVersion: info.Version,
+		Reason:        reason,
+		EventType:     eventType,
+		EventTime:     time.Now().UTC(),
+	}
+	if s.cfg.OnLifecycle != nil {
+		s.cfg.OnLifecycle(ev)
+	}
+	if !s.isClosed() {
+		_, _ = s.Broadcast(EnvelopeTopicEventPluginLifecycle, &ev)
+	}
+}
+
+func (s *Server) serveRequest(p *serverPlugin, env *pb.PipeEnvelope) {
+	defer s.wg.Done()
+	started := time.Now()
+
+	s.mu.Lock()
+	req := ServerRequest{
+		Plugin:     clonePluginInfo(p.info),
+		ID:         env.Id,
+		Kind:       EnvelopeKind(env.Kind),
+		Topic:      EnvelopeTopic(env.Topic),
+		Attributes: map[string]string{},
+		Timestamp:  fromTimestamp(env.Timestamp),
+	}
+	handler := s.handlers[req.Topic]
+	p.info.Telemetry.ControlRequestCount++
+	s.mu.Unlock()
+
+	for k, v := range env.Attributes {
+		req.Attributes[k] = v
+	}
+	if env.Subscription != nil {
+		sub := fromPBEventSubscription(env.Subscription)
+		req.Subscription = &sub
+	}
+	if env.Payload != nil {
+		req.Payload = decodeEnvelopePayload(env.Payload)
+	}
+
+	if s.cfg.OnRequest != nil {
+		s.cfg.OnRequest(req)
+	}
+	if handler == nil {
+		handler = s.builtinHandler(req.Topic)
+	}
+
+	var (
+		payload any
+		err     error
+	)
+	if handler == nil {
+		err = &RemoteError{
+			Topic:   req.Topic,
+			Code:    ServerCodeUnsupportedTopic,
+			Message: fmt.Sprintf("topic %d is not supported", req.Topic),
+		}
+	} else {
+		payload, err = handler(s.ctx, req)
+	}
+
+	out := &pb.PipeEnvelope{
+		Id:            s.nextID(),
+		CorrelationId: env.Id,
+		Kind:          pb.PipeEnvelopeKind(EnvelopeKindResponse),
+		Topic:         env.Topic,
+		Success:       true,
+		Message:       "ok",
+		Attributes:    map[string]string{},
+		Timestamp:     nowTimestamp(),
+	}
+	if err == nil {
+		var pbPayload proto.Message
+		if pbPayload, err = toPBPayload(payload); err == nil && pbPayload != nil {
+			out.Payload, err = anypb.New(pbPayload)
+		}
+	}
+	if err != nil {
+		out.Success = false
+		out.Payload = nil
+		out.ErrorCode = ServerCodeInternal
+		out.Message = err.Error()
+		var remoteErr *RemoteError
+		if errors.As(err, &remoteErr) {
+			out.ErrorCode = remoteErr.Code
+			out.Message = remoteErr.Message
+		}
+	}
+
+	s.mu.Lock()
+	if !out.Success {
+		p.info.Telemetry.ControlErrorCount++
+	}
+	p.info.Telemetry.LastRoundtripMs = uint32(time.Since(started).Milliseconds())
+	p.info.Telemetry.UpdatedAt = time.Now().UTC()
+	s.mu.Unlock()
+
+	_ = s.send(p, out)
+}
+
+func (s *Server) builtinHandler(topic EnvelopeTopic) ServerHandler {
+	switch topic {
+	case EnvelopeTopicQueryServerInfo:
+		return func(context.Context, ServerRequest) (any, error) {
+			return s.Info(), nil
+		}
+	case EnvelopeTopicQueryConnectedPlugins:
+		return func(context.Context, ServerRequest) (any, error) {
+			return PluginList{Plugins: s.Plugins()}, nil
+		}
+	case EnvelopeTopicQueryPluginTelemetry:
+		return s.handlePluginTelemetry
+	case EnvelopeTopicCommandSubscribeEvents:
+		return s.handleSubscribe
+	default:
+		return nil
+	}
+}
+
+func (s *Server) handlePluginTelemetry(_ context.Context, req ServerRequest) (any, error) {
+	uuid := req.Attributes["plugin_uuid"]
+	if uuid == "" {
+		uuid = req.Plugin.UUID
+	}
+	info, ok := s.Plugin(uuid)
+	if !ok {
+		return nil, &RemoteError{Topic: req.Topic, Code: ServerCodeNotFound, Message: "plugin not found"}
+	}
+	return info.Telemetry, nil
+}
+
+func (s *Server) handleSubscribe(_ context.Context, req ServerRequest) (any, error) {
+	var sub EventSubscription
+	if req.Subscription != nil {
+		sub = *req.Subscription
+	} else if payload, ok := req.Payload.(EventSubscription); ok {
+		sub = payload
+	}
+	sub.Topics = append([]EnvelopeTopic(nil), sub.Topics...)
+
+	s.mu.Lock()
+	if p, ok := s.plugins[req.Plugin.UUID]; ok {
+		p.info.EventSubscription = sub
+	}
+	s.mu.Unlock()
+	return sub, nil
+}
+
+func (s *Server) keepaliveLoop() {
+	defer s.wg.Done()
+	interval := s.cfg.KeepaliveTimeout / 4
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, p := range s.expiredPlugins() {
+				_ = s.send(p, &pb.PipeConnectionClosed{Timestamp: nowTimestamp()})
+				_ = p.conn.Close()
+			}
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Server) expiredPlugins() []*serverPlugin {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*serverPlugin
+	for _, p := range s.plugins {
+		if time.Since(p.info.LastHeartbeat) > s.cfg.KeepaliveTimeout {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func (s *Server) isTimedOut(p *serverPlugin) bool {
+	if s.cfg.KeepaliveTimeout <= 0 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Since(p.info.LastHeartbeat) > s.cfg.KeepaliveTimeout
+}
+
+func (s *Server) eventEnvelope(topic EnvelopeTopic, payload any) (*pb.PipeEnvelope, error) {
+	env := &pb.PipeEnvelope{
+		Id:         s.nextID(),
+		Kind:       pb.PipeEnvelopeKind(EnvelopeKindEvent),
+		Topic:      pb.PipeEnvelopeTopic(topic),
+		Success:    true,
+		Message:    "event",
+		Attributes: map[string]string{},
+		Timestamp:  nowTimestamp(),
+	}
+	msg, err := toPBPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	if msg != nil {
+		if env.Payload, err = anypb.New(msg); err != nil {
+			return nil, err
+		}
+	}
+	return env, nil
+}
+
+func (s *Server) send(p *serverPlugin, msg proto.Message) error {
+	packed, err := anypb.New(msg)
+	if err != nil {
+		return err
+	}
+
+	p.writeMu.Lock()
+	if s.cfg.WriteTimeout > 0 {
+		_ = p.conn.SetWriteDeadline(time.Now().Add(s.cfg.WriteTimeout))
+	}
+	err = writeDelimitedMessage(p.conn, packed)
+	p.writeMu.Unlock()
+
+	if err != nil {
+		// A failed or partial write leaves the stream unusable; the read loop unregisters.
+		_ = p.conn.Close()
+		return err
+	}
+	s.mu.Lock()
+	p.info.Telemetry.SentMessageCount++
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Server) subscribers(topic EnvelopeTopic) []*serverPlugin {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*serverPlugin
+	for _, p := range s.plugins {
+		if containsTopic(p.info.EventSubscription.Topics, topic) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func (s *Server) lookup(uuid string) (*serverPlugin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.plugins[uuid]
+	if !ok {
+		return nil, fmt.Errorf("plugin %s is not connected", uuid)
+	}
+	return p, nil
+}
+
+func (s *Server) serverInfoLocked() ServerInfo {
+	// Plugins read 0 as "no keepalive", so a sub-second timeout is rounded up to 1s.
+	keepalive := uint32((s.cfg.KeepaliveTimeout + time.Second - 1) / time.Second)
+	return ServerInfo{
+		InstanceID:           s.cfg.InstanceID,
+		Version:              s.cfg.Version,
+		KeepaliveTimeoutSec:  keepalive,
+		ConnectedPluginCount: uint32(len(s.plugins)),
+		UptimeSec:            uint64(time.Since(s.started).Seconds()),
+	}
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *Server) nextID() string {
+	return fmt.Sprintf("%s-%d-%d", s.cfg.InstanceID, time.Now().UnixNano(), s.seq.Add(1))
+}
+
+func clonePluginInfo(in PluginInfo) PluginInfo {
+	out := in
+	out.Metadata = make(map[string]string, len(in.Metadata))
+	for k, v := range in.Metadata {
+		out.Metadata[k] = v
+	}
+	out.EventSubscription.Topics = append([]EnvelopeTopic(nil), in.EventSubscription.Topics...)
+	return out
+}
@@ -0,0 +1,337 @@
+package clhplugin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	pb "github.com/SydneyOwl/clh-proto/gen/go/v20260312"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+type testServer struct {
+	*Server
+	listener *MemoryListener
+	events   chan PluginLifecycleChanged
+}
+
+func startServer(t *testing.T, opts ...ServerOption) *testServer {
+	t.Helper()
+	ts := &testServer{listener: NewMemoryListener(), events: make(chan PluginLifecycleChanged, 64)}
+	opts = append(opts, WithLifecycleHandler(func(ev PluginLifecycleChanged) { ts.events <- ev }))
+	server, err := NewServer(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.Server = server
+	go func() { _ = server.Serve(ts.listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+	return ts
+}
+
+// waitLifecycle reads lifecycle events until one for uuid has the given type.
+func (ts *testServer) waitLifecycle(t *testing.T, uuid string, typ PluginLifecycleEventType) PluginLifecycleChanged {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-ts.events:
+			if ev.PluginUUID == uuid && ev.EventType == typ {
+				return ev
+			}
+		case <-timeout:
+			t.Fatalf("no lifecycle event %d for %s", typ, uuid)
+		}
+	}
+}
+
+// rawPlugin is a plugin connection driven by hand, so tests control exactly what it
+// sends and whether it reads.
+type rawPlugin struct {
+	conn     net.Conn
+	received chan *anypb.Any
+	closed   chan struct{}
+}
+
+func dialRaw(t *testing.T, ts *testServer) net.Conn {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := ts.listener.Dial(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// registerRaw registers uuid for topics. Unless read is false it then drains the
+// connection in the background.
+func registerRaw(t *testing.T, ts *testServer, uuid string, read bool, topics ...EnvelopeTopic) *rawPlugin {
+	t.Helper()
+	conn := dialRaw(t, ts)
+	err := writeDelimitedMessage(conn, &pb.PipeRegisterPluginReq{
+		Uuid:              uuid,
+		Name:              "raw",
+		Version:           "1.0.0",
+		EventSubscription: toPBEventSubscription(&EventSubscription{Topics: topics}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := &pb.PipeRegisterPluginResp{}
+	if err := readDelimitedMessage(conn, resp); err != nil || !resp.Success {
+		t.Fatalf("register %s: %v, %v", uuid, resp, err)
+	}
+
+	p := &rawPlugin{conn: conn, received: make(chan *anypb.Any, 64), closed: make(chan struct{})}
+	if read {
+		go func() {
+			defer close(p.closed)
+			for {
+				msg := &anypb.Any{}
+				if err := readDelimitedMessage(conn, msg); err != nil {
+					return
+				}
+				p.received <- msg
+			}
+		}()
+	}
+	return p
+}
+
+func (p *rawPlugin) heartbeat() error {
+	msg, _ := anypb.New(&pb.PipeHeartbeat{Uuid: "raw", Timestamp: nowTimestamp()})
+	return writeDelimitedMessage(p.conn, msg)
+}
+
+// waitClosed waits for the server to close the connection and reports whether it said
+// PipeConnectionClosed first.
+func (p *rawPlugin) waitClosed(t *testing.T) bool {
+	t.Helper()
+	var notified bool
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-p.received:
+			if msg.MessageIs(&pb.PipeConnectionClosed{}) {
+				notified = true
+			}
+		case <-p.closed:
+			for len(p.received) > 0 {
+				if (<-p.received).MessageIs(&pb.PipeConnectionClosed{}) {
+					notified = true
+				}
+			}
+			return notified
+		case <-timeout:
+			t.Fatal("connection not closed")
+		}
+	}
+}
+
+func (p *rawPlugin) waitEvent(t *testing.T, topic EnvelopeTopic) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-p.received:
+			env := &pb.PipeEnvelope{}
+			if msg.UnmarshalTo(env) == nil && EnvelopeTopic(env.Topic) == topic {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no event on topic %d", topic)
+		}
+	}
+}
+
+func TestServerKeepaliveTimeout(t *testing.T) {
+	ts := startServer(t, WithKeepaliveTimeout(150*time.Millisecond))
+	silent := registerRaw(t, ts, "silent", true)
+	alive := registerRaw(t, ts, "alive", true)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if alive.heartbeat() != nil {
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	ev := ts.waitLifecycle(t, "silent", PluginLifecycleEventTimeout)
+	if ev.Reason != "keepalive timeout" {
+		t.Fatalf("reason = %q", ev.Reason)
+	}
+	if !silent.waitClosed(t) {
+		t.Error("silent plugin was not sent PipeConnectionClosed")
+	}
+	if _, ok := ts.Plugin("silent"); ok {
+		t.Error("silent plugin still listed")
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if _, ok := ts.Plugin("alive"); !ok {
+		t.Fatal("plugin sending heartbeats was dropped")
+	}
+}
+
+func TestServerKeepaliveSeconds(t *testing.T) {
+	tests := []struct {
+		timeout time.Duration
+		want    uint32
+	}{
+		{0, 0},
+		{time.Millisecond, 1},
+		{300 * time.Millisecond, 1},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+		{30 * time.Second, 30},
+	}
+	for _, tt := range tests {
+		server, err := NewServer(WithKeepaliveTimeout(tt.timeout))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := server.Info().KeepaliveTimeoutSec; got != tt.want {
+			t.Errorf("keepalive %v reported as %ds, want %ds", tt.timeout, got, tt.want)
+		}
+		_ = server.Close()
+	}
+}
+
+func TestServerReplace(t *testing.T) {
+	ts := startServer(t)
+	first := registerRaw(t, ts, "dup", true, EnvelopeTopicEventRigData)
+	ts.waitLifecycle(t, "dup", PluginLifecycleEventConnected)
+	second := registerRaw(t, ts, "dup", true, EnvelopeTopicEventRigData)
+
+	ts.waitLifecycle(t, "dup", PluginLifecycleEventReplaced)
+	if !first.waitClosed(t) {
+		t.Error("replaced connection was not sent PipeConnectionClosed")
+	}
+	if n := len(ts.Plugins()); n != 1 {
+		t.Fatalf("%d plugins, want 1", n)
+	}
+
+	n, err := ts.Broadcast(EnvelopeTopicEventRigData, RigData{Frequency: 7074000})
+	if err != nil || n != 1 {
+		t.Fatalf("Broadcast = %d, %v", n, err)
+	}
+	second.waitEvent(t, EnvelopeTopicEventRigData)
+
+	// The old connection closing does not unregister its replacement.
+	select {
+	case ev := <-ts.events:
+		if ev.EventType == PluginLifecycleEventDisconnected {
+			t.Fatalf("unexpected %+v", ev)
+		}
+	case <-time.After(50 * time.Millisecond):
+	}
+	if _, ok := ts.Plugin("dup"); !ok {
+		t.Fatal("replacement unregistered")
+	}
+}
+
+func TestServerBroadcastSkipsDeadPeers(t *testing.T) {
+	ts := startServer(t, WithWriteTimeout(50*time.Millisecond))
+	a := registerRaw(t, ts, "a", true, EnvelopeTopicEventRigData)
+	b := registerRaw(t, ts, "b", true, EnvelopeTopicEventRigData)
+	registerRaw(t, ts, "other", true, EnvelopeTopicEventServerStatus)
+	registerRaw(t, ts, "stalled", false, EnvelopeTopicEventRigData)
+
+	started := time.Now()
+	n, err := ts.Broadcast(EnvelopeTopicEventRigData, RigData{Frequency: 14074000})
+	if err != nil || n != 2 {
+		t.Fatalf("Broadcast = %d, %v; want 2", n, err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("Broadcast took %v behind a stalled peer", elapsed)
+	}
+	a.waitEvent(t, EnvelopeTopicEventRigData)
+	b.waitEvent(t, EnvelopeTopicEventRigData)
+
+	// The stalled peer is dropped after its write timed out.
+	ts.waitLifecycle(t, "stalled", PluginLifecycleEventDisconnected)
+	if n, _ := ts.Broadcast(EnvelopeTopicEventRigData, RigData{Frequency: 14074000}); n != 2 {
+		t.Fatalf("second Broadcast reached %d plugins, want 2", n)
+	}
+}
+
+func TestServerRegisterTimeout(t *testing.T) {
+	ts := startServer(t, WithRegisterTimeout(50*time.Millisecond))
+	conn := dialRaw(t, ts)
+
+	// Say nothing; the server gives up on the handshake and hangs up.
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var buf [1]byte
+	_, err := conn.Read(buf[:])
+	if err == nil || errors.Is(err, context.DeadlineExceeded) || isTimeout(err) {
+		t.Fatalf("read = %v, want the connection closed", err)
+	}
+	if n := len(ts.Plugins()); n != 0 {
+		t.Fatalf("%d plugins registered", n)
+	}
+}
+
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+func TestServerHandle(t *testing.T) {
+	ts := startServer(t, WithServerInstance("clh-test", "9.9"))
+	client, err := NewClient(PluginManifest{UUID: "h", Name: "h", Version: "1.0.0"}, WithDialer(ts.listener.Dial))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close(ctx)
+
+	info, err := client.QueryServerInfo(ctx)
+	if err != nil || info.InstanceID != "clh-test" || info.ConnectedPluginCount != 1 {
+		t.Fatalf("built-in server info = %+v, %v", info, err)
+	}
+
+	ts.Handle(EnvelopeTopicQueryServerInfo, func(context.Context, ServerRequest) (any, error) {
+		return ServerInfo{InstanceID: "custom"}, nil
+	})
+	if info, _ := client.QueryServerInfo(ctx); info.InstanceID != "custom" {
+		t.Fatalf("custom handler not used: %+v", info)
+	}
+	ts.Handle(EnvelopeTopicQueryServerInfo, nil)
+	if info, _ := client.QueryServerInfo(ctx); info.InstanceID != "clh-test" {
+		t.Fatalf("built-in handler not restored: %+v", info)
+	}
+
+	var remote *RemoteError
+	if _, err := client.QueryRigSnapshot(ctx); !errors.As(err, &remote) || remote.Code != ServerCodeUnsupportedTopic {
+		t.Fatalf("unhandled topic: err = %v", err)
+	}
+	ts.Handle(EnvelopeTopicQueryRigSnapshot, func(context.Context, ServerRequest) (any, error) {
+		return nil, errors.New("rig exploded")
+	})
+	if _, err := client.QueryRigSnapshot(ctx); !errors.As(err, &remote) || remote.Code != ServerCodeInternal {
+		t.Fatalf("failing handler: err = %v", err)
+	}
+
+	telemetry, err := client.QueryPluginTelemetry(ctx, "h")
+	if err != nil || telemetry.ReceivedMessageCount == 0 || telemetry.ControlErrorCount != 2 {
+		t.Fatalf("telemetry = %+v, %v", telemetry, err)
+	}
+}
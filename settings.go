@@ -0,0 +1,213 @@
+package clhplugin
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/SydneyOwl/clh-plugin-go-sdk/grid"
+)
+
+// SettingKey is a SettingsPatch key. Keys are "section.field" paths into the CLH
+// settings; udp.enable_udp_server is the form CLH documents, the others follow it.
+type SettingKey string
+
+const (
+	SettingInstanceName         SettingKey = "basic.instance_name"
+	SettingLanguage             SettingKey = "basic.language"
+	SettingMyMaidenheadGrid     SettingKey = "basic.my_maidenhead_grid"
+	SettingDisableAllCharts     SettingKey = "basic.disable_all_charts"
+	SettingEnablePlugin         SettingKey = "plugin.enable_plugin"
+	SettingAutoQSOUploadEnabled SettingKey = "cloudlog.auto_qso_upload_enabled"
+	SettingAutoRigUploadEnabled SettingKey = "cloudlog.auto_rig_upload_enabled"
+	SettingEnableUDPServer      SettingKey = "udp.enable_udp_server"
+)
+
+type SettingType string
+
+const (
+	SettingTypeString SettingType = "string"
+	SettingTypeBool   SettingType = "bool"
+	SettingTypeGrid   SettingType = "grid"
+	SettingTypeLocale SettingType = "locale"
+)
+
+// SettingField describes one key of the settings schema.
+type SettingField struct {
+	Key  SettingKey
+	Type SettingType
+	// Snapshot is the matching SettingsSnapshot field name.
+	Snapshot string
+}
+
+var settingsSchema = []SettingField{
+	{SettingInstanceName, SettingTypeString, "InstanceName"},
+	{SettingLanguage, SettingTypeLocale, "Language"},
+	{SettingMyMaidenheadGrid, SettingTypeGrid, "MyMaidenheadGrid"},
+	{SettingDisableAllCharts, SettingTypeBool, "DisableAllCharts"},
+	{SettingEnablePlugin, SettingTypeBool, "EnablePlugin"},
+	{SettingAutoQSOUploadEnabled, SettingTypeBool, "AutoQSOUploadEnabled"},
+	{SettingAutoRigUploadEnabled, SettingTypeBool, "AutoRigUploadEnabled"},
+	{SettingEnableUDPServer, SettingTypeBool, "EnableUDPServer"},
+}
+
+// SettingsSchema returns the typed settings keys in SettingsSnapshot field order.
+func SettingsSchema() []SettingField {
+	return append([]SettingField(nil), settingsSchema...)
+}
+
+func lookupSetting(key SettingKey) (SettingField, bool) {
+	for _, f := range settingsSchema {
+		if f.Key == key {
+			return f, true
+		}
+	}
+	return SettingField{}, false
+}
+
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(?:[-_][A-Za-z0-9]{2,8})*$`)
+
+// NormalizeSetting validates value for key and returns its canonical form: booleans as
+// "true"/"false", grids as "AB12cd". Keys outside the schema are passed through.
+func NormalizeSetting(key SettingKey, value string) (string, error) {
+	field, ok := lookupSetting(key)
+	if !ok {
+		return value, nil
+	}
+	switch field.Type {
+	case SettingTypeBool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return "", fmt.Errorf("setting %s: %q is not a boolean", key, value)
+		}
+		return strconv.FormatBool(b), nil
+	case SettingTypeGrid:
+		loc, err := grid.Normalize(value)
+		if err != nil {
+			return "", fmt.Errorf("setting %s: %q is not a Maidenhead locator", key, value)
+		}
+		return loc, nil
+	case SettingTypeLocale:
+		lang := strings.TrimSpace(value)
+		if !localePattern.MatchString(lang) {
+			return "", fmt.Errorf("setting %s: %q is not a language tag", key, value)
+		}
+		return lang, nil
+	default:
+		if strings.TrimSpace(value) == "" {
+			return "", fmt.Errorf("setting %s cannot be empty", key)
+		}
+		if strings.IndexFunc(value, unicode.IsControl) >= 0 {
+			return "", fmt.Errorf("setting %s: control characters are not allowed", key)
+		}
+		return value, nil
+	}
+}
+
+// SettingsBuilder builds a validated SettingsPatch. The first invalid value is kept and
+// returned by Build.
+//
+//	patch, err := sdk.NewSettingsBuilder().EnableUDPServer(true).MyMaidenheadGrid("PM01aa").Build()
+type SettingsBuilder struct {
+	values map[string]string
+	err    error
+}
+
+func NewSettingsBuilder() *SettingsBuilder {
+	return &SettingsBuilder{values: map[string]string{}}
+}
+
+// Set adds a key by name. Keys in the schema are validated; others are sent as is.
+func (b *SettingsBuilder) Set(key SettingKey, value string) *SettingsBuilder {
+	if b.err != nil {
+		return b
+	}
+	if key == "" {
+		b.err = errors.New("setting key cannot be empty")
+		return b
+	}
+	v, err := NormalizeSetting(key, value)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	b.values[string(key)] = v
+	return b
+}
+
+func (b *SettingsBuilder) setBool(key SettingKey, v bool) *SettingsBuilder {
+	return b.Set(key, strconv.FormatBool(v))
+}
+
+func (b *SettingsBuilder) InstanceName(name string) *SettingsBuilder {
+	return b.Set(SettingInstanceName, name)
+}
+
+func (b *SettingsBuilder) Language(lang string) *SettingsBuilder {
+	return b.Set(SettingLanguage, lang)
+}
+
+func (b *SettingsBuilder) MyMaidenheadGrid(locator string) *SettingsBuilder {
+	return b.Set(SettingMyMaidenheadGrid, locator)
+}
+
+func (b *SettingsBuilder) DisableAllCharts(v bool) *SettingsBuilder {
+	return b.setBool(SettingDisableAllCharts, v)
+}
+
+func (b *SettingsBuilder) EnablePlugin(v bool) *SettingsBuilder {
+	return b.setBool(SettingEnablePlugin, v)
+}
+
+func (b *SettingsBuilder) AutoQSOUpload(v bool) *SettingsBuilder {
+	return b.setBool(SettingAutoQSOUploadEnabled, v)
+}
+
+func (b *SettingsBuilder) AutoRigUpload(v bool) *SettingsBuilder {
+	return b.setBool(SettingAutoRigUploadEnabled, v)
+}
+
+func (b *SettingsBuilder) EnableUDPServer(v bool) *SettingsBuilder {
+	return b.setBool(SettingEnableUDPServer, v)
+}
+
+// Build returns the patch, or the first validation error.
+func (b *SettingsBuilder) Build() (SettingsPatch, error) {
+	if b.err != nil {
+		return SettingsPatch{}, b.err
+	}
+	values := make(map[string]string, len(b.values))
+	for k, v := range b.values {
+		values[k] = v
+	}
+	return SettingsPatch{Values: values}, nil
+}
+
+// DiffSettings returns the minimal patch that turns from into to. SampledAt is ignored;
+// the patch is empty when the settings are equal.
+func DiffSettings(from, to SettingsSnapshot) SettingsPatch {
+	values := map[string]string{}
+	setString := func(key SettingKey, a, b string) {
+		if a != b {
+			values[string(key)] = b
+		}
+	}
+	setBool := func(key SettingKey, a, b bool) {
+		if a != b {
+			values[string(key)] = strconv.FormatBool(b)
+		}
+	}
+
+	setString(SettingInstanceName, from.InstanceName, to.InstanceName)
+	setString(SettingLanguage, from.Language, to.Language)
+	setString(SettingMyMaidenheadGrid, from.MyMaidenheadGrid, to.MyMaidenheadGrid)
+	setBool(SettingDisableAllCharts, from.DisableAllCharts, to.DisableAllCharts)
+	setBool(SettingEnablePlugin, from.EnablePlugin, to.EnablePlugin)
+	setBool(SettingAutoQSOUploadEnabled, from.AutoQSOUploadEnabled, to.AutoQSOUploadEnabled)
+	setBool(SettingAutoRigUploadEnabled, from.AutoRigUploadEnabled, to.AutoRigUploadEnabled)
+	setBool(SettingEnableUDPServer, from.EnableUDPServer, to.EnableUDPServer)
+	return SettingsPatch{Values: values}
+}
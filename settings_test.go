@@ -0,0 +1,182 @@
+package clhplugin_test
+
+import (
+	"context"
+	"maps"
+	"reflect"
+	"testing"
+	"time"
+
+	clhplugin "github.com/SydneyOwl/clh-plugin-go-sdk"
+	"github.com/SydneyOwl/clh-plugin-go-sdk/clhtest"
+)
+
+func TestSettingsSchema(t *testing.T) {
+	schema := clhplugin.SettingsSchema()
+	snapshot := reflect.TypeOf(clhplugin.SettingsSnapshot{})
+	seen := map[string]bool{}
+	for _, field := range schema {
+		if _, ok := snapshot.FieldByName(field.Snapshot); !ok {
+			t.Errorf("%s maps to unknown snapshot field %s", field.Key, field.Snapshot)
+		}
+		seen[field.Snapshot] = true
+	}
+	// Every snapshot field but SampledAt has a key.
+	for i := range snapshot.NumField() {
+		if name := snapshot.Field(i).Name; name != "SampledAt" && !seen[name] {
+			t.Errorf("snapshot field %s has no setting key", name)
+		}
+	}
+
+	// The returned slice is a copy.
+	schema[0].Key = "changed"
+	if clhplugin.SettingsSchema()[0].Key == "changed" {
+		t.Fatal("SettingsSchema exposes the schema")
+	}
+}
+
+func TestNormalizeSetting(t *testing.T) {
+	tests := []struct {
+		key   clhplugin.SettingKey
+		value string
+		want  string
+		ok    bool
+	}{
+		{clhplugin.SettingEnableUDPServer, "true", "true", true},
+		{clhplugin.SettingEnableUDPServer, " 1 ", "true", true},
+		{clhplugin.SettingEnablePlugin, "F", "false", true},
+		{clhplugin.SettingAutoQSOUploadEnabled, "yes", "", false},
+		{clhplugin.SettingMyMaidenheadGrid, "pm01AA", "PM01aa", true},
+		{clhplugin.SettingMyMaidenheadGrid, " JO62 ", "JO62", true},
+		{clhplugin.SettingMyMaidenheadGrid, "ZZ99", "", false},
+		{clhplugin.SettingLanguage, "zh-CN", "zh-CN", true},
+		{clhplugin.SettingLanguage, "en_US", "en_US", true},
+		{clhplugin.SettingLanguage, "english!", "", false},
+		{clhplugin.SettingInstanceName, "shack", "shack", true},
+		{clhplugin.SettingInstanceName, "  ", "", false},
+		{clhplugin.SettingInstanceName, "a\nb", "", false},
+		{"general.unknown", "anything goes", "anything goes", true},
+	}
+	for _, tt := range tests {
+		got, err := clhplugin.NormalizeSetting(tt.key, tt.value)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("NormalizeSetting(%s, %q) = %q, %v", tt.key, tt.value, got, err)
+		}
+	}
+}
+
+func TestSettingsBuilder(t *testing.T) {
+	patch, err := clhplugin.NewSettingsBuilder().
+		InstanceName("shack").
+		Language("de").
+		MyMaidenheadGrid("jo62qm").
+		DisableAllCharts(true).
+		EnablePlugin(true).
+		AutoQSOUpload(false).
+		AutoRigUpload(true).
+		EnableUDPServer(true).
+		Set("general.unknown", "x").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		string(clhplugin.SettingInstanceName):         "shack",
+		string(clhplugin.SettingLanguage):             "de",
+		string(clhplugin.SettingMyMaidenheadGrid):     "JO62qm",
+		string(clhplugin.SettingDisableAllCharts):     "true",
+		string(clhplugin.SettingEnablePlugin):         "true",
+		string(clhplugin.SettingAutoQSOUploadEnabled): "false",
+		string(clhplugin.SettingAutoRigUploadEnabled): "true",
+		string(clhplugin.SettingEnableUDPServer):      "true",
+		"general.unknown":                             "x",
+	}
+	if !maps.Equal(patch.Values, want) {
+		t.Fatalf("patch = %v, want %v", patch.Values, want)
+	}
+
+	errTests := []struct {
+		name    string
+		builder *clhplugin.SettingsBuilder
+	}{
+		{"bad grid", clhplugin.NewSettingsBuilder().MyMaidenheadGrid("JO6")},
+		{"bad language", clhplugin.NewSettingsBuilder().Language("12")},
+		{"empty name", clhplugin.NewSettingsBuilder().InstanceName("")},
+		{"bad bool", clhplugin.NewSettingsBuilder().Set(clhplugin.SettingEnableUDPServer, "maybe")},
+		{"empty key", clhplugin.NewSettingsBuilder().Set("", "x")},
+		// The first error sticks, even when later values are valid.
+		{"sticky", clhplugin.NewSettingsBuilder().MyMaidenheadGrid("??").EnableUDPServer(false).InstanceName("ok")},
+	}
+	for _, tt := range errTests {
+		if patch, err := tt.builder.Build(); err == nil {
+			t.Errorf("%s: Build = %v, want an error", tt.name, patch.Values)
+		}
+	}
+}
+
+func TestDiffSettings(t *testing.T) {
+	from := clhplugin.SettingsSnapshot{
+		InstanceName:         "shack",
+		Language:             "en",
+		MyMaidenheadGrid:     "JO62",
+		EnablePlugin:         true,
+		AutoQSOUploadEnabled: true,
+		SampledAt:            time.Unix(1, 0),
+	}
+	to := from
+	to.SampledAt = time.Unix(2, 0)
+	if patch := clhplugin.DiffSettings(from, to); len(patch.Values) != 0 {
+		t.Fatalf("diff of equal settings = %v", patch.Values)
+	}
+
+	to.InstanceName = "portable"
+	to.Language = "de"
+	to.MyMaidenheadGrid = "JN58"
+	to.DisableAllCharts = true
+	to.EnablePlugin = false
+	to.AutoQSOUploadEnabled = false
+	to.AutoRigUploadEnabled = true
+	to.EnableUDPServer = true
+	want := map[string]string{
+		string(clhplugin.SettingInstanceName):         "portable",
+		string(clhplugin.SettingLanguage):             "de",
+		string(clhplugin.SettingMyMaidenheadGrid):     "JN58",
+		string(clhplugin.SettingDisableAllCharts):     "true",
+		string(clhplugin.SettingEnablePlugin):         "false",
+		string(clhplugin.SettingAutoQSOUploadEnabled): "false",
+		string(clhplugin.SettingAutoRigUploadEnabled): "true",
+		string(clhplugin.SettingEnableUDPServer):      "true",
+	}
+	if patch := clhplugin.DiffSettings(from, to); !maps.Equal(patch.Values, want) {
+		t.Fatalf("diff = %v, want %v", patch.Values, want)
+	}
+
+	// Only changed fields are included.
+	one := from
+	one.MyMaidenheadGrid = "JN58"
+	if patch := clhplugin.DiffSettings(from, one); len(patch.Values) != 1 || patch.Values[string(clhplugin.SettingMyMaidenheadGrid)] != "JN58" {
+		t.Fatalf("single-field diff = %v", patch.Values)
+	}
+}
+
+func TestUpdateSettings(t *testing.T) {
+	host := startHost(t)
+	host.Handle(clhplugin.EnvelopeTopicCommandUpdateSettings, func(context.Context, clhtest.Request) clhtest.Response {
+		return clhtest.Reply(clhplugin.SettingsSnapshot{EnableUDPServer: true})
+	})
+	client := connectClient(t, host)
+
+	patch, err := clhplugin.NewSettingsBuilder().EnableUDPServer(true).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, err := client.UpdateSettings(testContext(t), patch)
+	if err != nil || !settings.EnableUDPServer {
+		t.Fatalf("UpdateSettings = %+v, %v", settings, err)
+	}
+	reqs := host.Requests()
+	got, ok := reqs[len(reqs)-1].SettingsPatch()
+	if !ok || !maps.Equal(got.Values, patch.Values) {
+		t.Fatalf("host received %+v", reqs[len(reqs)-1].Payload)
+	}
+}
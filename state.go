@@ -0,0 +1,125 @@
+package clhplugin
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+const defaultStateBuffer = 32
+
+type ConnectionState string
+
+const (
+	ConnectionStateIdle            ConnectionState = "idle"
+	ConnectionStateDialing         ConnectionState = "dialing"
+	ConnectionStateRegistering     ConnectionState = "registering"
+	ConnectionStateConnected       ConnectionState = "connected"
+	ConnectionStateReconnecting    ConnectionState = "reconnecting"
+	ConnectionStateRemoteClosed    ConnectionState = "remote_closed"
+	ConnectionStateHeartbeatFailed ConnectionState = "heartbeat_failed"
+	ConnectionStateDisconnected    ConnectionState = "disconnected"
+	ConnectionStateClosed          ConnectionState = "closed"
+)
+
+// StateChange describes one connection state transition. Err carries the cause for
+// failure states and is nil otherwise.
+type StateChange struct {
+	From ConnectionState
+	To   ConnectionState
+	Err  error
+	Time time.Time
+}
+
+type StateHandler func(StateChange)
+
+// session is one registered connection to CLH; it ends when its readLoop returns.
+type session struct {
+	conn net.Conn
+	done chan struct{}
+
+	causeMu    sync.Mutex
+	causeState ConnectionState
+	causeErr   error
+}
+
+func newSession(conn net.Conn) *session {
+	return &session{conn: conn, done: make(chan struct{})}
+}
+
+// fail records why the session ended; the first recorded cause wins.
+func (s *session) fail(state ConnectionState, err error) {
+	s.causeMu.Lock()
+	defer s.causeMu.Unlock()
+	if s.causeState != "" {
+		return
+	}
+	s.causeState = state
+	s.causeErr = err
+}
+
+func (s *session) cause() (ConnectionState, error) {
+	s.causeMu.Lock()
+	defer s.causeMu.Unlock()
+	if s.causeState == "" {
+		return ConnectionStateDisconnected, nil
+	}
+	return s.causeState, s.causeErr
+}
+
+func (c *Client) State() ConnectionState {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	return c.state
+}
+
+// StateChanges returns a channel of state transitions. When the buffer is full the oldest
+// transition is dropped. The channel is closed once the client has finished.
+func (c *Client) StateChanges() <-chan StateChange {
+	return c.stateCh
+}
+
+func (c *Client) setState(to ConnectionState, err error) {
+	c.stateMu.Lock()
+	if c.stateDone || c.state == ConnectionStateClosed || c.state == to && err == nil {
+		c.stateMu.Unlock()
+		return
+	}
+	change := StateChange{
+		From: c.state,
+		To:   to,
+		Err:  err,
+		Time: time.Now().UTC(),
+	}
+	c.state = to
+
+	select {
+	case c.stateCh <- change:
+	default:
+		select {
+		case <-c.stateCh:
+		default:
+		}
+		select {
+		case c.stateCh <- change:
+		default:
+		}
+	}
+	c.stateMu.Unlock()
+
+	c.logStateChange(change)
+	if c.cfg.OnStateChange != nil {
+		c.cfg.OnStateChange(change)
+	}
+	c.stateSubs.emit(change)
+}
+
+func (c *Client) closeStateChanges() {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	if c.stateDone {
+		return
+	}
+	c.stateDone = true
+	close(c.stateCh)
+}
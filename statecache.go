@@ -0,0 +1,364 @@
+package clhplugin
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type CachePart string
+
+const (
+	CachePartRig      CachePart = "rig"
+	CachePartServer   CachePart = "server"
+	CachePartSettings CachePart = "settings"
+	CachePartQueue    CachePart = "queue"
+	CachePartResync   CachePart = "resync"
+)
+
+var stateCacheTopics = []EnvelopeTopic{
+	EnvelopeTopicEventRigData,
+	EnvelopeTopicEventServerStatus,
+	EnvelopeTopicEventSettingsChanged,
+	EnvelopeTopicEventQSOQueueStatus,
+}
+
+// QueueCounters mirrors the latest QSOQueueStatusChanged event. It stays zero until the
+// first event arrives, since the runtime snapshot carries no queue totals.
+type QueueCounters struct {
+	PendingCount  uint32
+	UploadedTotal uint64
+	FailedTotal   uint64
+	UpdatedAt     time.Time
+}
+
+// CacheUpdate is passed to StateCache.OnChange handlers after the cache changed.
+type CacheUpdate struct {
+	Part     CachePart
+	Snapshot RuntimeSnapshot
+	Queue    QueueCounters
+}
+
+// StateCache keeps a RuntimeSnapshot current from events. It is seeded with
+// QueryRuntimeSnapshot and re-queried after every reconnect or when callback dispatch
+// dropped messages; settings are re-queried, one query at a time, after SettingsChanged
+// events. Events older than what the cache holds are ignored, since the default dispatch
+// may deliver them out of order.
+type StateCache struct {
+	client *Client
+
+	mu       sync.RWMutex
+	snapshot RuntimeSnapshot
+	queue    QueueCounters
+	serverAt time.Time // EventTime of the last applied ServerStatusChanged
+	dropped  uint64
+
+	handlers      handlerSet[CacheUpdate]
+	unsubs        []func()
+	resyncing     atomic.Bool
+	resyncPending atomic.Bool
+	settingsCh    chan struct{}
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+// NewStateCache subscribes to the rig, server status, settings and QSO queue topics (in
+// addition to whatever SubscribeEvents requested) and seeds the cache. The client must be
+// connected.
+func (c *Client) NewStateCache(ctx context.Context) (*StateCache, error) {
+	sc := &StateCache{client: c, settingsCh: make(chan struct{}, 1), done: make(chan struct{})}
+	sc.unsubs = append(sc.unsubs,
+		c.OnRigData(sc.applyRigData),
+		c.OnServerStatus(sc.applyServerStatus),
+		c.OnSettingsChanged(sc.applySettingsChanged),
+		c.OnQSOQueueStatus(sc.applyQueueStatus),
+		c.stateSubs.add(nil, sc.onStateChange),
+		c.requireTopics(stateCacheTopics),
+	)
+
+	c.subMu.Lock()
+	sub := EventSubscription{}
+	if c.subscription != nil {
+		sub = *c.subscription
+	}
+	c.subMu.Unlock()
+
+	if _, err := c.SubscribeEvents(ctx, sub); err != nil {
+		sc.Close()
+		return nil, err
+	}
+	if err := sc.Resync(ctx); err != nil {
+		sc.Close()
+		return nil, err
+	}
+	go sc.settingsLoop()
+	return sc, nil
+}
+
+// Resync replaces the cached snapshot with a fresh QueryRuntimeSnapshot.
+func (sc *StateCache) Resync(ctx context.Context) error {
+	dropped := sc.client.dispatcher.counters.dropped.Load()
+	snapshot, err := sc.client.QueryRuntimeSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+
+	sc.mu.Lock()
+	sc.snapshot = snapshot
+	sc.serverAt = snapshot.SampledAt
+	sc.dropped = dropped
+	sc.mu.Unlock()
+	sc.notify(CachePartResync)
+	return nil
+}
+
+// Close stops tracking events and releases the cache's topics. The current subscription
+// keeps them until the next SubscribeEvents call.
+func (sc *StateCache) Close() {
+	sc.closeOnce.Do(func() {
+		close(sc.done)
+		for _, unsub := range sc.unsubs {
+			unsub()
+		}
+	})
+}
+
+// OnChange registers fn to run after every cache update and returns a func that removes it.
+func (sc *StateCache) OnChange(fn func(CacheUpdate)) func() {
+	return sc.handlers.add(nil, fn)
+}
+
+func (sc *StateCache) Snapshot() RuntimeSnapshot {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return cloneRuntimeSnapshot(sc.snapshot)
+}
+
+func (sc *StateCache) Rig() RigSnapshot {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.snapshot.RigSnapshot
+}
+
+func (sc *StateCache) ServerInfo() ServerInfo {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.snapshot.ServerInfo
+}
+
+func (sc *StateCache) Settings() SettingsSnapshot {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.snapshot.SettingsSnapshot
+}
+
+func (sc *StateCache) Queue() QueueCounters {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.queue
+}
+
+func (sc *StateCache) applyRigData(data RigData) {
+	if sc.checkGap() {
+		return
+	}
+	sc.mu.Lock()
+	rig := &sc.snapshot.RigSnapshot
+	if isStale(data.Timestamp, rig.SampledAt) {
+		sc.mu.Unlock()
+		return
+	}
+	if data.Provider != "" {
+		rig.Provider = data.Provider
+	}
+	if data.RigName != "" {
+		rig.RigModel = data.RigName
+	}
+	rig.TXFrequencyHz = data.Frequency
+	rig.TXMode = data.Mode
+	rig.RXFrequencyHz = data.FrequencyRX
+	rig.RXMode = data.ModeRX
+	rig.Split = data.Split
+	rig.Power = data.Power
+	rig.SampledAt = data.Timestamp
+	sc.mu.Unlock()
+	sc.notify(CachePartRig)
+}
+
+func (sc *StateCache) applyServerStatus(ev ServerStatusChanged) {
+	if sc.checkGap() {
+		return
+	}
+	sc.mu.Lock()
+	if isStale(ev.EventTime, sc.serverAt) {
+		sc.mu.Unlock()
+		return
+	}
+	if !ev.EventTime.IsZero() {
+		sc.serverAt = ev.EventTime
+	}
+	info := &sc.snapshot.ServerInfo
+	info.InstanceID = ev.InstanceID
+	info.Version = ev.Version
+	info.ConnectedPluginCount = ev.ConnectedPluginCount
+	sc.mu.Unlock()
+	sc.notify(CachePartServer)
+}
+
+// applySettingsChanged asks settingsLoop for a re-query, since the event only carries a
+// summary. Events arriving while a query is in flight are coalesced into one more query.
+func (sc *StateCache) applySettingsChanged(SettingsChanged) {
+	if sc.checkGap() {
+		return
+	}
+	select {
+	case sc.settingsCh <- struct{}{}:
+	default:
+	}
+}
+
+func (sc *StateCache) settingsLoop() {
+	for {
+		select {
+		case <-sc.settingsCh:
+		case <-sc.done:
+			return
+		case <-sc.client.doneCh:
+			return
+		}
+		settings, err := sc.client.QuerySettingsSnapshot(context.Background())
+		if err != nil {
+			continue
+		}
+		sc.mu.Lock()
+		if isStale(settings.SampledAt, sc.snapshot.SettingsSnapshot.SampledAt) {
+			sc.mu.Unlock()
+			continue
+		}
+		sc.snapshot.SettingsSnapshot = settings
+		sc.mu.Unlock()
+		sc.notify(CachePartSettings)
+	}
+}
+
+func (sc *StateCache) applyQueueStatus(ev QSOQueueStatusChanged) {
+	if sc.checkGap() {
+		return
+	}
+	sc.mu.Lock()
+	if isStale(ev.EventTime, sc.queue.UpdatedAt) {
+		sc.mu.Unlock()
+		return
+	}
+	sc.queue = QueueCounters{
+		PendingCount:  ev.PendingCount,
+		UploadedTotal: ev.UploadedTotal,
+		FailedTotal:   ev.FailedTotal,
+		UpdatedAt:     ev.EventTime,
+	}
+	sc.mu.Unlock()
+	sc.notify(CachePartQueue)
+}
+
+// onStateChange resyncs after every new session. The cache is seeded while connected, so
+// any later transition to Connected follows a reconnect, during which events were missed.
+func (sc *StateCache) onStateChange(change StateChange) {
+	if change.To == ConnectionStateConnected {
+		sc.resyncAsync()
+	}
+}
+
+// checkGap starts a resync when callback dispatch has dropped messages since the last
+// seed; only bounded DispatchConfig queues drop. The event that revealed the gap is
+// skipped; the resync supersedes it.
+func (sc *StateCache) checkGap() bool {
+	dropped := sc.client.dispatcher.counters.dropped.Load()
+	sc.mu.RLock()
+	gap := dropped != sc.dropped
+	sc.mu.RUnlock()
+	if gap {
+		sc.resyncAsync()
+	}
+	return gap
+}
+
+// resyncAsync runs one resync at a time; a request made while one is running triggers
+// another when it finishes, so a reconnect is never folded into an older resync.
+func (sc *StateCache) resyncAsync() {
+	sc.resyncPending.Store(true)
+	if !sc.resyncing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		for {
+			sc.resyncPending.Store(false)
+			_ = sc.Resync(context.Background())
+			sc.resyncing.Store(false)
+			if !sc.resyncPending.Load() || !sc.resyncing.CompareAndSwap(false, true) {
+				return
+			}
+		}
+	}()
+}
+
+// isStale reports whether an update stamped at is older than the cached value stamped
+// last. Unstamped values are never stale.
+func isStale(at, last time.Time) bool {
+	return !at.IsZero() && !last.IsZero() && at.Before(last)
+}
+
+func (sc *StateCache) notify(part CachePart) {
+	sc.mu.RLock()
+	update := CacheUpdate{
+		Part:     part,
+		Snapshot: cloneRuntimeSnapshot(sc.snapshot),
+		Queue:    sc.queue,
+	}
+	sc.mu.RUnlock()
+	sc.handlers.emit(update)
+}
+
+func cloneRuntimeSnapshot(in RuntimeSnapshot) RuntimeSnapshot {
+	out := in
+	out.PluginTelemetry = append([]PluginTelemetry(nil), in.PluginTelemetry...)
+	return out
+}
+
+// requireTopics keeps topics in every subscription sent by SubscribeEvents until the
+// returned func is called.
+func (c *Client) requireTopics(topics []EnvelopeTopic) func() {
+	c.subMu.Lock()
+	if c.requiredTopics == nil {
+		c.requiredTopics = map[EnvelopeTopic]int{}
+	}
+	for _, topic := range topics {
+		c.requiredTopics[topic]++
+	}
+	c.subMu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.subMu.Lock()
+			defer c.subMu.Unlock()
+			for _, topic := range topics {
+				if c.requiredTopics[topic]--; c.requiredTopics[topic] <= 0 {
+					delete(c.requiredTopics, topic)
+				}
+			}
+		})
+	}
+}
+
+func (c *Client) withRequiredTopics(topics []EnvelopeTopic) []EnvelopeTopic {
+	out := append([]EnvelopeTopic(nil), topics...)
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	for topic := range c.requiredTopics {
+		if !containsTopic(out, topic) {
+			out = append(out, topic)
+		}
+	}
+	return out
+}
@@ -0,0 +1,241 @@
+package clhplugin_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	clhplugin "github.com/SydneyOwl/clh-plugin-go-sdk"
+	"github.com/SydneyOwl/clh-plugin-go-sdk/clhtest"
+)
+
+// snapshotHost serves QueryRuntimeSnapshot and QuerySettingsSnapshot from values the test
+// can change between queries.
+type snapshotHost struct {
+	*clhtest.Host
+
+	mu       sync.Mutex
+	snapshot clhplugin.RuntimeSnapshot
+	settings clhplugin.SettingsSnapshot
+}
+
+func startSnapshotHost(t *testing.T, snapshot clhplugin.RuntimeSnapshot) *snapshotHost {
+	t.Helper()
+	h := &snapshotHost{Host: startHost(t), snapshot: snapshot, settings: snapshot.SettingsSnapshot}
+	h.Handle(clhplugin.EnvelopeTopicQueryRuntimeSnapshot, func(context.Context, clhtest.Request) clhtest.Response {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return clhtest.Reply(h.snapshot)
+	})
+	h.Handle(clhplugin.EnvelopeTopicQuerySettingsSnapshot, func(context.Context, clhtest.Request) clhtest.Response {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return clhtest.Reply(h.settings)
+	})
+	return h
+}
+
+func (h *snapshotHost) set(fn func(snapshot *clhplugin.RuntimeSnapshot, settings *clhplugin.SettingsSnapshot)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fn(&h.snapshot, &h.settings)
+}
+
+// waitUpdate reads cache updates until one for part satisfies cond.
+func waitUpdate(t *testing.T, updates <-chan clhplugin.CacheUpdate, part clhplugin.CachePart, cond func(clhplugin.CacheUpdate) bool) clhplugin.CacheUpdate {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case update := <-updates:
+			if update.Part == part && cond(update) {
+				return update
+			}
+		case <-timeout:
+			t.Fatalf("no %s update", part)
+		}
+	}
+}
+
+func TestStateCache(t *testing.T) {
+	t0 := time.Date(2026, 3, 12, 10, 0, 0, 0, time.UTC)
+	host := startSnapshotHost(t, clhplugin.RuntimeSnapshot{
+		ServerInfo:       clhplugin.ServerInfo{InstanceID: "clh-1", Version: "1.0"},
+		RigSnapshot:      clhplugin.RigSnapshot{RigModel: "IC-7300", TXFrequencyHz: 7074000, TXMode: "FT8", SampledAt: t0},
+		SettingsSnapshot: clhplugin.SettingsSnapshot{InstanceName: "shack", SampledAt: t0},
+		SampledAt:        t0,
+	})
+	client := connectClient(t, host.Host)
+	cache, err := client.NewStateCache(testContext(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+
+	if rig := cache.Rig(); rig.TXFrequencyHz != 7074000 || rig.RigModel != "IC-7300" {
+		t.Fatalf("seeded rig = %+v", rig)
+	}
+	if info := cache.ServerInfo(); info.InstanceID != "clh-1" {
+		t.Fatalf("seeded server info = %+v", info)
+	}
+	if settings := cache.Settings(); settings.InstanceName != "shack" {
+		t.Fatalf("seeded settings = %+v", settings)
+	}
+	if plugin, _ := host.Plugin(testManifest.UUID); len(plugin.EventSubscription.Topics) != 4 {
+		t.Fatalf("subscription = %+v", plugin.EventSubscription)
+	}
+
+	updates := make(chan clhplugin.CacheUpdate, 16)
+	cache.OnChange(func(update clhplugin.CacheUpdate) { updates <- update })
+
+	t.Run("rig", func(t *testing.T) {
+		if _, err := host.PushRigData(clhplugin.RigData{Frequency: 14074000, Mode: "FT8", Timestamp: t0.Add(time.Second)}); err != nil {
+			t.Fatal(err)
+		}
+		update := waitUpdate(t, updates, clhplugin.CachePartRig, func(clhplugin.CacheUpdate) bool { return true })
+		if rig := update.Snapshot.RigSnapshot; rig.TXFrequencyHz != 14074000 || rig.RigModel != "IC-7300" {
+			t.Fatalf("rig = %+v", rig)
+		}
+	})
+
+	t.Run("stale rig ignored", func(t *testing.T) {
+		if _, err := host.PushRigData(clhplugin.RigData{Frequency: 3573000, Timestamp: t0.Add(-time.Second)}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := host.PushRigData(clhplugin.RigData{Frequency: 21074000, Timestamp: t0.Add(2 * time.Second)}); err != nil {
+			t.Fatal(err)
+		}
+		waitUpdate(t, updates, clhplugin.CachePartRig, func(u clhplugin.CacheUpdate) bool {
+			if u.Snapshot.RigSnapshot.TXFrequencyHz == 3573000 {
+				t.Fatal("stale rig data applied")
+			}
+			return u.Snapshot.RigSnapshot.TXFrequencyHz == 21074000
+		})
+		if rig := cache.Rig(); rig.TXFrequencyHz != 21074000 {
+			t.Fatalf("rig = %+v", rig)
+		}
+	})
+
+	t.Run("server status", func(t *testing.T) {
+		ev := &clhplugin.ServerStatusChanged{InstanceID: "clh-1", Version: "1.1", ConnectedPluginCount: 3, EventTime: t0.Add(time.Second)}
+		if _, err := host.PushEvent(clhplugin.EnvelopeTopicEventServerStatus, ev); err != nil {
+			t.Fatal(err)
+		}
+		waitUpdate(t, updates, clhplugin.CachePartServer, func(clhplugin.CacheUpdate) bool { return true })
+		if info := cache.ServerInfo(); info.Version != "1.1" || info.ConnectedPluginCount != 3 {
+			t.Fatalf("server info = %+v", info)
+		}
+	})
+
+	t.Run("queue", func(t *testing.T) {
+		ev := &clhplugin.QSOQueueStatusChanged{PendingCount: 2, UploadedTotal: 40, FailedTotal: 1, EventTime: t0}
+		if _, err := host.PushEvent(clhplugin.EnvelopeTopicEventQSOQueueStatus, ev); err != nil {
+			t.Fatal(err)
+		}
+		update := waitUpdate(t, updates, clhplugin.CachePartQueue, func(clhplugin.CacheUpdate) bool { return true })
+		if update.Queue.PendingCount != 2 || update.Queue.UploadedTotal != 40 || cache.Queue() != update.Queue {
+			t.Fatalf("queue = %+v", update.Queue)
+		}
+	})
+
+	t.Run("settings requeried", func(t *testing.T) {
+		host.set(func(_ *clhplugin.RuntimeSnapshot, settings *clhplugin.SettingsSnapshot) {
+			settings.InstanceName = "portable"
+			settings.SampledAt = t0.Add(time.Minute)
+		})
+		ev := &clhplugin.SettingsChanged{ChangedPart: "general", EventTime: t0.Add(time.Minute)}
+		if _, err := host.PushEvent(clhplugin.EnvelopeTopicEventSettingsChanged, ev); err != nil {
+			t.Fatal(err)
+		}
+		waitUpdate(t, updates, clhplugin.CachePartSettings, func(clhplugin.CacheUpdate) bool { return true })
+		if settings := cache.Settings(); settings.InstanceName != "portable" {
+			t.Fatalf("settings = %+v", settings)
+		}
+	})
+}
+
+func TestStateCacheResyncsAfterReconnect(t *testing.T) {
+	t0 := time.Date(2026, 3, 12, 10, 0, 0, 0, time.UTC)
+	host := startSnapshotHost(t, clhplugin.RuntimeSnapshot{
+		RigSnapshot: clhplugin.RigSnapshot{TXFrequencyHz: 7074000, SampledAt: t0},
+		SampledAt:   t0,
+	})
+	client := connectClient(t, host.Host, clhplugin.WithAutoReconnect(fastReconnect()))
+	cache, err := client.NewStateCache(testContext(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+
+	updates := make(chan clhplugin.CacheUpdate, 16)
+	cache.OnChange(func(update clhplugin.CacheUpdate) { updates <- update })
+
+	// The rig moved while the plugin was away; no event reports it.
+	host.set(func(snapshot *clhplugin.RuntimeSnapshot, _ *clhplugin.SettingsSnapshot) {
+		snapshot.RigSnapshot = clhplugin.RigSnapshot{TXFrequencyHz: 10136000, SampledAt: t0.Add(time.Minute)}
+		snapshot.SampledAt = t0.Add(time.Minute)
+	})
+	if err := host.Drop(testManifest.UUID); err != nil {
+		t.Fatal(err)
+	}
+	waitUpdate(t, updates, clhplugin.CachePartResync, func(u clhplugin.CacheUpdate) bool {
+		return u.Snapshot.RigSnapshot.TXFrequencyHz == 10136000
+	})
+
+	// The cache's topics are part of the restored subscription.
+	if err := host.WaitFor(testContext(t), func(h *clhtest.Host) bool {
+		plugin, ok := h.Plugin(testManifest.UUID)
+		return ok && len(plugin.EventSubscription.Topics) == 4
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := host.PushRigData(clhplugin.RigData{Frequency: 10138000, Timestamp: t0.Add(2 * time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	waitUpdate(t, updates, clhplugin.CachePartRig, func(u clhplugin.CacheUpdate) bool {
+		return u.Snapshot.RigSnapshot.TXFrequencyHz == 10138000
+	})
+}
+
+func TestStateCacheResyncsAfterDroppedEvents(t *testing.T) {
+	t0 := time.Date(2026, 3, 12, 10, 0, 0, 0, time.UTC)
+	host := startSnapshotHost(t, clhplugin.RuntimeSnapshot{
+		RigSnapshot: clhplugin.RigSnapshot{TXFrequencyHz: 7074000, SampledAt: t0},
+		SampledAt:   t0,
+	})
+	client := connectClient(t, host.Host, clhplugin.WithOrderedDispatch(clhplugin.DispatchConfig{
+		QueueSize: 1, Overflow: clhplugin.OverflowDropNewest,
+	}))
+	cache, err := client.NewStateCache(testContext(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+
+	// A slow handler lets the single-slot queue overflow.
+	gate := make(chan struct{})
+	var once sync.Once
+	release := func() { once.Do(func() { close(gate) }) }
+	defer release()
+	unsub := client.OnRigData(func(clhplugin.RigData) { <-gate })
+	defer unsub()
+
+	updates := make(chan clhplugin.CacheUpdate, 16)
+	cache.OnChange(func(update clhplugin.CacheUpdate) { updates <- update })
+
+	host.set(func(snapshot *clhplugin.RuntimeSnapshot, _ *clhplugin.SettingsSnapshot) {
+		snapshot.RigSnapshot = clhplugin.RigSnapshot{TXFrequencyHz: 18100000, SampledAt: t0.Add(time.Hour)}
+	})
+	for i := 1; i <= 4; i++ {
+		if _, err := host.PushRigData(clhplugin.RigData{Frequency: uint64(i), Timestamp: t0.Add(time.Duration(i) * time.Second)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitUntil(t, func() bool { return client.Stats().Callback.Dropped > 0 })
+	release()
+
+	waitUpdate(t, updates, clhplugin.CachePartResync, func(u clhplugin.CacheUpdate) bool {
+		return u.Snapshot.RigSnapshot.TXFrequencyHz == 18100000
+	})
+}
@@ -0,0 +1,117 @@
+package clhplugin
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type QueueStats struct {
+	Enqueued  uint64
+	Delivered uint64
+	Dropped   uint64
+}
+
+// Stats is a point-in-time view of the client's inbound queues. Wait covers the
+// WaitMessage buffer; WaitByTopic only counts envelope messages. Subscriptions sums
+// all Subscribe channels and has no Delivered count.
+type Stats struct {
+	Callback      QueueStats
+	Subscriptions QueueStats
+	Wait          QueueStats
+	WaitByKind    map[InboundKind]QueueStats
+	WaitByTopic   map[EnvelopeTopic]QueueStats
+}
+
+type queueCounters struct {
+	enqueued  atomic.Uint64
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+func (q *queueCounters) snapshot() QueueStats {
+	return QueueStats{
+		Enqueued:  q.enqueued.Load(),
+		Delivered: q.delivered.Load(),
+		Dropped:   q.dropped.Load(),
+	}
+}
+
+type queueEvent int
+
+const (
+	queueEnqueued queueEvent = iota
+	queueDelivered
+	queueDropped
+)
+
+func (q *queueCounters) record(ev queueEvent) {
+	switch ev {
+	case queueEnqueued:
+		q.enqueued.Add(1)
+	case queueDelivered:
+		q.delivered.Add(1)
+	case queueDropped:
+		q.dropped.Add(1)
+	}
+}
+
+// keyedCounters tracks a queue in total and broken down by inbound kind and topic.
+type keyedCounters struct {
+	total queueCounters
+
+	mu      sync.Mutex
+	byKind  map[InboundKind]*queueCounters
+	byTopic map[EnvelopeTopic]*queueCounters
+}
+
+func (k *keyedCounters) record(msg Message, ev queueEvent) {
+	k.total.record(ev)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.byKind == nil {
+		k.byKind = map[InboundKind]*queueCounters{}
+		k.byTopic = map[EnvelopeTopic]*queueCounters{}
+	}
+	kc := k.byKind[msg.Kind]
+	if kc == nil {
+		kc = &queueCounters{}
+		k.byKind[msg.Kind] = kc
+	}
+	kc.record(ev)
+
+	if msg.Envelope == nil {
+		return
+	}
+	tc := k.byTopic[msg.Envelope.Topic]
+	if tc == nil {
+		tc = &queueCounters{}
+		k.byTopic[msg.Envelope.Topic] = tc
+	}
+	tc.record(ev)
+}
+
+func (k *keyedCounters) snapshot() (QueueStats, map[InboundKind]QueueStats, map[EnvelopeTopic]QueueStats) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	byKind := make(map[InboundKind]QueueStats, len(k.byKind))
+	for kind, q := range k.byKind {
+		byKind[kind] = q.snapshot()
+	}
+	byTopic := make(map[EnvelopeTopic]QueueStats, len(k.byTopic))
+	for topic, q := range k.byTopic {
+		byTopic[topic] = q.snapshot()
+	}
+	return k.total.snapshot(), byKind, byTopic
+}
+
+func (c *Client) Stats() Stats {
+	wait, byKind, byTopic := c.waitStats.snapshot()
+	return Stats{
+		Callback:      c.dispatcher.counters.snapshot(),
+		Subscriptions: c.subscribers.counters.snapshot(),
+		Wait:          wait,
+		WaitByKind:    byKind,
+		WaitByTopic:   byTopic,
+	}
+}
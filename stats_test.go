@@ -0,0 +1,154 @@
+package clhplugin_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	clhplugin "github.com/SydneyOwl/clh-plugin-go-sdk"
+	"github.com/SydneyOwl/clh-plugin-go-sdk/clhtest"
+)
+
+func waitClient(t *testing.T, host *clhtest.Host, size int, policy clhplugin.OverflowPolicy, blockTimeout time.Duration) *clhplugin.Client {
+	t.Helper()
+	client := connectClient(t, host,
+		clhplugin.WithWaitBufferSize(size),
+		clhplugin.WithWaitOverflow(policy, blockTimeout),
+	)
+	sub := clhplugin.EventSubscription{Topics: []clhplugin.EnvelopeTopic{
+		clhplugin.EnvelopeTopicEventRigData,
+		clhplugin.EnvelopeTopicEventWsjtxMessage,
+	}}
+	if _, err := client.SubscribeEvents(testContext(t), sub); err != nil {
+		t.Fatal(err)
+	}
+	// The reply is routed to WaitMessage as well; take it out so the buffer starts empty.
+	if msg, err := client.WaitMessage(testContext(t)); err != nil || msg.Envelope == nil || msg.Envelope.Topic != clhplugin.EnvelopeTopicCommandSubscribeEvents {
+		t.Fatalf("WaitMessage = %+v, %v; want the subscribe reply", msg, err)
+	}
+	return client
+}
+
+// waitFrequencies reads n messages from WaitMessage and returns their rig frequencies.
+func waitFrequencies(t *testing.T, client *clhplugin.Client, n int) []uint64 {
+	t.Helper()
+	var freqs []uint64
+	for range n {
+		msg, err := client.WaitMessage(testContext(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		freqs = append(freqs, rigFrequency(msg))
+	}
+	return freqs
+}
+
+func waitDropped(t *testing.T, client *clhplugin.Client, n uint64) {
+	t.Helper()
+	waitUntil(t, func() bool { return client.Stats().Wait.Dropped == n })
+}
+
+func TestWaitOverflow(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy clhplugin.OverflowPolicy
+		want   []uint64
+	}{
+		{"drop oldest", clhplugin.OverflowDropOldest, []uint64{3, 4}},
+		{"drop newest", clhplugin.OverflowDropNewest, []uint64{1, 2}},
+		{"block with timeout", clhplugin.OverflowBlock, []uint64{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host := startHost(t)
+			client := waitClient(t, host, 2, tt.policy, 20*time.Millisecond)
+
+			pushRig(t, host, 1, 2, 3, 4)
+			waitDropped(t, client, 2)
+			if got := waitFrequencies(t, client, 2); got[0] != tt.want[0] || got[1] != tt.want[1] {
+				t.Fatalf("WaitMessage returned %v, want %v", got, tt.want)
+			}
+			stats := client.Stats().Wait
+			if stats.Delivered != 3 || stats.Dropped != 2 {
+				t.Fatalf("wait stats = %+v", stats)
+			}
+		})
+	}
+}
+
+func TestWaitOverflowBlockUntilRead(t *testing.T) {
+	host := startHost(t)
+	client := waitClient(t, host, 1, clhplugin.OverflowBlock, 0)
+
+	// With no timeout the read loop waits for WaitMessage instead of dropping.
+	pushRig(t, host, 1, 2, 3)
+	time.Sleep(50 * time.Millisecond)
+	if got := waitFrequencies(t, client, 3); got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Fatalf("WaitMessage returned %v", got)
+	}
+	if stats := client.Stats().Wait; stats.Dropped != 0 || stats.Enqueued != 4 {
+		t.Fatalf("wait stats = %+v", stats)
+	}
+
+	// Close releases a read loop blocked on the full buffer.
+	pushRig(t, host, 4, 5)
+	waitUntil(t, func() bool { return client.Stats().Wait.Enqueued == 5 })
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := client.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitStatsByKindAndTopic(t *testing.T) {
+	host := startHost(t)
+	client := waitClient(t, host, 1, clhplugin.OverflowDropNewest, 0)
+
+	wsjtx, err := clhplugin.NewWsjtxMessage("WSJT-X", clhplugin.WsjtxHeartbeat{MaxSchemaNumber: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := host.PushWsjtxMessage(wsjtx); err != nil {
+		t.Fatal(err)
+	}
+	pushRig(t, host, 7074000, 14074000)
+	if err := host.PushRaw(testManifest.UUID, clhplugin.RigData{Frequency: 21074000}); err != nil {
+		t.Fatal(err)
+	}
+	waitDropped(t, client, 3)
+	if _, err := client.WaitMessage(testContext(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The counts include the subscribe reply that waitClient took out.
+	stats := client.Stats()
+	if want := (clhplugin.QueueStats{Enqueued: 2, Delivered: 2, Dropped: 3}); stats.Wait != want {
+		t.Fatalf("Wait = %+v, want %+v", stats.Wait, want)
+	}
+	wantKind := map[clhplugin.InboundKind]clhplugin.QueueStats{
+		clhplugin.InboundKindEnvelope: {Enqueued: 2, Delivered: 2, Dropped: 2},
+		clhplugin.InboundKindRigData:  {Dropped: 1},
+	}
+	if len(stats.WaitByKind) != len(wantKind) {
+		t.Fatalf("WaitByKind = %+v", stats.WaitByKind)
+	}
+	for kind, want := range wantKind {
+		if got := stats.WaitByKind[kind]; got != want {
+			t.Errorf("WaitByKind[%s] = %+v, want %+v", kind, got, want)
+		}
+	}
+	// Bare RigData has no envelope, so it only shows up by kind.
+	wantTopic := map[clhplugin.EnvelopeTopic]clhplugin.QueueStats{
+		clhplugin.EnvelopeTopicCommandSubscribeEvents: {Enqueued: 1, Delivered: 1},
+		clhplugin.EnvelopeTopicEventWsjtxMessage:      {Enqueued: 1, Delivered: 1},
+		clhplugin.EnvelopeTopicEventRigData:           {Dropped: 2},
+	}
+	if len(stats.WaitByTopic) != len(wantTopic) {
+		t.Fatalf("WaitByTopic = %+v", stats.WaitByTopic)
+	}
+	for topic, want := range wantTopic {
+		if got := stats.WaitByTopic[topic]; got != want {
+			t.Errorf("WaitByTopic[%d] = %+v, want %+v", topic, got, want)
+		}
+	}
+}
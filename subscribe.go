@@ -0,0 +1,134 @@
+package clhplugin
+
+import "sync"
+
+// MessageFilter selects inbound messages for Subscribe. Empty Kinds or Topics match
+// everything; a non-empty Topics only matches envelope messages. Match, when set, must
+// also return true.
+type MessageFilter struct {
+	Kinds  []InboundKind
+	Topics []EnvelopeTopic
+	Match  func(Message) bool
+}
+
+func (f MessageFilter) matches(msg Message) bool {
+	if len(f.Kinds) > 0 && !containsKind(f.Kinds, msg.Kind) {
+		return false
+	}
+	if len(f.Topics) > 0 {
+		if msg.Envelope == nil || !containsTopic(f.Topics, msg.Envelope.Topic) {
+			return false
+		}
+	}
+	if f.Match != nil && !f.Match(msg) {
+		return false
+	}
+	return true
+}
+
+func containsKind(kinds []InboundKind, kind InboundKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTopic(topics []EnvelopeTopic, topic EnvelopeTopic) bool {
+	for _, t := range topics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
+
+type subscriber struct {
+	filter MessageFilter
+	ch     chan Message
+}
+
+type subscriberSet struct {
+	mu       sync.RWMutex
+	closed   bool
+	seq      uint64
+	subs     map[uint64]*subscriber
+	dropped  func(Message)
+	counters queueCounters
+}
+
+func (s *subscriberSet) add(filter MessageFilter, size int) (<-chan Message, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := make(chan Message, size)
+	if s.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if s.subs == nil {
+		s.subs = map[uint64]*subscriber{}
+	}
+	s.seq++
+	id := s.seq
+	s.subs[id] = &subscriber{filter: filter, ch: ch}
+
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if sub, ok := s.subs[id]; ok {
+			delete(s.subs, id)
+			close(sub.ch)
+		}
+	}
+}
+
+func (s *subscriberSet) publish(msg Message) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, sub := range s.subs {
+		if !sub.filter.matches(msg) {
+			continue
+		}
+		for {
+			select {
+			case sub.ch <- msg:
+				s.counters.enqueued.Add(1)
+			default:
+				select {
+				case old := <-sub.ch:
+					s.counters.dropped.Add(1)
+					s.dropped(old)
+				default:
+				}
+				continue
+			}
+			break
+		}
+	}
+}
+
+func (s *subscriberSet) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	for id, sub := range s.subs {
+		close(sub.ch)
+		delete(s.subs, id)
+	}
+}
+
+// Subscribe returns an independent channel of inbound messages matching filter, fed in
+// parallel with WaitMessage and the callbacks. When the channel buffer is full the oldest
+// message is dropped. Call cancel to stop the subscription; the channel is also closed
+// when the client finishes. bufferSize <= 0 uses the WaitMessage buffer size.
+func (c *Client) Subscribe(filter MessageFilter, bufferSize int) (<-chan Message, func()) {
+	if bufferSize <= 0 {
+		bufferSize = c.cfg.WaitBufferSize
+	}
+	return c.subscribers.add(filter, bufferSize)
+}
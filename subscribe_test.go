@@ -0,0 +1,172 @@
+package clhplugin_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	clhplugin "github.com/SydneyOwl/clh-plugin-go-sdk"
+	"github.com/SydneyOwl/clh-plugin-go-sdk/clhtest"
+)
+
+func subscribedClient(t *testing.T, host *clhtest.Host) *clhplugin.Client {
+	t.Helper()
+	client := connectClient(t, host)
+	sub := clhplugin.EventSubscription{Topics: []clhplugin.EnvelopeTopic{
+		clhplugin.EnvelopeTopicEventRigData,
+		clhplugin.EnvelopeTopicEventWsjtxMessage,
+	}}
+	if _, err := client.SubscribeEvents(testContext(t), sub); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func pushRig(t *testing.T, host *clhtest.Host, freqs ...uint64) {
+	t.Helper()
+	for _, freq := range freqs {
+		if _, err := host.PushRigData(clhplugin.RigData{Frequency: freq}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// receive reads n messages from ch.
+func receive(t *testing.T, ch <-chan clhplugin.Message, n int) []clhplugin.Message {
+	t.Helper()
+	var msgs []clhplugin.Message
+	timeout := time.After(5 * time.Second)
+	for len(msgs) < n {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				t.Fatalf("channel closed after %d of %d messages", len(msgs), n)
+			}
+			msgs = append(msgs, msg)
+		case <-timeout:
+			t.Fatalf("received %d of %d messages", len(msgs), n)
+		}
+	}
+	return msgs
+}
+
+func waitClosed(t *testing.T, ch <-chan clhplugin.Message) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("channel not closed")
+		}
+	}
+}
+
+func TestSubscribeFilter(t *testing.T) {
+	host := startHost(t)
+	client := subscribedClient(t, host)
+
+	all, cancelAll := client.Subscribe(clhplugin.MessageFilter{}, 16)
+	defer cancelAll()
+	rig, cancelRig := client.Subscribe(clhplugin.MessageFilter{
+		Topics: []clhplugin.EnvelopeTopic{clhplugin.EnvelopeTopicEventRigData},
+	}, 16)
+	defer cancelRig()
+	high, cancelHigh := client.Subscribe(clhplugin.MessageFilter{
+		Kinds: []clhplugin.InboundKind{clhplugin.InboundKindEnvelope},
+		Match: func(msg clhplugin.Message) bool { return rigFrequency(msg) > 10000000 },
+	}, 16)
+	defer cancelHigh()
+	unknown, cancelUnknown := client.Subscribe(clhplugin.MessageFilter{
+		Kinds: []clhplugin.InboundKind{clhplugin.InboundKindUnknown},
+	}, 16)
+	defer cancelUnknown()
+
+	pushRig(t, host, 7074000, 14074000)
+	wsjtx, err := clhplugin.NewWsjtxMessage("WSJT-X", clhplugin.WsjtxHeartbeat{MaxSchemaNumber: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := host.PushWsjtxMessage(wsjtx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Publishing is synchronous across subscriptions, so once the unfiltered channel has
+	// seen all three the others hold whatever they matched.
+	receive(t, all, 3)
+	if msgs := receive(t, rig, 2); rigFrequency(msgs[0]) != 7074000 || rigFrequency(msgs[1]) != 14074000 {
+		t.Fatalf("rig subscription got %+v", msgs)
+	}
+	if msgs := receive(t, high, 1); rigFrequency(msgs[0]) != 14074000 {
+		t.Fatalf("matched subscription got %+v", msgs)
+	}
+	if n := len(rig) + len(high) + len(unknown); n != 0 {
+		t.Fatalf("%d unexpected messages", n)
+	}
+}
+
+func TestSubscribeDropOldest(t *testing.T) {
+	host := startHost(t)
+	client := subscribedClient(t, host)
+	filter := clhplugin.MessageFilter{Topics: []clhplugin.EnvelopeTopic{clhplugin.EnvelopeTopicEventRigData}}
+
+	all, cancelAll := client.Subscribe(filter, 16)
+	defer cancelAll()
+	small, cancelSmall := client.Subscribe(filter, 2)
+	defer cancelSmall()
+	before := client.Stats().Subscriptions
+
+	pushRig(t, host, 1, 2, 3, 4)
+	receive(t, all, 4)
+	msgs := receive(t, small, 2)
+	if rigFrequency(msgs[0]) != 3 || rigFrequency(msgs[1]) != 4 {
+		t.Fatalf("small subscription kept %d, %d; want the newest 3, 4", rigFrequency(msgs[0]), rigFrequency(msgs[1]))
+	}
+	stats := client.Stats().Subscriptions
+	if got := stats.Dropped - before.Dropped; got != 2 {
+		t.Fatalf("dropped %d, want 2", got)
+	}
+	if got := stats.Enqueued - before.Enqueued; got != 8 {
+		t.Fatalf("enqueued %d, want 8", got)
+	}
+}
+
+func TestSubscribeCancel(t *testing.T) {
+	host := startHost(t)
+	client := subscribedClient(t, host)
+	filter := clhplugin.MessageFilter{Topics: []clhplugin.EnvelopeTopic{clhplugin.EnvelopeTopicEventRigData}}
+
+	kept, cancelKept := client.Subscribe(filter, 16)
+	defer cancelKept()
+	cancelled, cancel := client.Subscribe(filter, 16)
+	cancel()
+	cancel() // a second cancel is a no-op
+	waitClosed(t, cancelled)
+
+	pushRig(t, host, 7074000)
+	receive(t, kept, 1)
+}
+
+func TestSubscribeClosedWithClient(t *testing.T) {
+	host := startHost(t)
+	client := subscribedClient(t, host)
+
+	a, cancelA := client.Subscribe(clhplugin.MessageFilter{}, 4)
+	defer cancelA()
+	b, cancelB := client.Subscribe(clhplugin.MessageFilter{Kinds: []clhplugin.InboundKind{clhplugin.InboundKindRigData}}, 4)
+	defer cancelB()
+
+	if err := client.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	waitClosed(t, a)
+	waitClosed(t, b)
+
+	// Subscribing after the client finished returns a closed channel.
+	late, cancelLate := client.Subscribe(clhplugin.MessageFilter{}, 4)
+	defer cancelLate()
+	waitClosed(t, late)
+}
@@ -0,0 +1,170 @@
+package clhplugin
+
+import (
+	"context"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	pb "github.com/SydneyOwl/clh-proto/gen/go/v20260312"
+)
+
+// TraceParentAttribute carries a W3C traceparent in PipeEnvelope attributes.
+const TraceParentAttribute = "traceparent"
+
+// Span attribute keys set by the client.
+const (
+	SpanAttrTopic         = "clh.topic"
+	SpanAttrKind          = "clh.kind"
+	SpanAttrRequestID     = "clh.request_id"
+	SpanAttrCorrelationID = "clh.correlation_id"
+	SpanAttrEnvelopeID    = "clh.envelope_id"
+	SpanAttrErrorCode     = "clh.error_code"
+)
+
+type SpanKind string
+
+const (
+	SpanKindClient   SpanKind = "client"
+	SpanKindConsumer SpanKind = "consumer"
+)
+
+// SpanContext identifies a span across processes, as in W3C Trace Context.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// TraceParent formats sc as a version 00 traceparent header value.
+func (sc SpanContext) TraceParent() string {
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" +
+		hex.EncodeToString([]byte{sc.Flags})
+}
+
+// ParseTraceParent parses a traceparent header value. Versions other than 00 are read by
+// their first four fields, as the specification asks.
+func ParseTraceParent(s string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, false
+	}
+	var version [1]byte
+	if !decodeHex(version[:], parts[0]) {
+		return SpanContext{}, false
+	}
+	var sc SpanContext
+	if !decodeHex(sc.TraceID[:], parts[1]) || !decodeHex(sc.SpanID[:], parts[2]) {
+		return SpanContext{}, false
+	}
+	var flags [1]byte
+	if !decodeHex(flags[:], parts[3]) {
+		return SpanContext{}, false
+	}
+	sc.Flags = flags[0]
+	return sc, sc.IsValid()
+}
+
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != 2*len(dst) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+type SpanStartOptions struct {
+	Kind SpanKind
+	// Links are related spans that are not the parent, e.g. the sender of an event.
+	Links []SpanContext
+}
+
+// Tracer starts spans. It is deliberately small so an OpenTelemetry tracer can be adapted
+// in a few lines without the SDK depending on OpenTelemetry; the parent comes from ctx.
+type Tracer interface {
+	Start(ctx context.Context, name string, opts SpanStartOptions) (context.Context, Span)
+}
+
+type Span interface {
+	SpanContext() SpanContext
+	SetAttribute(key string, value any)
+	RecordError(err error)
+	End()
+}
+
+// RemoteSpanContext returns the span context CLH attached to an inbound envelope.
+func (m Message) RemoteSpanContext() (SpanContext, bool) {
+	if m.Envelope == nil {
+		return SpanContext{}, false
+	}
+	return ParseTraceParent(m.Envelope.Attributes[TraceParentAttribute])
+}
+
+func spanContextOf(span Span) SpanContext {
+	if span == nil {
+		return SpanContext{}
+	}
+	return span.SpanContext()
+}
+
+// startRequestSpan opens a client span for one request attempt; endRequestSpan closes it.
+func (c *Client) startRequestSpan(ctx context.Context, kind EnvelopeKind, topic EnvelopeTopic) (context.Context, Span) {
+	ctx, span := c.cfg.Tracer.Start(ctx, "clh "+envelopeKindName(kind)+" "+strconv.Itoa(int(topic)), SpanStartOptions{
+		Kind: SpanKindClient,
+	})
+	span.SetAttribute(SpanAttrKind, envelopeKindName(kind))
+	span.SetAttribute(SpanAttrTopic, int(topic))
+	return ctx, span
+}
+
+func endRequestSpan(span Span, topic EnvelopeTopic, reqID string, resp *pb.PipeEnvelope, err error) {
+	if reqID != "" {
+		span.SetAttribute(SpanAttrRequestID, reqID)
+	}
+	switch {
+	case err != nil:
+		span.RecordError(err)
+	case !resp.Success:
+		span.SetAttribute(SpanAttrCorrelationID, resp.CorrelationId)
+		span.SetAttribute(SpanAttrErrorCode, resp.ErrorCode)
+		span.RecordError(remoteError(topic, resp))
+	default:
+		span.SetAttribute(SpanAttrCorrelationID, resp.CorrelationId)
+	}
+	span.End()
+}
+
+// startEventSpan opens a consumer span for an inbound event, linked to the sender's span
+// when CLH passed a traceparent. It covers routing only; callbacks may run later.
+func (c *Client) startEventSpan(msg Message) Span {
+	if c.cfg.Tracer == nil || msg.Envelope == nil || msg.Envelope.Kind != EnvelopeKindEvent {
+		return nil
+	}
+	opts := SpanStartOptions{Kind: SpanKindConsumer}
+	if remote, ok := msg.RemoteSpanContext(); ok {
+		opts.Links = append(opts.Links, remote)
+	}
+	_, span := c.cfg.Tracer.Start(context.Background(), "clh event "+strconv.Itoa(int(msg.Envelope.Topic)), opts)
+	span.SetAttribute(SpanAttrTopic, int(msg.Envelope.Topic))
+	span.SetAttribute(SpanAttrEnvelopeID, msg.Envelope.ID)
+	return span
+}
+
+func envelopeKindName(kind EnvelopeKind) string {
+	switch kind {
+	case EnvelopeKindEvent:
+		return "event"
+	case EnvelopeKindQuery:
+		return "query"
+	case EnvelopeKindCommand:
+		return "command"
+	case EnvelopeKindResponse:
+		return "response"
+	default:
+		return "unspecified"
+	}
+}
@@ -0,0 +1,157 @@
+package clhplugin_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	clhplugin "github.com/SydneyOwl/clh-plugin-go-sdk"
+	"github.com/SydneyOwl/clh-plugin-go-sdk/clhtest"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	const valid = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tests := []struct {
+		in    string
+		ok    bool
+		flags byte
+	}{
+		{valid, true, 1},
+		{" " + valid + " ", true, 1},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, 0},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-09-future", true, 9},
+		{valid + "-extra", false, 0},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, 0},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false, 0},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, 0},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, 0},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01", false, 0},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1", false, 0},
+		{"0-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, 0},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false, 0},
+		{"zz-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, 0},
+		{"", false, 0},
+	}
+	for _, tt := range tests {
+		sc, ok := clhplugin.ParseTraceParent(tt.in)
+		if ok != tt.ok {
+			t.Errorf("ParseTraceParent(%q) ok = %v, want %v", tt.in, ok, tt.ok)
+			continue
+		}
+		if ok && sc.Flags != tt.flags {
+			t.Errorf("ParseTraceParent(%q) flags = %d, want %d", tt.in, sc.Flags, tt.flags)
+		}
+	}
+
+	sc, _ := clhplugin.ParseTraceParent(valid)
+	if got := sc.TraceParent(); got != valid {
+		t.Errorf("TraceParent() = %q, want %q", got, valid)
+	}
+}
+
+type testSpan struct {
+	tracer *testTracer
+	name   string
+	opts   clhplugin.SpanStartOptions
+	sc     clhplugin.SpanContext
+	attrs  map[string]any
+	err    error
+	ended  bool
+}
+
+func (s *testSpan) SpanContext() clhplugin.SpanContext { return s.sc }
+
+func (s *testSpan) SetAttribute(key string, value any) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.attrs[key] = value
+}
+
+func (s *testSpan) RecordError(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.err = err
+}
+
+func (s *testSpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.ended = true
+}
+
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+func (tr *testTracer) Start(ctx context.Context, name string, opts clhplugin.SpanStartOptions) (context.Context, clhplugin.Span) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	n := byte(len(tr.spans) + 1)
+	span := &testSpan{tracer: tr, name: name, opts: opts, attrs: map[string]any{}}
+	span.sc.TraceID[15], span.sc.SpanID[7], span.sc.Flags = 0xaa, n, 1
+	tr.spans = append(tr.spans, span)
+	return ctx, span
+}
+
+// ended returns copies of the finished spans of the given kind.
+func (tr *testTracer) ended(kind clhplugin.SpanKind) []testSpan {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	var out []testSpan
+	for _, s := range tr.spans {
+		if s.ended && s.opts.Kind == kind {
+			out = append(out, *s)
+		}
+	}
+	return out
+}
+
+func TestTracing(t *testing.T) {
+	host := startHost(t)
+	tracer := &testTracer{}
+	client := connectClient(t, host, clhplugin.WithTracer(tracer))
+	ctx := testContext(t)
+
+	if _, err := client.QueryServerInfo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	spans := tracer.ended(clhplugin.SpanKindClient)
+	if len(spans) != 1 {
+		t.Fatalf("%d client spans, want 1", len(spans))
+	}
+	span := spans[0]
+	reqs := host.Requests()
+	last := reqs[len(reqs)-1]
+	if got := last.Attributes[clhplugin.TraceParentAttribute]; got != span.sc.TraceParent() {
+		t.Errorf("request traceparent = %q, want %q", got, span.sc.TraceParent())
+	}
+	if span.attrs[clhplugin.SpanAttrRequestID] != last.ID || span.attrs[clhplugin.SpanAttrKind] != "query" || span.err != nil {
+		t.Errorf("client span = %+v", span)
+	}
+
+	host.Handle(clhplugin.EnvelopeTopicQueryServerInfo, func(context.Context, clhtest.Request) clhtest.Response {
+		return clhtest.Fail(clhtest.CodeHandlerError, "boom")
+	})
+	if _, err := client.QueryServerInfo(ctx); err == nil {
+		t.Fatal("query succeeded")
+	}
+	spans = tracer.ended(clhplugin.SpanKindClient)
+	if failed := spans[len(spans)-1]; failed.err == nil || failed.attrs[clhplugin.SpanAttrErrorCode] != clhtest.CodeHandlerError {
+		t.Errorf("failed span = %+v", failed)
+	}
+
+	if _, err := client.SubscribeEvents(ctx, clhplugin.EventSubscription{
+		Topics: []clhplugin.EnvelopeTopic{clhplugin.EnvelopeTopicEventRigData},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := host.PushRigData(clhplugin.RigData{Frequency: 14074000}); err != nil {
+		t.Fatal(err)
+	}
+	waitUntil(t, func() bool { return len(tracer.ended(clhplugin.SpanKindConsumer)) == 1 })
+	event := tracer.ended(clhplugin.SpanKindConsumer)[0]
+	if event.attrs[clhplugin.SpanAttrTopic] != int(clhplugin.EnvelopeTopicEventRigData) {
+		t.Errorf("event span = %+v", event)
+	}
+}
@@ -0,0 +1,159 @@
+package clhplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Dialer opens the raw connection to a CLH host. The client performs registration and
+// framing on top of the returned net.Conn.
+type Dialer func(ctx context.Context) (net.Conn, error)
+
+const (
+	schemeTCP  = "tcp://"
+	schemeUnix = "unix://"
+	schemePipe = "pipe://"
+)
+
+// validateEndpoint checks a WithPipePath value. Plain paths keep the platform default
+// (unix socket, or named pipe on Windows); tcp://, unix:// and pipe:// select explicitly.
+func validateEndpoint(endpoint string) error {
+	scheme, rest, ok := splitScheme(endpoint)
+	if !ok {
+		return nil
+	}
+	switch scheme {
+	case schemeTCP:
+		if _, _, err := net.SplitHostPort(rest); err != nil {
+			return fmt.Errorf("invalid tcp endpoint %q: %w", endpoint, err)
+		}
+	case schemeUnix, schemePipe:
+		if rest == "" {
+			return fmt.Errorf("invalid endpoint %q: path is empty", endpoint)
+		}
+	default:
+		return fmt.Errorf("unsupported endpoint scheme in %q", endpoint)
+	}
+	return nil
+}
+
+func splitScheme(endpoint string) (scheme, rest string, ok bool) {
+	idx := strings.Index(endpoint, "://")
+	if idx <= 0 {
+		return "", endpoint, false
+	}
+	return strings.ToLower(endpoint[:idx+3]), endpoint[idx+3:], true
+}
+
+func dialEndpoint(ctx context.Context, endpoint string) (net.Conn, error) {
+	scheme, rest, ok := splitScheme(endpoint)
+	if !ok {
+		return dialPipe(ctx, endpoint)
+	}
+
+	var d net.Dialer
+	switch scheme {
+	case schemeTCP:
+		return d.DialContext(ctx, "tcp", rest)
+	case schemeUnix:
+		return d.DialContext(ctx, "unix", rest)
+	case schemePipe:
+		if runtime.GOOS == "windows" && !strings.HasPrefix(rest, `\\`) {
+			rest = `\\.\pipe\` + rest
+		}
+		return dialPipe(ctx, rest)
+	default:
+		return nil, fmt.Errorf("unsupported endpoint scheme in %q", endpoint)
+	}
+}
+
+func listenEndpoint(endpoint string) (net.Listener, error) {
+	scheme, rest, ok := splitScheme(endpoint)
+	if !ok {
+		return listenPipe(endpoint)
+	}
+
+	switch scheme {
+	case schemeTCP:
+		return net.Listen("tcp", rest)
+	case schemeUnix:
+		return net.Listen("unix", rest)
+	case schemePipe:
+		if runtime.GOOS == "windows" && !strings.HasPrefix(rest, `\\`) {
+			rest = `\\.\pipe\` + rest
+		}
+		return listenPipe(rest)
+	default:
+		return nil, fmt.Errorf("unsupported endpoint scheme in %q", endpoint)
+	}
+}
+
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	if c.cfg.Dialer != nil {
+		return c.cfg.Dialer(ctx)
+	}
+	return dialEndpoint(ctx, c.cfg.PipePath)
+}
+
+var errMemoryListenerClosed = errors.New("memory listener closed")
+
+type memoryAddr struct{}
+
+func (memoryAddr) Network() string { return "memory" }
+func (memoryAddr) String() string  { return "memory" }
+
+// MemoryListener is an in-process transport built on net.Pipe. Pass its Dial method to
+// WithDialer on the client side and Accept connections on the host side.
+type MemoryListener struct {
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func NewMemoryListener() *MemoryListener {
+	return &MemoryListener{
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+func (l *MemoryListener) Dial(ctx context.Context) (net.Conn, error) {
+	client, server := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.done:
+	case <-ctx.Done():
+		_ = client.Close()
+		_ = server.Close()
+		return nil, ctx.Err()
+	}
+	_ = client.Close()
+	_ = server.Close()
+	return nil, errMemoryListenerClosed
+}
+
+func (l *MemoryListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *MemoryListener) Close() error {
+	l.once.Do(func() {
+		close(l.done)
+	})
+	return nil
+}
+
+func (l *MemoryListener) Addr() net.Addr {
+	return memoryAddr{}
+}
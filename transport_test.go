@@ -0,0 +1,119 @@
+package clhplugin
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestValidateEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		ok       bool
+	}{
+		{"/tmp/clh.sock", true},
+		{`\\.\pipe\clh`, true},
+		{"tcp://127.0.0.1:7000", true},
+		{"TCP://localhost:7000", true},
+		{"tcp://[::1]:7000", true},
+		{"tcp://:7000", true},
+		{"tcp://127.0.0.1", false},
+		{"tcp://", false},
+		{"tcp://[::1", false},
+		{"unix:///tmp/clh.sock", true},
+		{"unix://relative.sock", true},
+		{"unix://", false},
+		{"pipe://clh", true},
+		{"pipe://", false},
+		{"http://127.0.0.1:7000", false},
+		{"udp://127.0.0.1:7000", false},
+	}
+	for _, tt := range tests {
+		err := validateEndpoint(tt.endpoint)
+		if (err == nil) != tt.ok {
+			t.Errorf("validateEndpoint(%q) = %v, want ok %v", tt.endpoint, err, tt.ok)
+		}
+		if _, err := NewClient(PluginManifest{UUID: "t", Name: "t", Version: "1.0.0"}, WithPipePath(tt.endpoint)); (err == nil) != tt.ok {
+			t.Errorf("WithPipePath(%q) = %v, want ok %v", tt.endpoint, err, tt.ok)
+		}
+	}
+}
+
+func TestSplitScheme(t *testing.T) {
+	tests := []struct {
+		endpoint, scheme, rest string
+		ok                     bool
+	}{
+		{"tcp://127.0.0.1:7000", schemeTCP, "127.0.0.1:7000", true},
+		{"Unix:///run/clh.sock", schemeUnix, "/run/clh.sock", true},
+		{"pipe://clh", schemePipe, "clh", true},
+		{"/run/clh.sock", "", "/run/clh.sock", false},
+		{"://x", "", "://x", false},
+	}
+	for _, tt := range tests {
+		scheme, rest, ok := splitScheme(tt.endpoint)
+		if scheme != tt.scheme || rest != tt.rest || ok != tt.ok {
+			t.Errorf("splitScheme(%q) = %q, %q, %v", tt.endpoint, scheme, rest, ok)
+		}
+	}
+}
+
+func TestEndpointRejectedWhenDialing(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := dialEndpoint(ctx, "udp://127.0.0.1:7000"); err == nil {
+		t.Error("dialEndpoint accepted udp://")
+	}
+	if _, err := listenEndpoint("udp://127.0.0.1:0"); err == nil {
+		t.Error("listenEndpoint accepted udp://")
+	}
+}
+
+// connectEndpoint serves a Server on l and connects a client to endpoint.
+func connectEndpoint(t *testing.T, l net.Listener, endpoint string) {
+	t.Helper()
+	server, err := NewServer(WithServerInstance("clh-endpoint", "1.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = server.Serve(l) }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	client, err := NewClient(PluginManifest{UUID: "t", Name: "t", Version: "1.0.0"}, WithPipePath(endpoint))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	defer client.Close(ctx)
+	if _, err := client.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	info, err := client.QueryServerInfo(ctx)
+	if err != nil || info.InstanceID != "clh-endpoint" {
+		t.Fatalf("QueryServerInfo = %+v, %v", info, err)
+	}
+}
+
+func TestTCPEndpoint(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("tcp loopback unavailable: %v", err)
+	}
+	connectEndpoint(t, l, "tcp://"+l.Addr().String())
+}
+
+func TestUnixEndpoint(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not used on windows")
+	}
+	path := filepath.Join(t.TempDir(), "clh.sock")
+	l, err := listenEndpoint("unix://" + path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	connectEndpoint(t, l, "unix://"+path)
+}
@@ -6,10 +6,26 @@ package clhplugin
 
 import (
 	"context"
+	"fmt"
 	"net"
+	"os"
+	"time"
 )
 
 func dialPipe(ctx context.Context, networkPath string) (net.Conn, error) {
 	var d net.Dialer
 	return d.DialContext(ctx, "unix", networkPath)
 }
+
+// listenPipe removes a socket file left behind by a host that exited, but refuses a path a
+// live host (such as CLH itself) is still accepting on.
+func listenPipe(networkPath string) (net.Listener, error) {
+	if fi, err := os.Stat(networkPath); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.DialTimeout("unix", networkPath, time.Second); err == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("%s is in use by another host", networkPath)
+		}
+		_ = os.Remove(networkPath)
+	}
+	return net.Listen("unix", networkPath)
+}
@@ -13,3 +13,7 @@ import (
 func dialPipe(ctx context.Context, networkPath string) (net.Conn, error) {
 	return winio.DialPipeContext(ctx, networkPath)
 }
+
+func listenPipe(networkPath string) (net.Listener, error) {
+	return winio.ListenPipe(networkPath, nil)
+}
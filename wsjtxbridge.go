@@ -0,0 +1,333 @@
+package clhplugin
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const maxWsjtxDatagram = 64 << 10
+
+// Attributes set on envelopes produced by the bridge.
+const (
+	BridgeAttrSource = "source"
+	BridgeAttrRemote = "remote_addr"
+	BridgeSourceUDP  = "wsjtx_udp"
+)
+
+type WsjtxBridgeOption func(*WsjtxBridgeConfig) error
+
+type WsjtxBridgeConfig struct {
+	// Interface selects the multicast interface; nil lets the system choose.
+	Interface *net.Interface
+	// ForwardTo lists downstream listeners that receive every datagram unchanged. Their
+	// replies (Reply, HaltTx, ...) are relayed to the WSJT-X instance they name.
+	ForwardTo []string
+	// BatchWindow also collects decodes into one PackedDecodeMessage, flushed this long
+	// after the first decode of a burst. Each decode is then delivered twice: on its own
+	// and in the batch. 0, the default, disables batches.
+	BatchWindow time.Duration
+	// OnError receives datagrams that failed to decode and forwarding errors.
+	OnError func(error)
+}
+
+func WithBridgeInterface(iface *net.Interface) WsjtxBridgeOption {
+	return func(cfg *WsjtxBridgeConfig) error {
+		cfg.Interface = iface
+		return nil
+	}
+}
+
+func WithBridgeForward(addrs ...string) WsjtxBridgeOption {
+	return func(cfg *WsjtxBridgeConfig) error {
+		for _, addr := range addrs {
+			if addr == "" {
+				return errors.New("forward address cannot be empty")
+			}
+		}
+		cfg.ForwardTo = append(cfg.ForwardTo, addrs...)
+		return nil
+	}
+}
+
+func WithBridgeBatchWindow(window time.Duration) WsjtxBridgeOption {
+	return func(cfg *WsjtxBridgeConfig) error {
+		if window < 0 {
+			return errors.New("batch window cannot be negative")
+		}
+		cfg.BatchWindow = window
+		return nil
+	}
+}
+
+func WithBridgeErrorHandler(fn func(error)) WsjtxBridgeOption {
+	return func(cfg *WsjtxBridgeConfig) error {
+		cfg.OnError = fn
+		return nil
+	}
+}
+
+// WsjtxBridge listens for WSJT-X/JTDX UDP datagrams and feeds them into the client's
+// message routing as CLH event envelopes: EventWsjtxMessage, EventWsjtxDecodeRealtime
+// for decodes and, with WithBridgeBatchWindow, EventWsjtxDecodeBatch for batches.
+type WsjtxBridge struct {
+	client  *Client
+	cfg     WsjtxBridgeConfig
+	conn    *net.UDPConn
+	forward []*net.UDPAddr
+	fwdConn *net.UDPConn
+
+	mu      sync.Mutex
+	sources map[string]*net.UDPAddr // WSJT-X id -> address it sends from
+	batch   []WsjtxDecode
+	timer   *time.Timer
+	closed  bool
+
+	seq       atomic.Uint64
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// StartWsjtxBridge listens on addr, e.g. ":2237" or the multicast group "224.0.0.73:2237".
+// The bridge runs until Close or until the client finishes.
+func (c *Client) StartWsjtxBridge(addr string, opts ...WsjtxBridgeOption) (*WsjtxBridge, error) {
+	var cfg WsjtxBridgeConfig
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	b := &WsjtxBridge{
+		client:  c,
+		cfg:     cfg,
+		sources: map[string]*net.UDPAddr{},
+		done:    make(chan struct{}),
+	}
+	for _, f := range cfg.ForwardTo {
+		fa, err := net.ResolveUDPAddr("udp", f)
+		if err != nil {
+			return nil, fmt.Errorf("forward address %s: %w", f, err)
+		}
+		b.forward = append(b.forward, fa)
+	}
+
+	if udpAddr.IP != nil && udpAddr.IP.IsMulticast() {
+		b.conn, err = net.ListenMulticastUDP("udp", cfg.Interface, udpAddr)
+	} else {
+		b.conn, err = net.ListenUDP("udp", udpAddr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(b.forward) > 0 {
+		if b.fwdConn, err = net.ListenUDP("udp", nil); err != nil {
+			_ = b.conn.Close()
+			return nil, err
+		}
+	}
+
+	go b.readLoop()
+	if b.fwdConn != nil {
+		go b.relayLoop()
+	}
+	go func() {
+		select {
+		case <-c.doneCh:
+			_ = b.Close()
+		case <-b.done:
+		}
+	}()
+	return b, nil
+}
+
+func (b *WsjtxBridge) Addr() net.Addr {
+	return b.conn.LocalAddr()
+}
+
+// Done is closed when the bridge stops.
+func (b *WsjtxBridge) Done() <-chan struct{} {
+	return b.done
+}
+
+// Close stops listening and flushes a pending decode batch.
+func (b *WsjtxBridge) Close() error {
+	var err error
+	b.closeOnce.Do(func() {
+		b.mu.Lock()
+		b.closed = true
+		b.mu.Unlock()
+		err = b.conn.Close()
+		if b.fwdConn != nil {
+			_ = b.fwdConn.Close()
+		}
+		b.flushBatch()
+		close(b.done)
+	})
+	return err
+}
+
+// Send marshals msg and sends it to the WSJT-X instance whose id is msg.Header.ID, from
+// the listening socket, which is where WSJT-X expects Reply, HaltTx, HighlightCallsign
+// and the other incoming messages.
+func (b *WsjtxBridge) Send(msg WsjtxMessage) error {
+	data, err := MarshalWsjtx(msg)
+	if err != nil {
+		return err
+	}
+	return b.sendTo(msg.Header.ID, data)
+}
+
+func (b *WsjtxBridge) sendTo(id string, data []byte) error {
+	b.mu.Lock()
+	dst := b.sources[id]
+	b.mu.Unlock()
+	if dst == nil {
+		return fmt.Errorf("no wsjtx instance %q heard yet", id)
+	}
+	_, err := b.conn.WriteToUDP(data, dst)
+	return err
+}
+
+func (b *WsjtxBridge) readLoop() {
+	buf := make([]byte, maxWsjtxDatagram)
+	for {
+		n, from, err := b.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-b.done:
+			default:
+				if !errors.Is(err, net.ErrClosed) {
+					b.report(err)
+				}
+				_ = b.Close()
+			}
+			return
+		}
+		data := buf[:n]
+		b.forwardDatagram(data)
+
+		msg, err := UnmarshalWsjtx(data)
+		if err != nil {
+			b.report(fmt.Errorf("wsjtx datagram from %s: %w", from, err))
+			continue
+		}
+		b.mu.Lock()
+		b.sources[msg.Header.ID] = from
+		b.mu.Unlock()
+		b.handle(msg, from)
+	}
+}
+
+// relayLoop reads what downstream listeners send back to the forwarding socket and passes
+// it on, unchanged, to the WSJT-X instance named in its header.
+func (b *WsjtxBridge) relayLoop() {
+	buf := make([]byte, maxWsjtxDatagram)
+	for {
+		n, from, err := b.fwdConn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-b.done:
+			default:
+				if !errors.Is(err, net.ErrClosed) {
+					b.report(err)
+				}
+			}
+			return
+		}
+		data := buf[:n]
+		id, err := wsjtxInstanceID(data)
+		if err == nil {
+			err = b.sendTo(id, data)
+		}
+		if err != nil {
+			b.report(fmt.Errorf("relay from %s: %w", from, err))
+		}
+	}
+}
+
+func (b *WsjtxBridge) forwardDatagram(data []byte) {
+	for _, dst := range b.forward {
+		if _, err := b.fwdConn.WriteToUDP(data, dst); err != nil {
+			b.report(fmt.Errorf("forward to %s: %w", dst, err))
+		}
+	}
+}
+
+func (b *WsjtxBridge) handle(msg WsjtxMessage, from *net.UDPAddr) {
+	topic := EnvelopeTopicEventWsjtxMessage
+	if msg.Decode != nil {
+		topic = EnvelopeTopicEventWsjtxDecodeRealtime
+	}
+	b.inject(topic, msg, from.String())
+
+	if msg.Decode == nil || b.cfg.BatchWindow <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.batch = append(b.batch, *msg.Decode)
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.cfg.BatchWindow, b.flushBatch)
+	}
+}
+
+func (b *WsjtxBridge) flushBatch() {
+	b.mu.Lock()
+	batch := b.batch
+	b.batch = nil
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+	b.inject(EnvelopeTopicEventWsjtxDecodeBatch, PackedDecodeMessage{
+		Messages:  batch,
+		Timestamp: time.Now().UTC(),
+	}, "")
+}
+
+func (b *WsjtxBridge) inject(topic EnvelopeTopic, payload any, remote string) {
+	now := time.Now().UTC()
+	attrs := map[string]string{BridgeAttrSource: BridgeSourceUDP}
+	if remote != "" {
+		attrs[BridgeAttrRemote] = remote
+	}
+	b.client.injectMessage(Message{
+		Kind:      InboundKindEnvelope,
+		Timestamp: now,
+		Envelope: &Envelope{
+			ID:         "wsjtx-bridge-" + strconv.FormatUint(b.seq.Add(1), 10),
+			Kind:       EnvelopeKindEvent,
+			Topic:      topic,
+			Success:    true,
+			Attributes: attrs,
+			Payload:    payload,
+			Timestamp:  now,
+		},
+	})
+}
+
+func (b *WsjtxBridge) report(err error) {
+	b.client.log.Debug("wsjtx bridge error", "error", err)
+	if b.cfg.OnError != nil {
+		b.cfg.OnError(err)
+	}
+}
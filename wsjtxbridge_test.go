@@ -0,0 +1,174 @@
+package clhplugin
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, opts ...Option) *Client {
+	t.Helper()
+	c, err := NewClient(PluginManifest{UUID: "test-plugin", Name: "test", Version: "1.0.0"}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close(context.Background()) })
+	return c
+}
+
+func listenUDP(t *testing.T) *net.UDPConn {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func readWsjtx(t *testing.T, conn *net.UDPConn) (WsjtxMessage, *net.UDPAddr) {
+	t.Helper()
+	buf := make([]byte, maxWsjtxDatagram)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, from, err := conn.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := UnmarshalWsjtx(buf[:n])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg, from
+}
+
+func sendWsjtx(t *testing.T, conn *net.UDPConn, to *net.UDPAddr, id string, payload any) {
+	t.Helper()
+	msg, err := NewWsjtxMessage(id, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := MarshalWsjtx(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.WriteToUDP(data, to); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWsjtxBridge(t *testing.T) {
+	c := newTestClient(t)
+	decodes := make(chan WsjtxDecode, 4)
+	batches := make(chan PackedDecodeMessage, 4)
+	c.OnWsjtxDecode(func(d WsjtxDecode) { decodes <- d })
+	c.OnPackedDecode(func(p PackedDecodeMessage) { batches <- p })
+
+	downstream := listenUDP(t)
+	bridge, err := c.StartWsjtxBridge("127.0.0.1:0",
+		WithBridgeForward(downstream.LocalAddr().String()),
+		WithBridgeBatchWindow(50*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bridge.Close()
+	bridgeAddr := bridge.Addr().(*net.UDPAddr)
+
+	wsjtx := listenUDP(t)
+	sendWsjtx(t, wsjtx, bridgeAddr, "WSJT-X", WsjtxDecode{SNR: -10, Mode: "~", Message: "CQ BH1XYZ OM89"})
+
+	select {
+	case d := <-decodes:
+		if d.Message != "CQ BH1XYZ OM89" {
+			t.Fatalf("decode = %+v", d)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("decode not delivered")
+	}
+	select {
+	case b := <-batches:
+		if len(b.Messages) != 1 {
+			t.Fatalf("batch = %+v", b)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("batch not delivered")
+	}
+
+	// The forward target gets the datagram unchanged, and its reply goes back to WSJT-X.
+	fwd, fwdFrom := readWsjtx(t, downstream)
+	if fwd.Decode == nil || fwd.Header.ID != "WSJT-X" {
+		t.Fatalf("forwarded = %+v", fwd)
+	}
+	sendWsjtx(t, downstream, fwdFrom, "WSJT-X", WsjtxHaltTx{AutoTXOnly: true})
+	relayed, relayedFrom := readWsjtx(t, wsjtx)
+	if relayed.HaltTx == nil || !relayed.HaltTx.AutoTXOnly {
+		t.Fatalf("relayed = %+v", relayed)
+	}
+	if relayedFrom.Port != bridgeAddr.Port {
+		t.Fatalf("relayed from %v, want the listening socket %v", relayedFrom, bridgeAddr)
+	}
+
+	// Send addresses the instance by id.
+	text, _ := NewWsjtxMessage("WSJT-X", WsjtxFreeText{Text: "TNX", Send: true})
+	if err := bridge.Send(text); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := readWsjtx(t, wsjtx); got.FreeText == nil || got.FreeText.Text != "TNX" {
+		t.Fatalf("sent = %+v", got)
+	}
+	unknown, _ := NewWsjtxMessage("JTDX", WsjtxHaltTx{})
+	if err := bridge.Send(unknown); err == nil {
+		t.Fatal("Send to an unheard instance succeeded")
+	}
+}
+
+func TestWsjtxBridgeClosesWithClient(t *testing.T) {
+	c := newTestClient(t)
+	bridge, err := c.StartWsjtxBridge("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-bridge.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("bridge still running after the client closed")
+	}
+}
+
+func TestWsjtxBridgeDefaultDeliversOnce(t *testing.T) {
+	c := newTestClient(t)
+	decodes := make(chan WsjtxDecode, 8)
+	batches := make(chan PackedDecodeMessage, 8)
+	c.OnWsjtxDecode(func(d WsjtxDecode) { decodes <- d })
+	c.OnPackedDecode(func(p PackedDecodeMessage) { batches <- p })
+
+	bridge, err := c.StartWsjtxBridge("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bridgeAddr := bridge.Addr().(*net.UDPAddr)
+
+	wsjtx := listenUDP(t)
+	sendWsjtx(t, wsjtx, bridgeAddr, "WSJT-X", WsjtxDecode{SNR: -10, Mode: "~", Message: "CQ BH1XYZ OM89"})
+	sendWsjtx(t, wsjtx, bridgeAddr, "WSJT-X", WsjtxDecode{SNR: -3, Mode: "~", Message: "BH1XYZ JA1ABC PM95"})
+	for i := 0; i < 2; i++ {
+		select {
+		case <-decodes:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%d decodes delivered, want 2", i)
+		}
+	}
+
+	// Close flushes any pending batch, so nothing can still be on its way afterwards.
+	if err := bridge.Close(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(decodes) != 0 || len(batches) != 0 {
+		t.Fatalf("%d extra decodes and %d batches delivered", len(decodes), len(batches))
+	}
+}
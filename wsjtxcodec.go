@@ -0,0 +1,490 @@
+package clhplugin
+
+import (
+	"fmt"
+	"time"
+)
+
+// WSJT-X UDP framing constants. Schema 2 and 3 differ only in Qt stream details that
+// the fields used here do not touch, so both are read and written the same way.
+const (
+	WsjtxMagic         uint32 = 0xadbccbda
+	WsjtxSchema2       uint32 = 2
+	WsjtxSchema3       uint32 = 3
+	defaultWsjtxSchema        = WsjtxSchema3
+)
+
+// NewWsjtxMessage wraps one payload struct (WsjtxReply, *WsjtxReply, WsjtxHaltTx, ...)
+// in a message for client id, setting the header type from the payload.
+func NewWsjtxMessage(id string, payload any) (WsjtxMessage, error) {
+	msg := WsjtxMessage{Header: WsjtxMessageHeader{
+		MagicNumber:  WsjtxMagic,
+		SchemaNumber: defaultWsjtxSchema,
+		ID:           id,
+	}}
+	switch p := payload.(type) {
+	case WsjtxHeartbeat:
+		msg.Header.Type, msg.Heartbeat = WsjtxMessageTypeHeartbeat, &p
+	case *WsjtxHeartbeat:
+		msg.Header.Type, msg.Heartbeat = WsjtxMessageTypeHeartbeat, p
+	case WsjtxStatus:
+		msg.Header.Type, msg.Status = WsjtxMessageTypeStatus, &p
+	case *WsjtxStatus:
+		msg.Header.Type, msg.Status = WsjtxMessageTypeStatus, p
+	case WsjtxDecode:
+		msg.Header.Type, msg.Decode = WsjtxMessageTypeDecode, &p
+	case *WsjtxDecode:
+		msg.Header.Type, msg.Decode = WsjtxMessageTypeDecode, p
+	case WsjtxClear:
+		msg.Header.Type, msg.Clear = WsjtxMessageTypeClear, &p
+	case *WsjtxClear:
+		msg.Header.Type, msg.Clear = WsjtxMessageTypeClear, p
+	case WsjtxReply:
+		msg.Header.Type, msg.Reply = WsjtxMessageTypeReply, &p
+	case *WsjtxReply:
+		msg.Header.Type, msg.Reply = WsjtxMessageTypeReply, p
+	case WsjtxQSOLogged:
+		msg.Header.Type, msg.QSOLogged = WsjtxMessageTypeQSOLogged, &p
+	case *WsjtxQSOLogged:
+		msg.Header.Type, msg.QSOLogged = WsjtxMessageTypeQSOLogged, p
+	case WsjtxClose:
+		msg.Header.Type, msg.Close = WsjtxMessageTypeClose, &p
+	case *WsjtxClose:
+		msg.Header.Type, msg.Close = WsjtxMessageTypeClose, p
+	case WsjtxHaltTx:
+		msg.Header.Type, msg.HaltTx = WsjtxMessageTypeHaltTx, &p
+	case *WsjtxHaltTx:
+		msg.Header.Type, msg.HaltTx = WsjtxMessageTypeHaltTx, p
+	case WsjtxFreeText:
+		msg.Header.Type, msg.FreeText = WsjtxMessageTypeFreeText, &p
+	case *WsjtxFreeText:
+		msg.Header.Type, msg.FreeText = WsjtxMessageTypeFreeText, p
+	case WsjtxWSPRDecode:
+		msg.Header.Type, msg.WSPRDecode = WsjtxMessageTypeWSPRDecode, &p
+	case *WsjtxWSPRDecode:
+		msg.Header.Type, msg.WSPRDecode = WsjtxMessageTypeWSPRDecode, p
+	case WsjtxLocation:
+		msg.Header.Type, msg.Location = WsjtxMessageTypeLocation, &p
+	case *WsjtxLocation:
+		msg.Header.Type, msg.Location = WsjtxMessageTypeLocation, p
+	case WsjtxLoggedADIF:
+		msg.Header.Type, msg.LoggedADIF = WsjtxMessageTypeLoggedADIF, &p
+	case *WsjtxLoggedADIF:
+		msg.Header.Type, msg.LoggedADIF = WsjtxMessageTypeLoggedADIF, p
+	case WsjtxHighlightCallsign:
+		msg.Header.Type, msg.HighlightCallsign = WsjtxMessageTypeHighlightCallsign, &p
+	case *WsjtxHighlightCallsign:
+		msg.Header.Type, msg.HighlightCallsign = WsjtxMessageTypeHighlightCallsign, p
+	case WsjtxSwitchConfiguration:
+		msg.Header.Type, msg.SwitchConfiguration = WsjtxMessageTypeSwitchConfiguration, &p
+	case *WsjtxSwitchConfiguration:
+		msg.Header.Type, msg.SwitchConfiguration = WsjtxMessageTypeSwitchConfiguration, p
+	case WsjtxConfigure:
+		msg.Header.Type, msg.Configure = WsjtxMessageTypeConfigure, &p
+	case *WsjtxConfigure:
+		msg.Header.Type, msg.Configure = WsjtxMessageTypeConfigure, p
+	default:
+		return WsjtxMessage{}, fmt.Errorf("unsupported wsjtx payload %T", payload)
+	}
+	return msg, nil
+}
+
+// MarshalWsjtx encodes msg in the WSJT-X UDP format. Header.Type selects the payload;
+// Close and Replay carry none, and a nil Clear clears the band activity window. A zero
+// SchemaNumber writes schema 3. Optional trailing fields are always written, nil
+// pointers as zero values.
+func MarshalWsjtx(msg WsjtxMessage) ([]byte, error) {
+	schema := msg.Header.SchemaNumber
+	if schema == 0 {
+		schema = defaultWsjtxSchema
+	}
+	if schema != WsjtxSchema2 && schema != WsjtxSchema3 {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedWsjtxSchema, schema)
+	}
+
+	w := &qdsWriter{}
+	w.u32(WsjtxMagic)
+	w.u32(schema)
+	w.u32(uint32(msg.Header.Type))
+	w.str(msg.Header.ID)
+
+	missing := func() ([]byte, error) {
+		return nil, fmt.Errorf("wsjtx message type %d has no payload", msg.Header.Type)
+	}
+	switch msg.Header.Type {
+	case WsjtxMessageTypeHeartbeat:
+		p := msg.Heartbeat
+		if p == nil {
+			return missing()
+		}
+		w.u32(p.MaxSchemaNumber)
+		w.str(p.Version)
+		w.str(deref(p.Revision))
+	case WsjtxMessageTypeStatus:
+		p := msg.Status
+		if p == nil {
+			return missing()
+		}
+		w.u64(p.DialFrequency)
+		w.str(p.Mode)
+		w.str(p.DXCall)
+		w.str(p.Report)
+		w.str(p.TXMode)
+		w.bool(p.TXEnabled)
+		w.bool(p.Transmitting)
+		w.bool(p.Decoding)
+		w.u32(p.RXDF)
+		w.u32(p.TXDF)
+		w.str(p.DECall)
+		w.str(p.DEGrid)
+		w.str(p.DXGrid)
+		w.bool(p.TXWatchdog)
+		w.str(p.SubMode)
+		w.bool(p.FastMode)
+		w.u8(uint8(deref(p.SpecialOpMode)))
+		w.u32(deref(p.FrequencyTolerance))
+		w.u32(deref(p.TRPeriod))
+		w.str(deref(p.ConfigName))
+		w.str(deref(p.TXMessage))
+	case WsjtxMessageTypeDecode:
+		p := msg.Decode
+		if p == nil {
+			return missing()
+		}
+		w.bool(p.IsNew)
+		w.qtime(p.Time)
+		w.i32(p.SNR)
+		w.f64(p.DeltaTime)
+		w.u32(p.DeltaFrequency)
+		w.str(p.Mode)
+		w.str(p.Message)
+		w.bool(p.LowConfidence)
+		w.bool(p.OffAir)
+	case WsjtxMessageTypeClear:
+		if msg.Clear != nil {
+			w.u8(uint8(msg.Clear.Window))
+		}
+	case WsjtxMessageTypeReply:
+		p := msg.Reply
+		if p == nil {
+			return missing()
+		}
+		w.qtime(p.Time)
+		w.i32(p.SNR)
+		w.f64(p.DeltaTime)
+		w.u32(p.DeltaFrequency)
+		w.str(p.Mode)
+		w.str(p.Message)
+		w.bool(p.LowConfidence)
+		w.u8(uint8(p.Modifiers))
+	case WsjtxMessageTypeQSOLogged:
+		p := msg.QSOLogged
+		if p == nil {
+			return missing()
+		}
+		w.qdatetime(p.DateTimeOff)
+		w.str(p.DXCall)
+		w.str(p.DXGrid)
+		w.u64(p.TXFrequency)
+		w.str(p.Mode)
+		w.str(p.ReportSent)
+		w.str(p.ReportReceived)
+		w.str(p.TXPower)
+		w.str(p.Comments)
+		w.str("") // Name has no field in WsjtxQSOLogged
+		w.qdatetime(p.DateTimeOn)
+		w.str(p.OperatorCall)
+		w.str(p.MyCall)
+		w.str(p.MyGrid)
+		w.str(deref(p.ExchangeSent))
+		w.str(deref(p.ExchangeReceived))
+		w.str(deref(p.ADIFPropagationMode))
+	case WsjtxMessageTypeClose, WsjtxMessageTypeReplay:
+	case WsjtxMessageTypeHaltTx:
+		p := msg.HaltTx
+		if p == nil {
+			return missing()
+		}
+		w.bool(p.AutoTXOnly)
+	case WsjtxMessageTypeFreeText:
+		p := msg.FreeText
+		if p == nil {
+			return missing()
+		}
+		w.str(p.Text)
+		w.bool(p.Send)
+	case WsjtxMessageTypeWSPRDecode:
+		p := msg.WSPRDecode
+		if p == nil {
+			return missing()
+		}
+		w.bool(p.IsNew)
+		w.qtime(p.Time)
+		w.i32(p.SNR)
+		w.f64(p.DeltaTime)
+		w.u64(p.Frequency)
+		w.i32(p.Drift)
+		w.str(p.Callsign)
+		w.str(p.Grid)
+		w.i32(p.Power)
+		w.bool(deref(p.OffAir))
+	case WsjtxMessageTypeLocation:
+		p := msg.Location
+		if p == nil {
+			return missing()
+		}
+		w.str(p.Location)
+	case WsjtxMessageTypeLoggedADIF:
+		p := msg.LoggedADIF
+		if p == nil {
+			return missing()
+		}
+		w.str(p.ADIFText)
+	case WsjtxMessageTypeHighlightCallsign:
+		p := msg.HighlightCallsign
+		if p == nil {
+			return missing()
+		}
+		w.str(p.Callsign)
+		w.qcolor(p.BackgroundColor)
+		w.qcolor(p.ForegroundColor)
+		w.bool(p.HighlightLast)
+	case WsjtxMessageTypeSwitchConfiguration:
+		p := msg.SwitchConfiguration
+		if p == nil {
+			return missing()
+		}
+		w.str(p.ConfigName)
+	case WsjtxMessageTypeConfigure:
+		p := msg.Configure
+		if p == nil {
+			return missing()
+		}
+		w.str(p.Mode)
+		w.u32(p.FrequencyTolerance)
+		w.str(p.SubMode)
+		w.bool(p.FastMode)
+		w.u32(p.TRPeriod)
+		w.u32(p.RXDF)
+		w.str(p.DXCall)
+		w.str(p.DXGrid)
+		w.bool(p.GenerateMessages)
+	default:
+		return nil, fmt.Errorf("unsupported wsjtx message type %d", msg.Header.Type)
+	}
+	return w.buf, nil
+}
+
+// wsjtxInstanceID returns the id in a datagram's header, without decoding its body.
+func wsjtxInstanceID(data []byte) (string, error) {
+	r := &qdsReader{data: data}
+	magic := r.u32()
+	r.take(8) // schema, type
+	id := r.str()
+	if r.err != nil {
+		return "", r.err
+	}
+	if magic != WsjtxMagic {
+		return "", ErrInvalidWsjtxMagic
+	}
+	return id, nil
+}
+
+// UnmarshalWsjtx decodes one WSJT-X UDP datagram. Trailing fields that older WSJT-X
+// versions omit are left nil or zero. Decode, Reply and WSPR times only carry the time
+// of day and are placed on the current UTC date, or the previous one when that would put
+// them more than a few minutes in the future.
+func UnmarshalWsjtx(data []byte) (WsjtxMessage, error) {
+	now := time.Now().UTC()
+	r := &qdsReader{data: data, now: now}
+
+	var msg WsjtxMessage
+	msg.Timestamp = now
+	msg.Header.MagicNumber = r.u32()
+	msg.Header.SchemaNumber = r.u32()
+	if r.err != nil {
+		return WsjtxMessage{}, r.err
+	}
+	if msg.Header.MagicNumber != WsjtxMagic {
+		return WsjtxMessage{}, ErrInvalidWsjtxMagic
+	}
+	if s := msg.Header.SchemaNumber; s != WsjtxSchema2 && s != WsjtxSchema3 {
+		return WsjtxMessage{}, fmt.Errorf("%w: %d", ErrUnsupportedWsjtxSchema, s)
+	}
+	msg.Header.Type = WsjtxMessageType(r.u32())
+	msg.Header.ID = r.str()
+
+	switch msg.Header.Type {
+	case WsjtxMessageTypeHeartbeat:
+		p := &WsjtxHeartbeat{MaxSchemaNumber: r.u32(), Version: r.str()}
+		if r.more() {
+			p.Revision = ptr(r.str())
+		}
+		msg.Heartbeat = p
+	case WsjtxMessageTypeStatus:
+		p := &WsjtxStatus{
+			DialFrequency: r.u64(),
+			Mode:          r.str(),
+			DXCall:        r.str(),
+			Report:        r.str(),
+			TXMode:        r.str(),
+			TXEnabled:     r.bool(),
+			Transmitting:  r.bool(),
+			Decoding:      r.bool(),
+			RXDF:          r.u32(),
+			TXDF:          r.u32(),
+			DECall:        r.str(),
+			DEGrid:        r.str(),
+			DXGrid:        r.str(),
+			TXWatchdog:    r.bool(),
+			SubMode:       r.str(),
+			FastMode:      r.bool(),
+		}
+		if r.more() {
+			p.SpecialOpMode = ptr(SpecialOperationMode(r.u8()))
+		}
+		if r.more() {
+			p.FrequencyTolerance = ptr(r.u32())
+		}
+		if r.more() {
+			p.TRPeriod = ptr(r.u32())
+		}
+		if r.more() {
+			p.ConfigName = ptr(r.str())
+		}
+		if r.more() {
+			p.TXMessage = ptr(r.str())
+		}
+		msg.Status = p
+	case WsjtxMessageTypeDecode:
+		p := &WsjtxDecode{
+			IsNew:          r.bool(),
+			Time:           r.qtime(),
+			SNR:            r.i32(),
+			DeltaTime:      r.f64(),
+			DeltaFrequency: r.u32(),
+			Mode:           r.str(),
+			Message:        r.str(),
+		}
+		if r.more() {
+			p.LowConfidence = r.bool()
+		}
+		if r.more() {
+			p.OffAir = r.bool()
+		}
+		msg.Decode = p
+	case WsjtxMessageTypeClear:
+		p := &WsjtxClear{}
+		if r.more() {
+			p.Window = ClearWindow(r.u8())
+		}
+		msg.Clear = p
+	case WsjtxMessageTypeReply:
+		p := &WsjtxReply{
+			Time:           r.qtime(),
+			SNR:            r.i32(),
+			DeltaTime:      r.f64(),
+			DeltaFrequency: r.u32(),
+			Mode:           r.str(),
+			Message:        r.str(),
+			LowConfidence:  r.bool(),
+		}
+		if r.more() {
+			p.Modifiers = uint32(r.u8())
+		}
+		msg.Reply = p
+	case WsjtxMessageTypeQSOLogged:
+		p := &WsjtxQSOLogged{
+			DateTimeOff:    r.qdatetime(),
+			DXCall:         r.str(),
+			DXGrid:         r.str(),
+			TXFrequency:    r.u64(),
+			Mode:           r.str(),
+			ReportSent:     r.str(),
+			ReportReceived: r.str(),
+			TXPower:        r.str(),
+			Comments:       r.str(),
+		}
+		_ = r.str() // Name
+		p.DateTimeOn = r.qdatetime()
+		p.OperatorCall = r.str()
+		p.MyCall = r.str()
+		p.MyGrid = r.str()
+		if r.more() {
+			p.ExchangeSent = ptr(r.str())
+		}
+		if r.more() {
+			p.ExchangeReceived = ptr(r.str())
+		}
+		if r.more() {
+			p.ADIFPropagationMode = ptr(r.str())
+		}
+		msg.QSOLogged = p
+	case WsjtxMessageTypeClose:
+		msg.Close = &WsjtxClose{}
+	case WsjtxMessageTypeReplay:
+	case WsjtxMessageTypeHaltTx:
+		msg.HaltTx = &WsjtxHaltTx{AutoTXOnly: r.bool()}
+	case WsjtxMessageTypeFreeText:
+		msg.FreeText = &WsjtxFreeText{Text: r.str(), Send: r.bool()}
+	case WsjtxMessageTypeWSPRDecode:
+		p := &WsjtxWSPRDecode{
+			IsNew:     r.bool(),
+			Time:      r.qtime(),
+			SNR:       r.i32(),
+			DeltaTime: r.f64(),
+			Frequency: r.u64(),
+			Drift:     r.i32(),
+			Callsign:  r.str(),
+			Grid:      r.str(),
+			Power:     r.i32(),
+		}
+		if r.more() {
+			p.OffAir = ptr(r.bool())
+		}
+		msg.WSPRDecode = p
+	case WsjtxMessageTypeLocation:
+		msg.Location = &WsjtxLocation{Location: r.str()}
+	case WsjtxMessageTypeLoggedADIF:
+		msg.LoggedADIF = &WsjtxLoggedADIF{ADIFText: r.str()}
+	case WsjtxMessageTypeHighlightCallsign:
+		msg.HighlightCallsign = &WsjtxHighlightCallsign{
+			Callsign:        r.str(),
+			BackgroundColor: r.qcolor(),
+			ForegroundColor: r.qcolor(),
+			HighlightLast:   r.bool(),
+		}
+	case WsjtxMessageTypeSwitchConfiguration:
+		msg.SwitchConfiguration = &WsjtxSwitchConfiguration{ConfigName: r.str()}
+	case WsjtxMessageTypeConfigure:
+		msg.Configure = &WsjtxConfigure{
+			Mode:               r.str(),
+			FrequencyTolerance: r.u32(),
+			SubMode:            r.str(),
+			FastMode:           r.bool(),
+			TRPeriod:           r.u32(),
+			RXDF:               r.u32(),
+			DXCall:             r.str(),
+			DXGrid:             r.str(),
+			GenerateMessages:   r.bool(),
+		}
+	default:
+		if r.err == nil {
+			return WsjtxMessage{}, fmt.Errorf("unsupported wsjtx message type %d", msg.Header.Type)
+		}
+	}
+	if r.err != nil {
+		return WsjtxMessage{}, r.err
+	}
+	return msg, nil
+}
+
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+func ptr[T any](v T) *T { return &v }
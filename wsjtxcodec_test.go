@@ -0,0 +1,171 @@
+package clhplugin
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWsjtxRoundTrip(t *testing.T) {
+	on := time.Date(2026, 3, 12, 1, 2, 3, 0, time.UTC)
+	off := on.Add(90 * time.Second)
+	payloads := []any{
+		WsjtxHeartbeat{MaxSchemaNumber: 3, Version: "2.7.0", Revision: ptr("a1b2c3")},
+		WsjtxStatus{
+			DialFrequency: 14074000, Mode: "FT8", DXCall: "JA1ABC", Report: "-12", TXMode: "FT8",
+			TXEnabled: true, Decoding: true, RXDF: 1500, TXDF: 1200, DECall: "BH1XYZ", DEGrid: "OM89",
+			DXGrid: "PM95", SubMode: "", SpecialOpMode: ptr(SpecialOperationModeFox),
+			FrequencyTolerance: ptr(uint32(20)), TRPeriod: ptr(uint32(15)), ConfigName: ptr("Default"),
+			TXMessage: ptr("JA1ABC BH1XYZ OM89"),
+		},
+		WsjtxDecode{IsNew: true, SNR: -12, DeltaTime: 0.2, DeltaFrequency: 1234, Mode: "~",
+			Message: "CQ BH1XYZ OM89", LowConfidence: true, OffAir: true},
+		WsjtxClear{Window: ClearWindowBoth},
+		WsjtxReply{SNR: -5, DeltaTime: -0.1, DeltaFrequency: 800, Mode: "~", Message: "CQ JA1ABC PM95", Modifiers: 0x02},
+		WsjtxQSOLogged{
+			DateTimeOff: off, DXCall: "JA1ABC", DXGrid: "PM95", TXFrequency: 14074000, Mode: "FT8",
+			ReportSent: "-12", ReportReceived: "-08", TXPower: "100", Comments: "tnx", DateTimeOn: on,
+			OperatorCall: "BH1XYZ", MyCall: "BH1XYZ", MyGrid: "OM89",
+			ExchangeSent: ptr(""), ExchangeReceived: ptr(""), ADIFPropagationMode: ptr("F2"),
+		},
+		WsjtxClose{},
+		WsjtxHaltTx{AutoTXOnly: true},
+		WsjtxFreeText{Text: "TNX 73 GL", Send: true},
+		WsjtxWSPRDecode{IsNew: true, SNR: -20, DeltaTime: 0.5, Frequency: 14097050, Drift: -1,
+			Callsign: "K1ABC", Grid: "FN42", Power: 37, OffAir: ptr(false)},
+		WsjtxLocation{Location: "OM89ab"},
+		WsjtxLoggedADIF{ADIFText: "<CALL:6>JA1ABC <EOR>"},
+		WsjtxHighlightCallsign{Callsign: "JA1ABC", BackgroundColor: 0xffff0000, ForegroundColor: 0x80112233, HighlightLast: true},
+		WsjtxSwitchConfiguration{ConfigName: "Contest"},
+		WsjtxConfigure{Mode: "FT4", FrequencyTolerance: 50, SubMode: "", FastMode: true, TRPeriod: 7,
+			RXDF: 1000, DXCall: "JA1ABC", DXGrid: "PM95", GenerateMessages: true},
+	}
+	for _, payload := range payloads {
+		msg, err := NewWsjtxMessage("WSJT-X", payload)
+		if err != nil {
+			t.Fatalf("NewWsjtxMessage(%T): %v", payload, err)
+		}
+		data, err := MarshalWsjtx(msg)
+		if err != nil {
+			t.Fatalf("MarshalWsjtx(%T): %v", payload, err)
+		}
+		got, err := UnmarshalWsjtx(data)
+		if err != nil {
+			t.Fatalf("UnmarshalWsjtx(%T): %v", payload, err)
+		}
+		got.Timestamp = time.Time{}
+		if !reflect.DeepEqual(got, msg) {
+			t.Errorf("%T round trip:\n got %+v\nwant %+v", payload, got, msg)
+		}
+	}
+
+	if _, err := NewWsjtxMessage("WSJT-X", "text"); err == nil {
+		t.Error("NewWsjtxMessage(string) succeeded")
+	}
+	if _, err := MarshalWsjtx(WsjtxMessage{Header: WsjtxMessageHeader{Type: WsjtxMessageTypeReply}}); err == nil {
+		t.Error("MarshalWsjtx without a payload succeeded")
+	}
+}
+
+func TestUnmarshalWsjtxWire(t *testing.T) {
+	// Heartbeat as WSJT-X 2.x sends it: id "WSJT-X", max schema 3, version "2.6.1", revision "24fcd1".
+	heartbeat := "adbccbda" + "00000002" + "00000000" +
+		"00000006" + hex.EncodeToString([]byte("WSJT-X")) +
+		"00000003" +
+		"00000005" + hex.EncodeToString([]byte("2.6.1")) +
+		"00000006" + hex.EncodeToString([]byte("24fcd1"))
+	data, _ := hex.DecodeString(heartbeat)
+	msg, err := UnmarshalWsjtx(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := WsjtxHeartbeat{MaxSchemaNumber: 3, Version: "2.6.1", Revision: ptr("24fcd1")}
+	if msg.Header.ID != "WSJT-X" || msg.Header.SchemaNumber != 2 || !reflect.DeepEqual(*msg.Heartbeat, want) {
+		t.Fatalf("heartbeat = %+v %+v", msg.Header, msg.Heartbeat)
+	}
+	if out, _ := MarshalWsjtx(msg); hex.EncodeToString(out) != heartbeat {
+		t.Fatalf("MarshalWsjtx = %x, want %s", out, heartbeat)
+	}
+
+	// A decode from a version without the trailing low-confidence and off-air flags.
+	w := &qdsWriter{}
+	w.u32(WsjtxMagic)
+	w.u32(WsjtxSchema2)
+	w.u32(uint32(WsjtxMessageTypeDecode))
+	w.str("JTDX")
+	w.bool(true)
+	w.u32(qNull)
+	w.i32(-7)
+	w.f64(0.3)
+	w.u32(900)
+	w.str("~")
+	w.str("JA1ABC BH1XYZ R-07")
+	msg, err = UnmarshalWsjtx(w.buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := msg.Decode; d == nil || d.Message != "JA1ABC BH1XYZ R-07" || d.SNR != -7 || !d.Time.IsZero() || d.OffAir {
+		t.Fatalf("old decode = %+v", msg.Decode)
+	}
+}
+
+func TestUnmarshalWsjtxErrors(t *testing.T) {
+	valid, _ := MarshalWsjtx(WsjtxMessage{
+		Header:   WsjtxMessageHeader{Type: WsjtxMessageTypeFreeText, ID: "WSJT-X"},
+		FreeText: &WsjtxFreeText{Text: "hello"},
+	})
+	badMagic := append([]byte{0, 0, 0, 0}, valid[4:]...)
+	badSchema := append(append([]byte(nil), valid[:4]...), append([]byte{0, 0, 0, 9}, valid[8:]...)...)
+	unknown := append(append([]byte(nil), valid[:8]...), append([]byte{0, 0, 0, 99}, valid[12:]...)...)
+
+	tests := []struct {
+		name string
+		data []byte
+		is   error
+	}{
+		{"empty", nil, errShortWsjtxMessage},
+		{"bad magic", badMagic, ErrInvalidWsjtxMagic},
+		{"bad schema", badSchema, ErrUnsupportedWsjtxSchema},
+		{"truncated", valid[:len(valid)-3], errShortWsjtxMessage},
+		{"unknown type", unknown, nil},
+	}
+	for _, tt := range tests {
+		_, err := UnmarshalWsjtx(tt.data)
+		if err == nil {
+			t.Errorf("%s: no error", tt.name)
+			continue
+		}
+		if tt.is != nil && !errors.Is(err, tt.is) {
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.is)
+		}
+	}
+	if _, err := MarshalWsjtx(WsjtxMessage{Header: WsjtxMessageHeader{SchemaNumber: 1}}); !errors.Is(err, ErrUnsupportedWsjtxSchema) {
+		t.Errorf("MarshalWsjtx schema 1: %v", err)
+	}
+}
+
+func TestQTimeDate(t *testing.T) {
+	ms := func(h, m, s int) uint32 { return uint32((h*3600 + m*60 + s) * 1000) }
+	tests := []struct {
+		now  time.Time
+		ms   uint32
+		want time.Time
+	}{
+		// A decode from just before midnight read just after it belongs to the previous day.
+		{time.Date(2026, 3, 12, 0, 0, 20, 0, time.UTC), ms(23, 59, 45), time.Date(2026, 3, 11, 23, 59, 45, 0, time.UTC)},
+		{time.Date(2026, 3, 1, 0, 1, 0, 0, time.UTC), ms(23, 59, 30), time.Date(2026, 2, 28, 23, 59, 30, 0, time.UTC)},
+		{time.Date(2026, 3, 12, 14, 0, 10, 0, time.UTC), ms(14, 0, 0), time.Date(2026, 3, 12, 14, 0, 0, 0, time.UTC)},
+		// Slightly ahead of the local clock stays on the same day.
+		{time.Date(2026, 3, 12, 14, 0, 0, 0, time.UTC), ms(14, 2, 0), time.Date(2026, 3, 12, 14, 2, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		w := &qdsWriter{}
+		w.u32(tt.ms)
+		r := &qdsReader{data: w.buf, now: tt.now}
+		if got := r.qtime(); !got.Equal(tt.want) {
+			t.Errorf("qtime(%d) at %v = %v, want %v", tt.ms, tt.now, got, tt.want)
+		}
+	}
+}